@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"stock/fetcher"
+	"stock/indicators"
 )
 
 // Analysis 分析结果
@@ -27,7 +28,7 @@ type ClaudeAnalyzer struct {
 	apiKey     string
 	apiURL     string
 	model      string
-	klineFetch *fetcher.KLineFetcher
+	klineFetch fetcher.BarSource
 	results    sync.Map // map[string]*Analysis
 	mu         sync.RWMutex
 }
@@ -51,10 +52,18 @@ func NewClaudeAnalyzer(apiKey, apiBase, model string) *ClaudeAnalyzer {
 	}
 }
 
+// SetBarSource 替换K线数据源（默认东方财富/新浪）
+func (a *ClaudeAnalyzer) SetBarSource(src fetcher.BarSource) {
+	if src == nil {
+		return
+	}
+	a.klineFetch = src
+}
+
 // AnalyzeStock 分析股票
 func (a *ClaudeAnalyzer) AnalyzeStock(code, name string) (*Analysis, error) {
 	// 获取最近3个月日K线（约60个交易日）
-	klines, err := a.klineFetch.FetchStockKLine(code, 60)
+	klines, err := a.klineFetch.FetchStockKLine(code, 60, fetcher.KLineOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("获取K线数据失败: %w", err)
 	}
@@ -89,7 +98,7 @@ func (a *ClaudeAnalyzer) AnalyzeStock(code, name string) (*Analysis, error) {
 // AnalyzeFutures 分析期货
 func (a *ClaudeAnalyzer) AnalyzeFutures(code, name string) (*Analysis, error) {
 	// 获取最近3个月日K线（约60个交易日）
-	klines, err := a.klineFetch.FetchFuturesKLine(code, 60)
+	klines, err := a.klineFetch.FetchFuturesKLine(code, 60, fetcher.KLineOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("获取K线数据失败: %w", err)
 	}
@@ -132,6 +141,7 @@ func (a *ClaudeAnalyzer) buildPrompt(name, typ string, klines []fetcher.KLine) s
 		sb.WriteString(fmt.Sprintf("%s | %.2f | %.2f | %.2f | %.2f | %d\n",
 			k.Date, k.Open, k.Close, k.High, k.Low, k.Volume))
 	}
+	writeIndicators(&sb, indicators.Latest(fetcher.KLinesToBars(klines, time.Local)))
 
 	sb.WriteString("\n请从以下角度进行分析（总共不超过300字）:\n")
 	sb.WriteString("1. 整体趋势: 3个月内的主要趋势方向\n")
@@ -142,6 +152,40 @@ func (a *ClaudeAnalyzer) buildPrompt(name, typ string, klines []fetcher.KLine) s
 	return sb.String()
 }
 
+// writeIndicators 追加最新一根K线的技术指标（未就绪的省略）
+func writeIndicators(sb *strings.Builder, s indicators.Snapshot) {
+	var lines []string
+	add := func(format string, vals ...float64) {
+		for _, v := range vals {
+			if v == 0 {
+				return
+			}
+		}
+		args := make([]any, len(vals))
+		for i, v := range vals {
+			args[i] = v
+		}
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	add("MA5/10/20: %.2f / %.2f / %.2f", s.MA5, s.MA10, s.MA20)
+	add("MA60: %.2f", s.MA60)
+	add("MACD(12,26,9): DIF %.3f, DEA %.3f, 柱 %.3f", s.MACDDIF, s.MACDDEA, s.MACDHist)
+	add("RSI6/14: %.1f / %.1f", s.RSI6, s.RSI14)
+	add("KDJ(9,3,3): K %.1f, D %.1f, J %.1f", s.K, s.D, s.J)
+	add("BOLL(20,2): 上轨 %.2f, 中轨 %.2f, 下轨 %.2f", s.BollUpper, s.BollMid, s.BollLower)
+	add("ATR14: %.3f", s.ATR14)
+	add("ADX14: %.1f (+DI %.1f, -DI %.1f)", s.ADX14, s.PlusDI, s.MinusDI)
+	add("20日唐奇安通道: %.2f ~ %.2f", s.DonchianLower, s.DonchianUpper)
+	add("VWAP20: %.2f", s.VWAP20)
+	if len(lines) == 0 {
+		return
+	}
+	sb.WriteString("\n最新技术指标:\n")
+	for _, l := range lines {
+		sb.WriteString("- " + l + "\n")
+	}
+}
+
 // callClaude 调用 Claude API
 func (a *ClaudeAnalyzer) callClaude(prompt string) (string, error) {
 	reqBody := map[string]interface{}{
@@ -0,0 +1,172 @@
+package backtest
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Causes of UnfilledOrder.
+const (
+	CauseLimitUp   = "limit_up"   // buy at a limit-up open
+	CauseLimitDown = "limit_down" // sell at a limit-down open / stop on a locked limit-down bar
+	CauseTPlus1    = "t_plus_1"   // stock bought on the same trading day
+	CauseSuspended = "suspended"  // zero-volume bar, or resumed after a long gap
+)
+
+// UnfilledOrder is an order the A-share rule layer refused to fill.
+type UnfilledOrder struct {
+	Symbol string       `json:"symbol,omitempty"`
+	Time   string       `json:"time"` // bar the fill was attempted on
+	Action SignalAction `json:"action"`
+	Reason string       `json:"reason"` // signal / exit reason
+	Cause  string       `json:"cause"`
+	// Retry is true when the order was kept and retried on the next bar
+	// (exits and orders hitting a suspension); rejected buys are dropped.
+	Retry bool `json:"retry"`
+}
+
+// suspensionGapDays is the calendar gap between two bars above which the
+// later bar is treated as a resumption after suspension (holidays are shorter).
+const suspensionGapDays = 15
+
+// chiNextReformDate is when ChiNext (300/301) moved from ±10% to ±20% limits.
+var chiNextReformDate = time.Date(2020, 8, 24, 0, 0, 0, 0, time.Local)
+
+// priceLimitPct returns the daily price limit of an A-share by board:
+// STAR/ChiNext ±20%, Beijing ±30%, main board ±10%, ST ±5% (main board only).
+func priceLimitPct(symbol string, t time.Time, st bool) float64 {
+	s := strings.ToLower(strings.TrimSpace(symbol))
+	board := ""
+	if len(s) > 2 && (s[:2] == "sh" || s[:2] == "sz" || s[:2] == "bj") {
+		board, s = s[:2], s[2:]
+	}
+	switch {
+	case board == "bj" || strings.HasPrefix(s, "8") || strings.HasPrefix(s, "4") || strings.HasPrefix(s, "92"):
+		return 0.30
+	case strings.HasPrefix(s, "688") || strings.HasPrefix(s, "689"):
+		return 0.20
+	case (strings.HasPrefix(s, "300") || strings.HasPrefix(s, "301")) && !t.Before(chiNextReformDate):
+		return 0.20
+	case st:
+		return 0.05
+	default:
+		return 0.10
+	}
+}
+
+// limitPrices returns the limit-up/limit-down prices of bars[i], based on the
+// close of the previous trading day (0, 0 when unknown).
+func (cfg RunConfig) limitPrices(inst Instrument, bars []Bar, i int) (float64, float64) {
+	j := i - 1
+	for j >= 0 && sameDay(bars[j].Time, bars[i].Time) {
+		j--
+	}
+	if j < 0 || bars[j].Close <= 0 {
+		return 0, 0
+	}
+	prev := bars[j].Close
+	pct := priceLimitPct(inst.Symbol, bars[i].Time, cfg.STStocks[inst.Symbol])
+	return round2(prev * (1 + pct)), round2(prev * (1 - pct))
+}
+
+func (cfg RunConfig) aShareRules(inst Instrument) bool {
+	return cfg.AShareRules && inst.Type == InstrumentTypeStock
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// orderBlocked applies the A-share rules to filling sig at the open of bars[i].
+// It returns the cause ("" = fillable) and whether to retry on the next bar.
+func (cfg RunConfig) orderBlocked(inst Instrument, bars []Bar, i int, sig *Signal, pos Position) (string, bool) {
+	if !cfg.aShareRules(inst) {
+		return "", false
+	}
+	buy := sig.Action == SignalBuy && pos.Side == SideFlat
+	sell := sig.Action == SignalSell && pos.Side == SideLong
+	if !buy && !sell {
+		return "", false
+	}
+	bar := bars[i]
+	if bar.Volume <= 0 {
+		return CauseSuspended, true
+	}
+	if i >= 1 && bar.Time.Sub(bars[i-1].Time) > suspensionGapDays*24*time.Hour {
+		// First bar after a suspension (missing bars): the pre-suspension signal
+		// is stale, so hold it one bar and let the strategy see the resumption.
+		return CauseSuspended, true
+	}
+	up, down := cfg.limitPrices(inst, bars, i)
+	if buy {
+		if up > 0 && bar.Open >= up-0.005 {
+			return CauseLimitUp, false
+		}
+		return "", false
+	}
+	if sameDay(pos.EntryTime, bar.Time) {
+		return CauseTPlus1, true
+	}
+	if down > 0 && bar.Open <= down+0.005 {
+		return CauseLimitDown, true
+	}
+	return "", false
+}
+
+// protectiveBlocked applies the A-share rules to a protective exit on bars[i].
+func (cfg RunConfig) protectiveBlocked(inst Instrument, bars []Bar, i int, pos Position, exit *Signal) string {
+	if !cfg.aShareRules(inst) {
+		return ""
+	}
+	bar := bars[i]
+	switch {
+	case bar.Volume <= 0:
+		return CauseSuspended
+	case sameDay(pos.EntryTime, bar.Time):
+		return CauseTPlus1
+	}
+	_, down := cfg.limitPrices(inst, bars, i)
+	if down <= 0 {
+		return ""
+	}
+	// A gap fill needs a tradable open; a touch fill needs the bar to leave the limit.
+	switch exit.Reason {
+	case ReasonStopGap:
+		if bar.Open <= down+0.005 {
+			return CauseLimitDown
+		}
+	case ReasonStopHit:
+		if math.Abs(bar.High-down) < 0.005 {
+			return CauseLimitDown
+		}
+	}
+	return ""
+}
+
+// gateOrder checks a pending order against the A-share rules before it fills
+// at the open of bars[i]. When the order is blocked it records the attempt in
+// unfilled and returns false with the order to keep pending (nil = dropped).
+func (cfg RunConfig) gateOrder(inst Instrument, bars []Bar, i int, pending *Signal, pos Position, unfilled *[]UnfilledOrder) (*Signal, bool) {
+	cause, retry := cfg.orderBlocked(inst, bars, i, pending, pos)
+	if cause == "" {
+		return pending, true
+	}
+	*unfilled = append(*unfilled, UnfilledOrder{
+		Symbol: inst.Symbol,
+		Time:   cfg.FormatTime(bars[i].Time),
+		Action: pending.Action,
+		Reason: pending.Reason,
+		Cause:  cause,
+		Retry:  retry,
+	})
+	if !retry {
+		return nil, false
+	}
+	// re-arm for the next bar's open
+	keep := *pending
+	keep.Time = bars[i].Time
+	return &keep, false
+}
@@ -0,0 +1,123 @@
+package backtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPriceLimitPct(t *testing.T) {
+	after := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)
+	before := time.Date(2019, 1, 2, 0, 0, 0, 0, time.Local)
+	cases := []struct {
+		symbol string
+		t      time.Time
+		st     bool
+		want   float64
+	}{
+		{"sh600000", after, false, 0.10},
+		{"sh600000", after, true, 0.05},
+		{"sh688981", after, false, 0.20},
+		{"sz300750", after, false, 0.20},
+		{"sz300750", after, true, 0.20},
+		{"sz300750", before, false, 0.10},
+		{"sz000001", after, false, 0.10},
+		{"bj830799", after, false, 0.30},
+	}
+	for _, tc := range cases {
+		if got := priceLimitPct(tc.symbol, tc.t, tc.st); got != tc.want {
+			t.Errorf("%s st=%v %s: got %v, want %v", tc.symbol, tc.st, tc.t.Format("2006"), got, tc.want)
+		}
+	}
+}
+
+func flatBars(n int) []Bar {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	bars := make([]Bar, n)
+	for i := range bars {
+		bars[i] = Bar{Time: start.AddDate(0, 0, i), Open: 10, High: 10.1, Low: 9.9, Close: 10, Volume: 100}
+	}
+	return bars
+}
+
+func TestAShareRulesInRunOne(t *testing.T) {
+	cfg := DefaultRunConfig()
+	cfg.AShareRules = true
+	cfg.IntrabarExits = true
+	cfg.SlippageBps = 0
+	cfg.CommissionBps = 0
+	inst := Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}
+	buySell := &scriptStrategy{at: map[int]SignalAction{1: SignalBuy, 5: SignalSell}}
+
+	// buy at a limit-up open is dropped
+	bars := flatBars(20)
+	bars[2].Open, bars[2].High = 11, 11
+	cfg.Strategy = buySell
+	res := runOne(inst, bars, cfg)
+	if len(res.Trades) != 0 || len(res.Unfilled) != 1 || res.Unfilled[0].Cause != CauseLimitUp || res.Unfilled[0].Retry {
+		t.Fatalf("limit-up buy: trades %#v unfilled %#v", res.Trades, res.Unfilled)
+	}
+
+	// sell at a limit-down open is retried on the next bar
+	bars = flatBars(20)
+	bars[6].Open, bars[6].Low = 9, 9
+	res = runOne(inst, bars, cfg)
+	if len(res.Trades) != 1 || res.Trades[0].ExitTime != "2024-01-08" || len(res.Unfilled) != 1 || res.Unfilled[0].Cause != CauseLimitDown {
+		t.Fatalf("limit-down sell: trades %#v unfilled %#v", res.Trades, res.Unfilled)
+	}
+
+	// suspended (zero-volume) bars defer the order
+	bars = flatBars(20)
+	bars[6].Volume = 0
+	bars[7].Volume = 0
+	res = runOne(inst, bars, cfg)
+	if len(res.Trades) != 1 || res.Trades[0].ExitTime != "2024-01-09" || len(res.Unfilled) != 2 || res.Unfilled[1].Cause != CauseSuspended {
+		t.Fatalf("suspended sell: trades %#v unfilled %#v", res.Trades, res.Unfilled)
+	}
+
+	// T+1: a stop touched on the entry day fills on the next day
+	bars = flatBars(20)
+	bars[2].Low = 9.4
+	bars[3].Low = 9.4
+	cfg.Strategy = &levelStrategy{}
+	res = runOne(inst, bars, cfg)
+	if len(res.Trades) != 1 || res.Trades[0].ExitTime != "2024-01-04" || res.Trades[0].ReasonExit != ReasonStopHit {
+		t.Fatalf("t+1 stop: trades %#v", res.Trades)
+	}
+	if len(res.Unfilled) != 1 || res.Unfilled[0].Cause != CauseTPlus1 {
+		t.Fatalf("t+1 stop: unfilled %#v", res.Unfilled)
+	}
+
+	// rules off: the limit-up buy fills
+	cfg.AShareRules = false
+	cfg.Strategy = buySell
+	bars = flatBars(20)
+	bars[2].Open, bars[2].High = 11, 11
+	if res = runOne(inst, bars, cfg); len(res.Trades) != 1 || len(res.Unfilled) != 0 {
+		t.Fatalf("rules off: trades %#v unfilled %#v", res.Trades, res.Unfilled)
+	}
+}
+
+func TestSTStocksNormalized(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backtest.yaml")
+	yml := "backtest:\n  st_stocks: [SZ000001, 600001.SH, \" 000002 \"]\n  instruments:\n    stocks: [\"000001\"]\n"
+	if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadRunConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for _, sym := range []string{"sz000001", "sh600001", "sz000002"} {
+		if !cfg.STStocks[sym] {
+			t.Errorf("%s not in st_stocks %v", sym, cfg.STStocks)
+		}
+	}
+	if len(cfg.Instruments) != 1 || !cfg.STStocks[cfg.Instruments[0].Symbol] {
+		t.Fatalf("instrument %+v does not match st_stocks", cfg.Instruments)
+	}
+	if cfg.AShareRules {
+		t.Fatalf("ashare_rules must be opt-in")
+	}
+}
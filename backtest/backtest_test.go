@@ -1,6 +1,13 @@
 package backtest
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stock/fetcher"
+)
 
 func TestTsaiSenBreakBottomFlipReclaimSupport(t *testing.T) {
 	// Synthetic series:
@@ -30,3 +37,118 @@ func TestTsaiSenBreakBottomFlipReclaimSupport(t *testing.T) {
 		t.Fatalf("expected buy signal, got %#v", got)
 	}
 }
+
+func TestRunnerWithMemorySource(t *testing.T) {
+	src := fetcher.NewMemoryBarSource()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	var kl []fetcher.KLine
+	for i := 0; i < 80; i++ {
+		kl = append(kl, fetcher.KLine{
+			Date:   start.AddDate(0, 0, i).Format("2006-01-02"),
+			Open:   10,
+			High:   11,
+			Low:    9,
+			Close:  10,
+			Volume: 100,
+		})
+	}
+	src.Set("sh600000", kl)
+
+	cfg := DefaultRunConfig()
+	cfg.Instruments = []Instrument{{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}}
+	results, err := NewRunnerWithSource(src).Run(cfg)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Errors) > 0 {
+		t.Fatalf("unexpected results: %#v", results)
+	}
+	if got := len(results[0].EquityCurve); got != 80 {
+		t.Fatalf("expected 80 equity points, got %d", got)
+	}
+}
+
+type adjustRecordingSource struct {
+	*fetcher.MemoryBarSource
+	got []fetcher.Adjust
+}
+
+func (s *adjustRecordingSource) FetchStockKLine(code string, days int, opt fetcher.KLineOptions) ([]fetcher.KLine, error) {
+	s.got = append(s.got, opt.Adjust)
+	return s.MemoryBarSource.FetchStockKLine(code, days, opt)
+}
+
+func TestAdjustModePassedToSourceAndRecorded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backtest.yaml")
+	yml := "backtest:\n  adjust: backward\n  instruments:\n    stocks: [sh600000]\n    futures: [rb0]\n"
+	if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadRunConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Adjust != fetcher.AdjustBackward {
+		t.Fatalf("adjust = %q", cfg.Adjust)
+	}
+
+	mem := fetcher.NewMemoryBarSource()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	var kl []fetcher.KLine
+	for i := 0; i < 60; i++ {
+		kl = append(kl, fetcher.KLine{Date: start.AddDate(0, 0, i).Format("2006-01-02"), Open: 10, High: 11, Low: 9, Close: 10, Volume: 100})
+	}
+	mem.Set("sh600000", kl)
+	mem.Set("nf_RB0", kl)
+	src := &adjustRecordingSource{MemoryBarSource: mem}
+
+	results, err := NewRunnerWithSource(src).Run(cfg)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(src.got) != 1 || src.got[0] != fetcher.AdjustBackward {
+		t.Fatalf("source saw adjust %v", src.got)
+	}
+	if results[0].Adjust != "backward" || results[1].Adjust != "none" {
+		t.Fatalf("recorded adjust = %q/%q", results[0].Adjust, results[1].Adjust)
+	}
+
+	if err := os.WriteFile(path, []byte("backtest:\n  adjust: sideways\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRunConfig(path); err == nil {
+		t.Fatalf("expected error for unknown adjust mode")
+	}
+}
+
+func TestRunnerIntradayBars(t *testing.T) {
+	src := fetcher.NewMemoryBarSource()
+	var kl []fetcher.KLine
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)
+	for d := 0; d < 3; d++ {
+		for m := 0; m < 48; m++ {
+			ts := day.AddDate(0, 0, d).Add(9*time.Hour + 35*time.Minute + time.Duration(m*5)*time.Minute)
+			kl = append(kl, fetcher.KLine{Date: ts.Format(fetcher.MinuteLayout), Open: 10, High: 11, Low: 9, Close: 10, Volume: 100})
+		}
+	}
+	src.Set("nf_RB0", kl)
+
+	cfg := DefaultRunConfig()
+	cfg.Frequency = fetcher.Period5Min
+	cfg.End = day.AddDate(0, 0, 1) // inclusive: keeps every bar of 01-03
+	cfg.Instruments = []Instrument{{Symbol: "nf_RB0", Type: InstrumentTypeFutures, Multiplier: 1}}
+	results, err := NewRunnerWithSource(src).Run(cfg)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(results[0].Errors) > 0 {
+		t.Fatalf("errors: %v", results[0].Errors)
+	}
+	curve := results[0].EquityCurve
+	if len(curve) != 96 {
+		t.Fatalf("expected 96 equity points, got %d", len(curve))
+	}
+	if curve[0].Time != "2024-01-02 09:35" || curve[95].Time != "2024-01-03 13:30" {
+		t.Fatalf("unexpected equity times %s .. %s", curve[0].Time, curve[95].Time)
+	}
+}
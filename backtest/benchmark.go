@@ -0,0 +1,169 @@
+package backtest
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"stock/config"
+	"stock/fetcher"
+)
+
+// Benchmark compares a result with buy-and-hold of the benchmark symbol
+// (backtest.benchmark), started with the same initial cash and no costs.
+// Beta, alpha (annualized) and correlation use daily returns.
+type Benchmark struct {
+	Symbol          string  `json:"symbol"`
+	ReturnPct       float64 `json:"return_pct"`
+	ExcessReturnPct float64 `json:"excess_return_pct"`
+	Beta            float64 `json:"beta"`
+	AlphaPct        float64 `json:"alpha_pct"`
+	// InformationRatio is the annualized mean / tracking error of daily excess returns.
+	InformationRatio float64 `json:"information_ratio"`
+	Correlation      float64 `json:"correlation"`
+	// EquityCurve is the benchmark equity at each point of the result's equity curve.
+	EquityCurve []Point `json:"equity_curve,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// benchmarkInstrument turns a backtest.benchmark symbol into an Instrument:
+// sh/sz/bj codes (indices included) load as stocks, anything else as futures.
+func (cfg RunConfig) benchmarkInstrument(symbol string) (Instrument, bool) {
+	s := strings.TrimSpace(symbol)
+	if s == "" {
+		return Instrument{}, false
+	}
+	if l := strings.ToLower(s); len(l) > 2 && (l[:2] == "sh" || l[:2] == "sz" || l[:2] == "bj") {
+		return Instrument{Symbol: l, Type: InstrumentTypeStock, LotSize: 100}, true
+	}
+	return cfg.FuturesInstrument(config.NormalizeFuturesCode(s)), true
+}
+
+// loadBenchmark loads the benchmark series once per run; nil when none is configured.
+func (r *Runner) loadBenchmark(cfg RunConfig) ([]Bar, *Benchmark) {
+	if cfg.Benchmark == nil {
+		return nil, nil
+	}
+	bars, _, err := r.loadSeries(*cfg.Benchmark, cfg)
+	if err != nil {
+		return nil, &Benchmark{Symbol: cfg.Benchmark.Symbol, Error: err.Error()}
+	}
+	return bars, nil
+}
+
+// attachBenchmark sets res.Benchmark from the loaded benchmark bars (or the load error).
+func attachBenchmark(res *Result, cfg RunConfig, bars []Bar, loadErr *Benchmark) {
+	switch {
+	case cfg.Benchmark == nil || len(res.EquityCurve) == 0:
+	case loadErr != nil:
+		b := *loadErr
+		res.Benchmark = &b
+	default:
+		b := CompareBenchmark(cfg.Benchmark.Symbol, res.EquityCurve, bars, cfg.InitialCash)
+		res.Benchmark = &b
+	}
+}
+
+// CompareBenchmark values initialCash held in the benchmark along curve and
+// derives excess return, beta, alpha, information ratio and correlation.
+// bars must be sorted by time.
+func CompareBenchmark(symbol string, curve []Point, bars []Bar, initialCash float64) Benchmark {
+	out := Benchmark{Symbol: symbol}
+	if len(curve) == 0 || len(bars) == 0 {
+		out.Error = "no benchmark data"
+		return out
+	}
+	base := initialCash
+	if base <= 0 {
+		base = curve[0].Equity
+	}
+
+	// benchmark close at or before each curve point
+	closes := make([]float64, len(curve))
+	times := make([]time.Time, len(curve))
+	j := -1
+	first := 0.0
+	for i, p := range curve {
+		t, err := fetcher.ParseKLineTime(p.Time, time.Local)
+		if err != nil {
+			out.Error = err.Error()
+			return out
+		}
+		times[i] = t
+		for j+1 < len(bars) && !bars[j+1].Time.After(t) {
+			j++
+		}
+		if j >= 0 {
+			closes[i] = bars[j].Close
+		} else {
+			// curve starts before the benchmark: use its first close
+			closes[i] = bars[0].Close
+		}
+		if first == 0 {
+			first = closes[i]
+		}
+	}
+	if first <= 0 {
+		out.Error = "invalid benchmark price"
+		return out
+	}
+
+	strat := make([]datedEquity, len(curve))
+	bench := make([]datedEquity, len(curve))
+	out.EquityCurve = make([]Point, len(curve))
+	for i, p := range curve {
+		v := base * closes[i] / first
+		out.EquityCurve[i] = Point{Time: p.Time, Equity: round2(v)}
+		strat[i] = datedEquity{t: times[i], v: p.Equity}
+		bench[i] = datedEquity{t: times[i], v: v}
+	}
+
+	last := len(curve) - 1
+	stratRet := curve[last].Equity/base - 1
+	benchRet := bench[last].v/base - 1
+	out.ReturnPct = round2(benchRet * 100)
+	out.ExcessReturnPct = round2((stratRet - benchRet) * 100)
+
+	rs := dailyReturns(dailyCloses(strat), base)
+	rb := dailyReturns(dailyCloses(bench), base)
+	n := len(rs)
+	if n < 2 {
+		return out
+	}
+	ms, mb := mean(rs), mean(rb)
+	var cov, vs, vb, te float64
+	diffs := make([]float64, n)
+	for i := range rs {
+		cov += (rs[i] - ms) * (rb[i] - mb)
+		vs += (rs[i] - ms) * (rs[i] - ms)
+		vb += (rb[i] - mb) * (rb[i] - mb)
+		diffs[i] = rs[i] - rb[i]
+	}
+	md := mean(diffs)
+	for _, d := range diffs {
+		te += (d - md) * (d - md)
+	}
+	te = math.Sqrt(te / float64(n-1))
+	if vb > 0 {
+		out.Beta = round4(cov / vb)
+		out.AlphaPct = round2((ms - cov/vb*mb) * tradingDaysPerYear * 100)
+	}
+	if vs > 0 && vb > 0 {
+		out.Correlation = round4(cov / math.Sqrt(vs*vb))
+	}
+	if te > 0 {
+		out.InformationRatio = round2(md / te * math.Sqrt(tradingDaysPerYear))
+	}
+	return out
+}
+
+func mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	s := 0.0
+	for _, x := range xs {
+		s += x
+	}
+	return s / float64(len(xs))
+}
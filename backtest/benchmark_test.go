@@ -0,0 +1,91 @@
+package backtest
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stock/fetcher"
+)
+
+func TestCompareBenchmark(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)
+	moves := []float64{0.01, -0.02, 0.015, 0.005, -0.01, 0.02}
+	var bars []Bar
+	var curve []Point
+	b, s := 100.0, 1000.0
+	for i := 0; i <= len(moves); i++ {
+		if i > 0 {
+			b *= 1 + moves[i-1]
+			s *= 1 + 2*moves[i-1] // twice the benchmark's daily moves
+		}
+		day := start.AddDate(0, 0, i)
+		bars = append(bars, Bar{Time: day, Close: b})
+		curve = append(curve, Point{Time: day.Format("2006-01-02"), Equity: s})
+	}
+
+	got := CompareBenchmark("sh000300", curve, bars, 1000)
+	if got.Error != "" {
+		t.Fatalf("error: %s", got.Error)
+	}
+	if got.Beta != 2 || got.Correlation != 1 || math.Abs(got.AlphaPct) > 0.01 {
+		t.Fatalf("beta/corr/alpha %#v", got)
+	}
+	wantBench := (b/100 - 1) * 100
+	if math.Abs(got.ReturnPct-wantBench) > 0.01 || math.Abs(got.ExcessReturnPct-((s/1000-1)*100-wantBench)) > 0.01 {
+		t.Fatalf("returns %#v", got)
+	}
+	if n := len(got.EquityCurve); n != len(curve) || got.EquityCurve[0].Equity != 1000 {
+		t.Fatalf("benchmark curve %#v", got.EquityCurve)
+	}
+}
+
+func TestRunnerAttachesBenchmark(t *testing.T) {
+	src := fetcher.NewMemoryBarSource()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	var stock, index []fetcher.KLine
+	for i := 0; i < 80; i++ {
+		d := start.AddDate(0, 0, i).Format("2006-01-02")
+		stock = append(stock, fetcher.KLine{Date: d, Open: 10, High: 11, Low: 9, Close: 10, Volume: 100})
+		c := 3000 + float64(i)
+		index = append(index, fetcher.KLine{Date: d, Open: c, High: c, Low: c, Close: c, Volume: 100})
+	}
+	src.Set("sh600000", stock)
+	src.Set("sh000300", index)
+
+	path := filepath.Join(t.TempDir(), "backtest.yaml")
+	yml := "backtest:\n  benchmark: SH000300\n  instruments:\n    stocks: [sh600000]\n"
+	if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadRunConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Benchmark == nil || cfg.Benchmark.Symbol != "sh000300" || cfg.Benchmark.Type != InstrumentTypeStock {
+		t.Fatalf("benchmark instrument %#v", cfg.Benchmark)
+	}
+	results, err := NewRunnerWithSource(src).Run(cfg)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	bm := results[0].Benchmark
+	if bm == nil || bm.Error != "" {
+		t.Fatalf("benchmark %#v", bm)
+	}
+	// flat strategy vs an index that rose 79 points from 3000
+	if want := round2(79.0 / 3000 * 100); bm.ReturnPct != want || bm.ExcessReturnPct != -want {
+		t.Fatalf("benchmark returns %#v", bm)
+	}
+
+	cfg.Benchmark = &Instrument{Symbol: "sh000905", Type: InstrumentTypeStock}
+	results, err = NewRunnerWithSource(src).Run(cfg)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if bm := results[0].Benchmark; bm == nil || bm.Error == "" || len(results[0].Errors) > 0 {
+		t.Fatalf("missing benchmark should be reported on the benchmark only: %#v", results[0])
+	}
+}
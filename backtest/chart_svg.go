@@ -7,6 +7,9 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"time"
+
+	"stock/fetcher"
 )
 
 type ChartLine struct {
@@ -122,8 +125,8 @@ func RenderCandlesSVG(symbol string, bars []Bar, lines []ChartLine, points []Cha
 	buf.WriteString(`<rect x="0" y="0" width="100%" height="100%" fill="` + bg + `"/>` + "\n")
 
 	// Header
-	firstD := bars[0].Time.Format("2006-01-02")
-	lastD := bars[len(bars)-1].Time.Format("2006-01-02")
+	firstD := chartTime(bars[0].Time)
+	lastD := chartTime(bars[len(bars)-1].Time)
 	title := strings.TrimSpace(symbol)
 	if title == "" {
 		title = "UNKNOWN"
@@ -199,17 +202,12 @@ func RenderCandlesSVG(symbol string, bars []Bar, lines []ChartLine, points []Cha
 		if col == "" {
 			col = "#38bdf8"
 		}
-		// locate x by date
-		x := -1.0
-		for i := range bars {
-			if bars[i].Time.Format("2006-01-02") == pt.Date {
-				x = xAt(i)
-				break
-			}
-		}
-		if x < 0 {
+		// locate x by date (or date+minute for intraday bars)
+		idx := barIndexAt(bars, pt.Date)
+		if idx < 0 {
 			continue
 		}
+		x := xAt(idx)
 		y := priceToY(pt.Price)
 		buf.WriteString(`<circle cx="` + fmtFloat(x) + `" cy="` + fmtFloat(y) + `" r="3.5" fill="` + col + `" />` + "\n")
 		label := strings.TrimSpace(pt.Label)
@@ -229,6 +227,29 @@ func RenderCandlesSVG(symbol string, bars []Bar, lines []ChartLine, points []Cha
 	return buf.Bytes(), nil
 }
 
+// chartTime labels a bar: date-only for daily bars, date+minute for intraday bars.
+func chartTime(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 {
+		return t.Format(fetcher.DateLayout)
+	}
+	return t.Format(fetcher.MinuteLayout)
+}
+
+// barIndexAt finds the bar labeled date; a date-only label on intraday bars matches the day's first bar.
+func barIndexAt(bars []Bar, date string) int {
+	for i := range bars {
+		if chartTime(bars[i].Time) == date {
+			return i
+		}
+	}
+	for i := range bars {
+		if bars[i].Time.Format(fetcher.DateLayout) == date {
+			return i
+		}
+	}
+	return -1
+}
+
 func fmtFloat(x float64) string {
 	// stable compact formatting for SVG attributes
 	return strconv.FormatFloat(x, 'f', 2, 64)
@@ -0,0 +1,113 @@
+package backtest
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RenderHistogramSVG draws bins as bars; marks are vertical lines at x = Price
+// (e.g. percentiles or the actual outcome).
+func RenderHistogramSVG(title string, bins []HistogramBin, marks []ChartLine, opt SVGChartOptions) ([]byte, error) {
+	opt = opt.withDefaults()
+	if len(bins) == 0 {
+		return nil, fmt.Errorf("no histogram bins")
+	}
+	minX, maxX := bins[0].Lo, bins[len(bins)-1].Hi
+	maxC := 0
+	for _, b := range bins {
+		if b.Count > maxC {
+			maxC = b.Count
+		}
+	}
+	if maxX <= minX {
+		// single value: widen around it
+		pad := math.Max(math.Abs(minX)*0.01, 1)
+		minX -= pad
+		maxX += pad
+	}
+	if maxC == 0 {
+		return nil, fmt.Errorf("empty histogram")
+	}
+
+	// Layout
+	w := float64(opt.Width)
+	h := float64(opt.Height)
+	mLeft := 70.0
+	mRight := 20.0
+	mTop := 24.0
+	mBottom := 40.0
+	plotW := w - mLeft - mRight
+	plotH := h - mTop - mBottom
+	if plotW <= 10 || plotH <= 10 {
+		return nil, fmt.Errorf("invalid chart size")
+	}
+
+	xAt := func(v float64) float64 {
+		r := math.Max(0, math.Min(1, (v-minX)/(maxX-minX)))
+		return mLeft + r*plotW
+	}
+	yAt := func(c int) float64 {
+		return mTop + (1.0-float64(c)/float64(maxC))*plotH
+	}
+
+	bg := "#0b1220"
+	grid := "rgba(255,255,255,0.08)"
+	bar := "#38bdf8"
+	txt := "rgba(255,255,255,0.85)"
+	font := `font-family="ui-monospace, Menlo, Monaco, Consolas, monospace"`
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="` + strconv.Itoa(opt.Width) + `" height="` + strconv.Itoa(opt.Height) + `" viewBox="0 0 ` + strconv.Itoa(opt.Width) + ` ` + strconv.Itoa(opt.Height) + `">` + "\n")
+	buf.WriteString(`<rect x="0" y="0" width="100%" height="100%" fill="` + bg + `"/>` + "\n")
+	buf.WriteString(`<text x="` + fmtFloat(mLeft) + `" y="16" fill="` + txt + `" font-size="14" ` + font + `>` + html.EscapeString(strings.TrimSpace(title)) + `</text>` + "\n")
+
+	// Grid: count lines (5)
+	for k := 0; k <= 5; k++ {
+		y := mTop + (float64(k)/5.0)*plotH
+		buf.WriteString(`<line x1="` + fmtFloat(mLeft) + `" y1="` + fmtFloat(y) + `" x2="` + fmtFloat(mLeft+plotW) + `" y2="` + fmtFloat(y) + `" stroke="` + grid + `" stroke-width="1"/>` + "\n")
+		c := float64(maxC) * (1 - float64(k)/5.0)
+		buf.WriteString(`<text x="` + fmtFloat(6) + `" y="` + fmtFloat(y+4) + `" fill="` + txt + `" font-size="12" ` + font + `>` + strconv.Itoa(int(math.Round(c))) + `</text>` + "\n")
+	}
+
+	// Bars
+	for _, b := range bins {
+		if b.Count == 0 {
+			continue
+		}
+		x0, x1 := xAt(b.Lo), xAt(b.Hi)
+		bw := math.Max(1, x1-x0-1)
+		y := yAt(b.Count)
+		buf.WriteString(`<rect x="` + fmtFloat(x0) + `" y="` + fmtFloat(y) + `" width="` + fmtFloat(bw) + `" height="` + fmtFloat(mTop+plotH-y) + `" fill="` + bar + `" opacity="0.85"/>` + "\n")
+	}
+
+	// Marks
+	for i, m := range marks {
+		col := strings.TrimSpace(m.Color)
+		if col == "" {
+			col = "rgba(255,255,255,0.65)"
+		}
+		x := xAt(m.Price)
+		style := ""
+		if m.Dash {
+			style = ` stroke-dasharray="6 6"`
+		}
+		buf.WriteString(`<line x1="` + fmtFloat(x) + `" y1="` + fmtFloat(mTop) + `" x2="` + fmtFloat(x) + `" y2="` + fmtFloat(mTop+plotH) + `" stroke="` + col + `" stroke-width="1.2"` + style + `/>` + "\n")
+		if label := strings.TrimSpace(m.Label); label != "" {
+			// stagger labels so neighbouring marks stay readable
+			y := mTop + 14 + float64(i%4)*14
+			buf.WriteString(`<text x="` + fmtFloat(x+4) + `" y="` + fmtFloat(y) + `" fill="` + col + `" font-size="12" ` + font + `>` + html.EscapeString(label) + `</text>` + "\n")
+		}
+	}
+
+	// Footer: x range
+	buf.WriteString(`<text x="` + fmtFloat(mLeft) + `" y="` + fmtFloat(mTop+plotH+mBottom-12) + `" fill="` + txt + `" font-size="12" ` + font + `>` + html.EscapeString(fmtPrice(minX)) + `</text>` + "\n")
+	buf.WriteString(`<text x="` + fmtFloat(mLeft+plotW-70) + `" y="` + fmtFloat(mTop+plotH+mBottom-12) + `" fill="` + txt + `" font-size="12" ` + font + `>` + html.EscapeString(fmtPrice(maxX)) + `</text>` + "\n")
+
+	buf.WriteString(`</svg>` + "\n")
+	return buf.Bytes(), nil
+}
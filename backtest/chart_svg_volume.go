@@ -124,8 +124,8 @@ func RenderCandlesWithVolumeSVG(symbol string, bars []Bar, lines []ChartLine, po
 	buf.WriteString(`<rect x="0" y="0" width="100%" height="100%" fill="` + bg + `"/>` + "\n")
 
 	// Header
-	firstD := bars[0].Time.Format("2006-01-02")
-	lastD := bars[len(bars)-1].Time.Format("2006-01-02")
+	firstD := chartTime(bars[0].Time)
+	lastD := chartTime(bars[len(bars)-1].Time)
 	title := strings.TrimSpace(symbol)
 	if title == "" {
 		title = "UNKNOWN"
@@ -247,16 +247,12 @@ func RenderCandlesWithVolumeSVG(symbol string, bars []Bar, lines []ChartLine, po
 		if col == "" {
 			col = "#38bdf8"
 		}
-		x := -1.0
-		for i := range bars {
-			if bars[i].Time.Format("2006-01-02") == pt.Date {
-				x = xAt(i)
-				break
-			}
-		}
-		if x < 0 {
+		// locate x by date (or date+minute for intraday bars)
+		idx := barIndexAt(bars, pt.Date)
+		if idx < 0 {
 			continue
 		}
+		x := xAt(idx)
 		y := priceToY(pt.Price)
 		buf.WriteString(`<circle cx="` + fmtFloat(x) + `" cy="` + fmtFloat(y) + `" r="3.5" fill="` + col + `" />` + "\n")
 		label := strings.TrimSpace(pt.Label)
@@ -1,13 +1,16 @@
 package backtest
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"sort"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"stock/config"
+	"stock/fetcher"
 )
 
 type YAMLConfig struct {
@@ -22,17 +25,49 @@ type YAMLConfig struct {
 		StockLotSize  int64   `yaml:"stock_lot_size"`
 		FuturesMult   float64 `yaml:"futures_multiplier"`
 		FuturesMargin float64 `yaml:"futures_margin_rate"`
+		Adjust        string  `yaml:"adjust"`
+		Frequency     string  `yaml:"frequency"`
+
+		// Benchmark is compared with every result as buy-and-hold (e.g. sh000300).
+		Benchmark string `yaml:"benchmark"`
 
 		Instruments struct {
 			Stocks  []string `yaml:"stocks"`
 			Futures []string `yaml:"futures"`
 		} `yaml:"instruments"`
+
+		// Costs selects the fee model (simple by default, china opt-in).
+		Costs costsYAML `yaml:"costs"`
+
+		// AShareRules enables T+1, price limits and suspension handling for stocks (opt-in, default false).
+		AShareRules *bool    `yaml:"ashare_rules"`
+		STStocks    []string `yaml:"st_stocks"`
+
+		// IntrabarExits fills strategy stop/target levels inside the bar (opt-in, default false).
+		IntrabarExits    *bool  `yaml:"intrabar_exits"`
+		IntrabarConflict string `yaml:"intrabar_conflict"`
+
+		// Sizing selects the position sizing model (percent of the budget by default).
+		Sizing Sizing `yaml:"sizing"`
+
+		Portfolio struct {
+			Enabled      bool `yaml:"enabled"`
+			MaxPositions int  `yaml:"max_positions"`
+		} `yaml:"portfolio"`
+
+		// ContractSpecs overrides the built-in futures contract table per product code.
+		ContractSpecs map[string]ContractSpec `yaml:"contract_specs"`
+
+		// Continuous maps a continuous symbol (e.g. nf_I888) to the contracts it is stitched from.
+		Continuous map[string]continuousYAML `yaml:"continuous"`
 	} `yaml:"backtest"`
 
 	Strategy struct {
 		Type   string         `yaml:"type"`
 		Params map[string]any `yaml:"params"`
 	} `yaml:"strategy"`
+
+	Data config.DataConfig `yaml:"data"`
 }
 
 type RunConfig struct {
@@ -45,8 +80,59 @@ type RunConfig struct {
 	CommissionBps float64
 	FuturesMargin float64
 
+	// FuturesMultiplier and FuturesMargin apply to products missing from ContractSpecs.
+	FuturesMultiplier float64
+	// ContractSpecs is the built-in futures table merged with backtest.contract_specs.
+	ContractSpecs ContractSpecs
+
+	// Adjust is the price adjustment for stock bars (futures are never adjusted).
+	Adjust fetcher.Adjust
+	// Frequency is the bar period (daily by default, or 1/5/15/30/60-minute bars).
+	// Days counts bars of this period.
+	Frequency fetcher.Period
+
 	Instruments []Instrument
 	Strategy    Strategy
+	// StrategyType and StrategyParams are strategy.type/params as loaded,
+	// so tools like the optimizer can build variants of the strategy.
+	StrategyType   string
+	StrategyParams map[string]any
+
+	// Benchmark is held buy-and-hold alongside every result (nil = none).
+	Benchmark *Instrument
+
+	// Costs prices every fill; nil means SimpleCosts{CommissionBps}.
+	Costs CostModel
+
+	// AShareRules applies the A-share rule layer to stocks: T+1, daily price
+	// limits by board (STStocks get ±5% on the main board) and suspensions.
+	AShareRules bool
+	STStocks    map[string]bool
+
+	// IntrabarExits fills protective stop/target orders at the open on a gap or
+	// at the level on a touch; IntrabarConflict picks the fill when a bar touches both.
+	IntrabarExits    bool
+	IntrabarConflict IntrabarConflict
+
+	// Sizing sizes new positions within the budget; see Sizing.
+	Sizing Sizing
+
+	// Portfolio runs all instruments on one shared account (RunPortfolio)
+	// instead of one full-cash account per instrument.
+	Portfolio bool
+	// MaxPositions caps concurrent open positions in portfolio mode (0 = one per instrument).
+	MaxPositions int
+
+	// Continuous maps continuous futures symbols to their stitching rules;
+	// those symbols are backtested on the back-adjusted series.
+	Continuous map[string]ContinuousSpec
+
+	// TradeFrom starts trading at this time: earlier bars in the window only
+	// warm up the strategy (no fills, no equity points). Zero = trade every bar.
+	TradeFrom time.Time
+
+	// Data selects the bar source(s); see fetcher.NewBarSourceFromConfig.
+	Data config.DataConfig
 
 	// Scan-only options (not loaded from YAML)
 	ScanChart     bool
@@ -56,17 +142,52 @@ type RunConfig struct {
 
 func DefaultRunConfig() RunConfig {
 	return RunConfig{
-		Days:          5000,
-		InitialCash:   1_000_000,
-		PositionPct:   1.0,
-		SlippageBps:   5,
-		CommissionBps: 1,
-		FuturesMargin: 1.0,
-		Instruments:   nil,
-		Strategy:      NewTsaiSenStrategy(TsaiSenParams{}),
+		Days:              5000,
+		InitialCash:       1_000_000,
+		PositionPct:       1.0,
+		SlippageBps:       5,
+		CommissionBps:     1,
+		FuturesMargin:     1.0,
+		FuturesMultiplier: 1,
+		ContractSpecs:     DefaultContractSpecs(),
+		Adjust:            fetcher.AdjustForward,
+		Frequency:         fetcher.PeriodDaily,
+		IntrabarConflict:  ConflictStop,
+		Sizing:            Sizing{Model: SizingPercent},
+		Instruments:       nil,
+		Strategy:          NewTsaiSenStrategy(TsaiSenParams{}),
 	}
 }
 
+// FormatTime formats a bar time for reports: date-only for daily bars, minutes for intraday bars.
+func (cfg RunConfig) FormatTime(t time.Time) string {
+	return cfg.period().FormatTime(t)
+}
+
+func (cfg RunConfig) period() fetcher.Period {
+	return fetcher.KLineOptions{Period: cfg.Frequency}.KLinePeriod()
+}
+
+// inWindow reports whether a bar time falls inside Start..End (End is inclusive:
+// every intraday bar of that day is kept).
+func (cfg RunConfig) inWindow(t time.Time) bool {
+	if !cfg.Start.IsZero() && t.Before(cfg.Start) {
+		return false
+	}
+	if !cfg.End.IsZero() && !t.Before(cfg.End.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// AdjustFor returns the adjustment mode the bars of inst are loaded with.
+func (cfg RunConfig) AdjustFor(inst Instrument) fetcher.Adjust {
+	if inst.Type != InstrumentTypeStock {
+		return fetcher.AdjustNone
+	}
+	return fetcher.KLineOptions{Adjust: cfg.Adjust}.StockAdjust()
+}
+
 func LoadRunConfig(path string) (RunConfig, error) {
 	raw, err := os.ReadFile(path)
 	if err != nil {
@@ -99,18 +220,73 @@ func LoadRunConfig(path string) (RunConfig, error) {
 		cfg.FuturesMargin = yc.Backtest.FuturesMargin
 	}
 
+	adjust, err := fetcher.ParseAdjust(yc.Backtest.Adjust)
+	if err != nil {
+		return RunConfig{}, fmt.Errorf("invalid backtest.adjust: %w", err)
+	}
+	cfg.Adjust = adjust
+
+	freq, err := fetcher.ParsePeriod(yc.Backtest.Frequency)
+	if err != nil {
+		return RunConfig{}, fmt.Errorf("invalid backtest.frequency: %w", err)
+	}
+	cfg.Frequency = freq
+
 	stockLotSize := yc.Backtest.StockLotSize
 	if stockLotSize <= 0 {
 		stockLotSize = 100
 	}
-	futuresMult := yc.Backtest.FuturesMult
-	if futuresMult <= 0 {
-		futuresMult = 1
+	costs, err := yc.Backtest.Costs.toModel(cfg.CommissionBps)
+	if err != nil {
+		return RunConfig{}, fmt.Errorf("invalid backtest.costs: %w", err)
 	}
+	cfg.Costs = costs
+
+	if yc.Backtest.AShareRules != nil {
+		cfg.AShareRules = *yc.Backtest.AShareRules
+	}
+	for _, s := range yc.Backtest.STStocks {
+		if s = config.NormalizeStockCode(s); s != "" {
+			if cfg.STStocks == nil {
+				cfg.STStocks = map[string]bool{}
+			}
+			cfg.STStocks[s] = true
+		}
+	}
+
+	if yc.Backtest.IntrabarExits != nil {
+		cfg.IntrabarExits = *yc.Backtest.IntrabarExits
+	}
+	conflict, err := ParseIntrabarConflict(yc.Backtest.IntrabarConflict)
+	if err != nil {
+		return RunConfig{}, fmt.Errorf("invalid backtest.intrabar_conflict: %w", err)
+	}
+	cfg.IntrabarConflict = conflict
+
+	sizing, err := yc.Backtest.Sizing.normalized()
+	if err != nil {
+		return RunConfig{}, fmt.Errorf("invalid backtest.sizing: %w", err)
+	}
+	cfg.Sizing = sizing
+
+	cfg.Portfolio = yc.Backtest.Portfolio.Enabled
+	if yc.Backtest.Portfolio.MaxPositions < 0 {
+		return RunConfig{}, fmt.Errorf("invalid backtest.portfolio.max_positions: must be >= 0")
+	}
+	cfg.MaxPositions = yc.Backtest.Portfolio.MaxPositions
+
+	if yc.Backtest.FuturesMult > 0 {
+		cfg.FuturesMultiplier = yc.Backtest.FuturesMult
+	}
+	specs, err := cfg.ContractSpecs.Merge(yc.Backtest.ContractSpecs)
+	if err != nil {
+		return RunConfig{}, fmt.Errorf("invalid backtest.contract_specs: %w", err)
+	}
+	cfg.ContractSpecs = specs
 
 	var instruments []Instrument
 	for _, s := range yc.Backtest.Instruments.Stocks {
-		sym := s
+		sym := config.NormalizeStockCode(s)
 		if sym == "" {
 			continue
 		}
@@ -125,14 +301,34 @@ func LoadRunConfig(path string) (RunConfig, error) {
 		if sym == "" {
 			continue
 		}
-		instruments = append(instruments, Instrument{
-			Symbol:     sym,
-			Type:       InstrumentTypeFutures,
-			Multiplier: futuresMult,
-			AllowShort: true,
-		})
+		instruments = append(instruments, cfg.FuturesInstrument(sym))
+	}
+	if len(yc.Backtest.Continuous) > 0 {
+		cfg.Continuous = make(map[string]ContinuousSpec, len(yc.Backtest.Continuous))
+		// sorted so the instrument order does not depend on map iteration
+		names := make([]string, 0, len(yc.Backtest.Continuous))
+		for name := range yc.Backtest.Continuous {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			spec, err := yc.Backtest.Continuous[name].toSpec()
+			if err != nil {
+				return RunConfig{}, fmt.Errorf("invalid backtest.continuous.%s: %w", name, err)
+			}
+			sym := config.NormalizeFuturesCode(name)
+			cfg.Continuous[sym] = spec
+			if hasInstrument(instruments, sym) {
+				continue
+			}
+			instruments = append(instruments, cfg.FuturesInstrument(sym))
+		}
 	}
 	cfg.Instruments = instruments
+	cfg.Data = yc.Data
+	if inst, ok := cfg.benchmarkInstrument(yc.Backtest.Benchmark); ok {
+		cfg.Benchmark = &inst
+	}
 
 	if yc.Backtest.Start != "" {
 		t, err := time.ParseInLocation("2006-01-02", yc.Backtest.Start, time.Local)
@@ -149,26 +345,57 @@ func LoadRunConfig(path string) (RunConfig, error) {
 		cfg.End = t
 	}
 
-	switch yc.Strategy.Type {
-	case "", "tsai_sen":
-		var p TsaiSenParams
-		if yc.Strategy.Params != nil {
-			b, _ := yaml.Marshal(yc.Strategy.Params)
-			_ = yaml.Unmarshal(b, &p)
-		}
-		p = p.withDefaults()
-		cfg.Strategy = NewTsaiSenStrategy(p)
-	case "patterns":
-		var p PatternsParams
-		if yc.Strategy.Params != nil {
-			b, _ := yaml.Marshal(yc.Strategy.Params)
-			_ = yaml.Unmarshal(b, &p)
-		}
-		p = p.withDefaults()
-		cfg.Strategy = NewPatternsStrategy(p)
-	default:
-		return RunConfig{}, fmt.Errorf("unknown strategy.type: %s", yc.Strategy.Type)
+	strategy, err := NewStrategy(yc.Strategy.Type, yc.Strategy.Params)
+	if err != nil {
+		return RunConfig{}, err
 	}
+	if err := cfg.checkTimeframes(strategy); err != nil {
+		return RunConfig{}, fmt.Errorf("invalid strategy.params: %w", err)
+	}
+	cfg.Strategy = strategy
+	cfg.StrategyType = yc.Strategy.Type
+	cfg.StrategyParams = yc.Strategy.Params
 
 	return cfg, nil
 }
+
+// NewStrategy builds the strategy named by strategy.type from its params map.
+func NewStrategy(typ string, params map[string]any) (Strategy, error) {
+	return newStrategy(typ, params, false)
+}
+
+func newStrategy(typ string, params map[string]any, strict bool) (Strategy, error) {
+	e, err := lookupStrategy(typ)
+	if err != nil {
+		return nil, err
+	}
+	return e.build(params, strict)
+}
+
+// decodeParams decodes a strategy.params map into a params struct. Loading a
+// config stays lenient (malformed values keep their defaults); strict mode
+// rejects unknown names and bad values.
+func decodeParams(params map[string]any, out any, strict bool) error {
+	if params == nil {
+		return nil
+	}
+	b, err := yaml.Marshal(params)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(strict)
+	if err := dec.Decode(out); err != nil && strict {
+		return fmt.Errorf("strategy.params: %w", err)
+	}
+	return nil
+}
+
+func hasInstrument(instruments []Instrument, symbol string) bool {
+	for _, inst := range instruments {
+		if inst.Symbol == symbol {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"stock/config"
+	"stock/fetcher"
+)
+
+// RollRule decides when a continuous series switches to the next contract.
+type RollRule string
+
+const (
+	// RollByOpenInterest rolls once the next contract's open interest exceeds the current one.
+	RollByOpenInterest RollRule = "open_interest"
+	// RollByVolume rolls once the next contract's volume exceeds the current one.
+	RollByVolume RollRule = "volume"
+	// RollByExpiry rolls RollDays trading days before the current contract expires.
+	RollByExpiry RollRule = "expiry"
+)
+
+// BackAdjust is how history before a roll is shifted to remove the roll gap.
+type BackAdjust string
+
+const (
+	// BackAdjustDifference adds the price gap (new - old) to earlier bars.
+	BackAdjustDifference BackAdjust = "difference"
+	// BackAdjustRatio multiplies earlier bars by new/old; keeps prices positive and returns intact.
+	BackAdjustRatio BackAdjust = "ratio"
+	// BackAdjustNone splices raw prices (gaps stay in the series).
+	BackAdjustNone BackAdjust = "none"
+)
+
+// ContinuousSpec describes how to stitch individual contracts into one continuous series.
+type ContinuousSpec struct {
+	// Contracts in delivery order, e.g. nf_I2501, nf_I2505, nf_I2509.
+	Contracts []string
+	Roll      RollRule
+	// RollDays is the number of trading days before expiry to roll (RollByExpiry only).
+	RollDays int
+	Adjust   BackAdjust
+	// Expiries overrides the last trading day per contract; by default it is
+	// derived from the YYMM suffix (15th of the delivery month).
+	Expiries map[string]time.Time
+}
+
+// Roll records one contract switch of a continuous series.
+type Roll struct {
+	// Date is the first bar taken from the new contract.
+	Date      string  `json:"date"`
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	FromClose float64 `json:"from_close"`
+	ToClose   float64 `json:"to_close"`
+	// Adjustment applied to the history before Date: the price gap for
+	// difference, the factor for ratio, 0 when unadjusted.
+	Adjustment float64 `json:"adjustment,omitempty"`
+}
+
+type continuousYAML struct {
+	Contracts []string          `yaml:"contracts"`
+	Roll      string            `yaml:"roll"`
+	RollDays  int               `yaml:"roll_days"`
+	Adjust    string            `yaml:"adjust"`
+	Expiries  map[string]string `yaml:"expiries"`
+}
+
+func (y continuousYAML) toSpec() (ContinuousSpec, error) {
+	spec := ContinuousSpec{
+		Roll:     RollRule(strings.ToLower(strings.TrimSpace(y.Roll))),
+		RollDays: y.RollDays,
+		Adjust:   BackAdjust(strings.ToLower(strings.TrimSpace(y.Adjust))),
+	}
+	for _, c := range y.Contracts {
+		if sym := config.NormalizeFuturesCode(c); sym != "" {
+			spec.Contracts = append(spec.Contracts, sym)
+		}
+	}
+	if len(spec.Contracts) == 0 {
+		return ContinuousSpec{}, fmt.Errorf("contracts must not be empty")
+	}
+	switch spec.Roll {
+	case "":
+		spec.Roll = RollByOpenInterest
+	case RollByOpenInterest, RollByVolume, RollByExpiry:
+	default:
+		return ContinuousSpec{}, fmt.Errorf("unknown roll: %s (open_interest|volume|expiry)", y.Roll)
+	}
+	if spec.RollDays < 0 {
+		return ContinuousSpec{}, fmt.Errorf("roll_days must be >= 0")
+	}
+	if spec.RollDays == 0 && spec.Roll == RollByExpiry {
+		spec.RollDays = 5
+	}
+	switch spec.Adjust {
+	case "":
+		spec.Adjust = BackAdjustRatio
+	case BackAdjustDifference, BackAdjustRatio, BackAdjustNone:
+	default:
+		return ContinuousSpec{}, fmt.Errorf("unknown adjust: %s (difference|ratio|none)", y.Adjust)
+	}
+	for c, d := range y.Expiries {
+		t, err := time.ParseInLocation(fetcher.DateLayout, strings.TrimSpace(d), time.Local)
+		if err != nil {
+			return ContinuousSpec{}, fmt.Errorf("expiries[%s]: %w", c, err)
+		}
+		if spec.Expiries == nil {
+			spec.Expiries = map[string]time.Time{}
+		}
+		spec.Expiries[config.NormalizeFuturesCode(c)] = t
+	}
+	return spec, nil
+}
+
+// expiry returns the last trading day used by RollByExpiry.
+func (s ContinuousSpec) expiry(contract string, bars []Bar) time.Time {
+	if t, ok := s.Expiries[contract]; ok {
+		return t
+	}
+	if t, ok := expiryFromCode(contract); ok {
+		return t
+	}
+	if len(bars) > 0 {
+		return bars[len(bars)-1].Time
+	}
+	return time.Time{}
+}
+
+// expiryFromCode derives an approximate expiry from a YYMM suffix (nf_I2501 -> 2025-01-15).
+func expiryFromCode(contract string) (time.Time, bool) {
+	i := len(contract)
+	for i > 0 && contract[i-1] >= '0' && contract[i-1] <= '9' {
+		i--
+	}
+	digits := contract[i:]
+	if len(digits) != 4 {
+		return time.Time{}, false
+	}
+	yy, _ := strconv.Atoi(digits[:2])
+	mm, _ := strconv.Atoi(digits[2:])
+	if mm < 1 || mm > 12 {
+		return time.Time{}, false
+	}
+	return time.Date(2000+yy, time.Month(mm), 15, 0, 0, 0, 0, time.Local), true
+}
+
+// BuildContinuous stitches per-contract bars into one back-adjusted series.
+// Roll decisions use the close of a bar and take effect from the next bar, so
+// the series never looks ahead.
+func BuildContinuous(spec ContinuousSpec, series map[string][]Bar) ([]Bar, []Roll, error) {
+	type leg struct {
+		symbol string
+		bars   []Bar
+		byTime map[int64]int
+		rollAt time.Time // RollByExpiry: last bar to hold this contract
+	}
+	var legs []*leg
+	for _, c := range spec.Contracts {
+		bars := series[c]
+		if len(bars) == 0 {
+			continue
+		}
+		l := &leg{symbol: c, bars: bars, byTime: make(map[int64]int, len(bars))}
+		for i, b := range bars {
+			l.byTime[b.Time.Unix()] = i
+		}
+		if spec.Roll == RollByExpiry {
+			exp := spec.expiry(c, bars)
+			n := sort.Search(len(bars), func(i int) bool { return bars[i].Time.After(exp) })
+			k := n - 1 - spec.RollDays
+			if k < 0 {
+				k = 0
+			}
+			l.rollAt = bars[k].Time
+		}
+		legs = append(legs, l)
+	}
+	if len(legs) == 0 {
+		return nil, nil, fmt.Errorf("no bars for any contract")
+	}
+
+	// Union of bar times across contracts.
+	seen := map[int64]time.Time{}
+	for _, l := range legs {
+		for _, b := range l.bars {
+			seen[b.Time.Unix()] = b.Time
+		}
+	}
+	times := make([]time.Time, 0, len(seen))
+	for _, t := range seen {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	var (
+		out      []Bar
+		rolls    []Roll
+		segStart []int // index in out where each roll's new segment starts
+		cur      = 0
+	)
+	for _, t := range times {
+		key := t.Unix()
+		i, ok := legs[cur].byTime[key]
+		if !ok {
+			// Current contract has no bar here: before its listing, or expired.
+			if len(out) == 0 || t.After(legs[cur].bars[len(legs[cur].bars)-1].Time) {
+				// fall through to the forced-roll check below
+			} else {
+				continue
+			}
+		}
+		if ok {
+			out = append(out, legs[cur].bars[i])
+		}
+
+		// Decide at this close whether the next bar comes from the next contract.
+		if cur+1 >= len(legs) {
+			continue
+		}
+		next := legs[cur+1]
+		j, nextOK := next.byTime[key]
+		if !nextOK {
+			continue
+		}
+		roll := !ok
+		if ok {
+			switch spec.Roll {
+			case RollByOpenInterest:
+				roll = next.bars[j].OpenInterest > legs[cur].bars[i].OpenInterest
+			case RollByVolume:
+				roll = next.bars[j].Volume > legs[cur].bars[i].Volume
+			case RollByExpiry:
+				roll = !t.Before(legs[cur].rollAt)
+			}
+			if i == len(legs[cur].bars)-1 {
+				roll = true
+			}
+		}
+		if !roll {
+			continue
+		}
+		if !ok {
+			// Expired without an overlapping bar: splice at the next contract's bar.
+			out = append(out, next.bars[j])
+		}
+
+		r := Roll{From: legs[cur].symbol, To: next.symbol, ToClose: next.bars[j].Close}
+		if ok {
+			r.FromClose = legs[cur].bars[i].Close
+		}
+		rolls = append(rolls, r)
+		segStart = append(segStart, len(out))
+		cur++
+	}
+
+	// Fill roll dates and back-adjust earlier history, newest roll first so
+	// factors accumulate toward the past.
+	for k := len(rolls) - 1; k >= 0; k-- {
+		start := segStart[k]
+		if start < len(out) {
+			rolls[k].Date = out[start].Time.Format(fetcher.DateLayout)
+		}
+		if rolls[k].FromClose <= 0 || rolls[k].ToClose <= 0 {
+			continue
+		}
+		switch spec.Adjust {
+		case BackAdjustDifference:
+			d := rolls[k].ToClose - rolls[k].FromClose
+			rolls[k].Adjustment = round2(d)
+			for i := 0; i < start; i++ {
+				out[i].Open += d
+				out[i].High += d
+				out[i].Low += d
+				out[i].Close += d
+			}
+		case BackAdjustRatio:
+			f := rolls[k].ToClose / rolls[k].FromClose
+			rolls[k].Adjustment = round4(f)
+			for i := 0; i < start; i++ {
+				out[i].Open *= f
+				out[i].High *= f
+				out[i].Low *= f
+				out[i].Close *= f
+			}
+		}
+	}
+	// Rolls that never produced a bar (series ended on the roll bar) are dropped.
+	kept := rolls[:0]
+	for _, r := range rolls {
+		if r.Date != "" {
+			kept = append(kept, r)
+		}
+	}
+	return out, kept, nil
+}
+
+func round4(x float64) float64 {
+	return math.Round(x*10000) / 10000
+}
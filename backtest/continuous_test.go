@@ -0,0 +1,150 @@
+package backtest
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stock/fetcher"
+)
+
+// contractBars builds daily bars from day `from` (inclusive) to `to` (exclusive)
+// with constant close and a linear open interest.
+func contractBars(start time.Time, from, to int, close float64, oi func(i int) int64) []Bar {
+	var out []Bar
+	for i := from; i < to; i++ {
+		out = append(out, Bar{
+			Time: start.AddDate(0, 0, i), Open: close, High: close + 1, Low: close - 1, Close: close,
+			Volume: 100, OpenInterest: oi(i),
+		})
+	}
+	return out
+}
+
+func TestBuildContinuousOpenInterestRoll(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	series := map[string][]Bar{
+		// front month loses OI, next month gains it; they cross at day 11
+		"nf_I2501": contractBars(start, 0, 20, 100, func(i int) int64 { return int64(1000 - 50*i) }),
+		"nf_I2505": contractBars(start, 5, 30, 110, func(i int) int64 { return int64(50 * i) }),
+	}
+
+	for _, tc := range []struct {
+		adjust BackAdjust
+		want   float64 // adjusted close of the first bar
+		factor float64
+	}{
+		{BackAdjustDifference, 110, 10},
+		{BackAdjustRatio, 110, 1.1},
+		{BackAdjustNone, 100, 0},
+	} {
+		spec := ContinuousSpec{Contracts: []string{"nf_I2501", "nf_I2505"}, Roll: RollByOpenInterest, Adjust: tc.adjust}
+		bars, rolls, err := BuildContinuous(spec, series)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.adjust, err)
+		}
+		if len(bars) != 30 {
+			t.Fatalf("%s: expected 30 bars, got %d", tc.adjust, len(bars))
+		}
+		if len(rolls) != 1 {
+			t.Fatalf("%s: expected 1 roll, got %#v", tc.adjust, rolls)
+		}
+		// OI crosses at the close of day 11 (550 > 450); the new contract starts on day 12.
+		r := rolls[0]
+		if r.Date != "2024-01-13" || r.From != "nf_I2501" || r.To != "nf_I2505" {
+			t.Fatalf("%s: unexpected roll %#v", tc.adjust, r)
+		}
+		if r.Adjustment != tc.factor {
+			t.Fatalf("%s: adjustment = %v, want %v", tc.adjust, r.Adjustment, tc.factor)
+		}
+		if math.Abs(bars[0].Close-tc.want) > 1e-9 {
+			t.Fatalf("%s: first close = %v, want %v", tc.adjust, bars[0].Close, tc.want)
+		}
+		if bars[12].Close != 110 {
+			t.Fatalf("%s: first bar after roll should be raw, got %v", tc.adjust, bars[12].Close)
+		}
+	}
+}
+
+func TestBuildContinuousExpiryRoll(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	flat := func(int) int64 { return 0 }
+	series := map[string][]Bar{
+		"nf_I2501": contractBars(start, 0, 20, 100, flat),
+		"nf_I2505": contractBars(start, 0, 30, 100, flat),
+	}
+	spec := ContinuousSpec{
+		Contracts: []string{"nf_I2501", "nf_I2505"},
+		Roll:      RollByExpiry,
+		RollDays:  3,
+		Adjust:    BackAdjustRatio,
+		Expiries:  map[string]time.Time{"nf_I2501": start.AddDate(0, 0, 15)},
+	}
+	_, rolls, err := BuildContinuous(spec, series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// expiry day 15, 3 bars before is day 12; the next contract starts on day 13
+	if len(rolls) != 1 || rolls[0].Date != "2024-01-14" {
+		t.Fatalf("unexpected rolls %#v", rolls)
+	}
+}
+
+func TestRunnerContinuousFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backtest.yaml")
+	yml := `backtest:
+  continuous:
+    I888:
+      contracts: [i2501, nf_I2505]
+      roll: volume
+      adjust: difference
+`
+	if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadRunConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.Instruments) != 1 || cfg.Instruments[0].Symbol != "nf_I888" || cfg.Instruments[0].Type != InstrumentTypeFutures {
+		t.Fatalf("unexpected instruments %#v", cfg.Instruments)
+	}
+
+	src := fetcher.NewMemoryBarSource()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	mk := func(from, to int, close float64, vol func(i int) int64) []fetcher.KLine {
+		var kl []fetcher.KLine
+		for i := from; i < to; i++ {
+			kl = append(kl, fetcher.KLine{Date: start.AddDate(0, 0, i).Format(fetcher.DateLayout), Open: close, High: close, Low: close, Close: close, Volume: vol(i)})
+		}
+		return kl
+	}
+	src.Set("nf_I2501", mk(0, 50, 800, func(i int) int64 { return int64(1000 - 10*i) }))
+	src.Set("nf_I2505", mk(20, 80, 820, func(i int) int64 { return int64(10 * i) }))
+
+	results, err := NewRunnerWithSource(src).Run(cfg)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	res := results[0]
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors: %v", res.Errors)
+	}
+	if len(res.EquityCurve) != 80 {
+		t.Fatalf("expected 80 equity points, got %d", len(res.EquityCurve))
+	}
+	// volume would only cross on day 51, but nf_I2501 ends on day 49: forced roll
+	if len(res.Rolls) != 1 || res.Rolls[0].Adjustment != 20 {
+		t.Fatalf("unexpected rolls %#v", res.Rolls)
+	}
+}
+
+func TestRound4Negative(t *testing.T) {
+	for _, c := range []struct{ in, want float64 }{{1.23456, 1.2346}, {-1.23456, -1.2346}, {-0.00004, 0}, {-0.00006, -0.0001}, {-0.61234, -0.6123}} {
+		if got := round4(c.in); got != c.want {
+			t.Errorf("round4(%v) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
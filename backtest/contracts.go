@@ -0,0 +1,233 @@
+package backtest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContractSpec is the trading specification of one futures product.
+type ContractSpec struct {
+	Exchange   string  `yaml:"exchange" json:"exchange,omitempty"`
+	Multiplier float64 `yaml:"multiplier" json:"multiplier"`
+	TickSize   float64 `yaml:"tick_size" json:"tick_size,omitempty"`
+	// MarginRate is the fraction of notional held as margin (0.12 = 12%).
+	MarginRate float64 `yaml:"margin_rate" json:"margin_rate,omitempty"`
+	// CommissionPerLot is a fixed fee per lot; when set it replaces CommissionBps.
+	CommissionPerLot float64 `yaml:"commission_per_lot" json:"commission_per_lot,omitempty"`
+	CommissionBps    float64 `yaml:"commission_bps" json:"commission_bps,omitempty"`
+	// CloseTodayRatio scales the fee for closing a position opened the same
+	// day (CFFEX index futures 10x, some products 0); nil = same fee.
+	CloseTodayRatio *float64 `yaml:"close_today_ratio" json:"close_today_ratio,omitempty"`
+}
+
+// ContractSpecs maps product codes (I, RB, AU, IF, ...) to their specification.
+type ContractSpecs map[string]ContractSpec
+
+func perLot(exchange string, mult, tick, margin, fee float64) ContractSpec {
+	return ContractSpec{Exchange: exchange, Multiplier: mult, TickSize: tick, MarginRate: margin, CommissionPerLot: fee}
+}
+
+func byBps(exchange string, mult, tick, margin, bps float64) ContractSpec {
+	return ContractSpec{Exchange: exchange, Multiplier: mult, TickSize: tick, MarginRate: margin, CommissionBps: bps}
+}
+
+func closeToday(spec ContractSpec, ratio float64) ContractSpec {
+	spec.CloseTodayRatio = &ratio
+	return spec
+}
+
+// builtinContractSpecs holds exchange-level defaults for common domestic products.
+// Margin rates and fees are typical exchange minimums; brokers add on top, so
+// override them in backtest.contract_specs when you know your own.
+var builtinContractSpecs = ContractSpecs{
+	// 上期所 SHFE
+	"CU": byBps("SHFE", 5, 10, 0.10, 0.5),
+	"AL": perLot("SHFE", 5, 5, 0.10, 3),
+	"ZN": perLot("SHFE", 5, 5, 0.10, 3),
+	"PB": byBps("SHFE", 5, 5, 0.10, 0.4),
+	"NI": perLot("SHFE", 1, 10, 0.12, 3),
+	"SN": perLot("SHFE", 1, 10, 0.12, 3),
+	"AU": closeToday(perLot("SHFE", 1000, 0.02, 0.10, 10), 0),
+	"AG": byBps("SHFE", 15, 1, 0.12, 0.5),
+	"RB": byBps("SHFE", 10, 1, 0.10, 1),
+	"HC": byBps("SHFE", 10, 1, 0.10, 1),
+	"SS": perLot("SHFE", 5, 5, 0.10, 2),
+	"BU": byBps("SHFE", 10, 1, 0.12, 1),
+	"RU": perLot("SHFE", 10, 5, 0.10, 3),
+	"FU": byBps("SHFE", 10, 1, 0.12, 0.5),
+	"SP": byBps("SHFE", 10, 2, 0.10, 0.5),
+	"AO": byBps("SHFE", 20, 1, 0.09, 1),
+	"BR": byBps("SHFE", 5, 5, 0.12, 0.2),
+	// 上期能源 INE
+	"SC": perLot("INE", 1000, 0.1, 0.12, 20),
+	"LU": byBps("INE", 10, 1, 0.10, 0.1),
+	"NR": byBps("INE", 10, 5, 0.10, 0.2),
+	"BC": byBps("INE", 5, 10, 0.10, 0.1),
+	"EC": byBps("INE", 50, 0.1, 0.12, 6),
+	// 大商所 DCE
+	"I":  byBps("DCE", 100, 0.5, 0.12, 1),
+	"J":  byBps("DCE", 100, 0.5, 0.20, 1),
+	"JM": byBps("DCE", 60, 0.5, 0.20, 1),
+	"M":  perLot("DCE", 10, 1, 0.08, 1.5),
+	"Y":  perLot("DCE", 10, 2, 0.08, 2.5),
+	"P":  perLot("DCE", 10, 2, 0.10, 2.5),
+	"A":  perLot("DCE", 10, 1, 0.08, 2),
+	"B":  perLot("DCE", 10, 1, 0.08, 1),
+	"C":  perLot("DCE", 10, 1, 0.08, 1.2),
+	"CS": perLot("DCE", 10, 1, 0.08, 1.5),
+	"L":  perLot("DCE", 5, 1, 0.08, 1),
+	"V":  perLot("DCE", 5, 1, 0.08, 1),
+	"PP": perLot("DCE", 5, 1, 0.08, 1),
+	"EG": perLot("DCE", 10, 1, 0.08, 3),
+	"EB": perLot("DCE", 5, 1, 0.08, 3),
+	"PG": perLot("DCE", 20, 1, 0.08, 6),
+	"JD": byBps("DCE", 10, 1, 0.09, 1.5),
+	"LH": byBps("DCE", 16, 5, 0.12, 2),
+	// 郑商所 CZCE
+	"MA": perLot("CZCE", 10, 1, 0.08, 2),
+	"TA": perLot("CZCE", 5, 2, 0.07, 3),
+	"SR": perLot("CZCE", 10, 1, 0.07, 3),
+	"CF": perLot("CZCE", 5, 5, 0.07, 4.3),
+	"OI": perLot("CZCE", 10, 1, 0.09, 2),
+	"RM": perLot("CZCE", 10, 1, 0.09, 1.5),
+	"FG": perLot("CZCE", 20, 1, 0.09, 6),
+	"SA": perLot("CZCE", 20, 1, 0.09, 3.5),
+	"AP": perLot("CZCE", 10, 1, 0.10, 5),
+	"UR": perLot("CZCE", 20, 1, 0.08, 5),
+	"SF": perLot("CZCE", 5, 2, 0.12, 3),
+	"SM": perLot("CZCE", 5, 2, 0.12, 3),
+	"PF": perLot("CZCE", 5, 2, 0.08, 3),
+	"SH": byBps("CZCE", 30, 1, 0.10, 1),
+	"PX": byBps("CZCE", 5, 2, 0.10, 1),
+	"CJ": perLot("CZCE", 5, 5, 0.12, 3),
+	"PK": perLot("CZCE", 5, 2, 0.10, 4),
+	// 中金所 CFFEX
+	"IF": closeToday(byBps("CFFEX", 300, 0.2, 0.12, 0.23), 10),
+	"IH": closeToday(byBps("CFFEX", 300, 0.2, 0.12, 0.23), 10),
+	"IC": closeToday(byBps("CFFEX", 200, 0.2, 0.14, 0.23), 10),
+	"IM": closeToday(byBps("CFFEX", 200, 0.2, 0.14, 0.23), 10),
+	"T":  perLot("CFFEX", 10000, 0.005, 0.02, 3),
+	"TF": perLot("CFFEX", 10000, 0.005, 0.012, 3),
+	"TS": perLot("CFFEX", 20000, 0.002, 0.005, 3),
+	"TL": perLot("CFFEX", 10000, 0.01, 0.035, 3),
+	// 广期所 GFEX
+	"SI": byBps("GFEX", 5, 5, 0.09, 1),
+	"LC": byBps("GFEX", 1, 50, 0.09, 0.8),
+}
+
+// DefaultContractSpecs returns a copy of the built-in contract table.
+func DefaultContractSpecs() ContractSpecs {
+	out := make(ContractSpecs, len(builtinContractSpecs))
+	for k, v := range builtinContractSpecs {
+		out[k] = v
+	}
+	return out
+}
+
+// ProductCode extracts the product code from a futures symbol:
+// nf_I2501 / nf_I0 / i2501 -> I; hf_CL -> HF_CL.
+func ProductCode(symbol string) string {
+	s := strings.TrimSpace(symbol)
+	if len(s) >= 3 && strings.EqualFold(s[:3], "nf_") {
+		s = s[3:]
+	}
+	s = strings.TrimRight(s, "0123456789")
+	return strings.ToUpper(s)
+}
+
+// Lookup returns the spec of the product that symbol belongs to.
+func (cs ContractSpecs) Lookup(symbol string) (ContractSpec, bool) {
+	spec, ok := cs[ProductCode(symbol)]
+	return spec, ok
+}
+
+// Merge overlays overrides onto cs field by field (zero fields keep the
+// built-in value) and returns the result; cs is not modified.
+func (cs ContractSpecs) Merge(overrides map[string]ContractSpec) (ContractSpecs, error) {
+	out := make(ContractSpecs, len(cs)+len(overrides))
+	for k, v := range cs {
+		out[k] = v
+	}
+	for product, o := range overrides {
+		key := ProductCode(product)
+		if key == "" {
+			return nil, fmt.Errorf("empty product code")
+		}
+		if o.Multiplier < 0 || o.TickSize < 0 || o.MarginRate < 0 || o.MarginRate > 1 || o.CommissionPerLot < 0 || o.CommissionBps < 0 ||
+			(o.CloseTodayRatio != nil && *o.CloseTodayRatio < 0) {
+			return nil, fmt.Errorf("%s: values must be >= 0 and margin_rate <= 1", product)
+		}
+		spec := out[key]
+		if o.Exchange != "" {
+			spec.Exchange = strings.ToUpper(o.Exchange)
+		}
+		if o.Multiplier > 0 {
+			spec.Multiplier = o.Multiplier
+		}
+		if o.TickSize > 0 {
+			spec.TickSize = o.TickSize
+		}
+		if o.MarginRate > 0 {
+			spec.MarginRate = o.MarginRate
+		}
+		switch {
+		case o.CommissionPerLot > 0:
+			spec.CommissionPerLot = o.CommissionPerLot
+			spec.CommissionBps = 0
+		case o.CommissionBps > 0:
+			spec.CommissionBps = o.CommissionBps
+			spec.CommissionPerLot = 0
+		}
+		if o.CloseTodayRatio != nil {
+			spec.CloseTodayRatio = o.CloseTodayRatio
+		}
+		out[key] = spec
+	}
+	return out, nil
+}
+
+// MissingContractSpecs returns the futures instruments (and benchmark) whose
+// product is not in the contract table; they trade with the fallback below.
+func (cfg RunConfig) MissingContractSpecs() []string {
+	insts := cfg.Instruments
+	if cfg.Benchmark != nil {
+		insts = append(insts[:len(insts):len(insts)], *cfg.Benchmark)
+	}
+	var out []string
+	for _, inst := range insts {
+		if inst.Type != InstrumentTypeFutures {
+			continue
+		}
+		if _, ok := cfg.ContractSpecs.Lookup(inst.Symbol); !ok {
+			out = append(out, inst.Symbol)
+		}
+	}
+	return out
+}
+
+// FuturesInstrument builds a futures Instrument from the contract table.
+// Products missing from the table fall back to backtest.futures_multiplier
+// and the run-level margin rate / commission.
+func (cfg RunConfig) FuturesInstrument(symbol string) Instrument {
+	inst := Instrument{
+		Symbol:     symbol,
+		Type:       InstrumentTypeFutures,
+		Multiplier: cfg.FuturesMultiplier,
+		AllowShort: true,
+	}
+	if inst.Multiplier <= 0 {
+		inst.Multiplier = 1
+	}
+	if spec, ok := cfg.ContractSpecs.Lookup(symbol); ok {
+		if spec.Multiplier > 0 {
+			inst.Multiplier = spec.Multiplier
+		}
+		inst.Exchange = spec.Exchange
+		inst.TickSize = spec.TickSize
+		inst.MarginRate = spec.MarginRate
+		inst.CommissionPerLot = spec.CommissionPerLot
+		inst.CommissionBps = spec.CommissionBps
+		inst.CloseTodayRatio = spec.CloseTodayRatio
+	}
+	return inst
+}
@@ -0,0 +1,92 @@
+package backtest
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadRunConfigContractSpecs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backtest.yaml")
+	yml := `backtest:
+  futures_multiplier: 7
+  instruments:
+    futures: [nf_I2505, ma0, nf_AU0, nf_XX0]
+  contract_specs:
+    AU:
+      margin_rate: 0.2
+      commission_bps: 0.5
+    xx:
+      multiplier: 3
+      exchange: dce
+`
+	if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadRunConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	got := map[string]Instrument{}
+	for _, inst := range cfg.Instruments {
+		got[inst.Symbol] = inst
+	}
+
+	if i := got["nf_I2505"]; i.Multiplier != 100 || i.TickSize != 0.5 || i.Exchange != "DCE" || i.CommissionBps != 1 {
+		t.Fatalf("iron ore spec: %#v", i)
+	}
+	if ma := got["nf_MA0"]; ma.Multiplier != 10 || ma.CommissionPerLot != 2 {
+		t.Fatalf("methanol spec: %#v", ma)
+	}
+	// override switches gold from per-lot to bps fees and keeps the built-in multiplier
+	if au := got["nf_AU0"]; au.Multiplier != 1000 || au.MarginRate != 0.2 || au.CommissionPerLot != 0 || au.CommissionBps != 0.5 {
+		t.Fatalf("gold spec: %#v", au)
+	}
+	if xx := got["nf_XX0"]; xx.Multiplier != 3 || xx.Exchange != "DCE" {
+		t.Fatalf("custom product spec: %#v", xx)
+	}
+	if DefaultContractSpecs()["AU"].MarginRate != 0.10 {
+		t.Fatalf("override leaked into the built-in table")
+	}
+
+	// unknown products without an override use the global fallback
+	inst := cfg.FuturesInstrument("nf_ZZ0")
+	if inst.Multiplier != 7 || inst.MarginRate != 0 {
+		t.Fatalf("fallback instrument: %#v", inst)
+	}
+	if m := cfg.MissingContractSpecs(); len(m) != 0 {
+		t.Fatalf("missing specs %v", m)
+	}
+	cfg.Instruments = append(cfg.Instruments, inst)
+	if m := cfg.MissingContractSpecs(); len(m) != 1 || m[0] != "nf_ZZ0" {
+		t.Fatalf("missing specs %v", m)
+	}
+	if b := cfg.FuturesInstrument("nf_B0"); b.Multiplier != 10 || b.Exchange != "DCE" {
+		t.Fatalf("soybean No.2 spec: %#v", b)
+	}
+}
+
+func TestContractSpecFeesAndTicks(t *testing.T) {
+	cfg := DefaultRunConfig()
+	cfg.CommissionBps = 3
+	ma := cfg.FuturesInstrument("nf_MA2505")
+	if fee := cfg.fees(ma, Position{}, SignalBuy, time.Time{}, 2500, 4).Total; fee != 8 {
+		t.Fatalf("per-lot fee = %v", fee)
+	}
+	i := cfg.FuturesInstrument("nf_I2505")
+	if fee := cfg.fees(i, Position{}, SignalBuy, time.Time{}, 800, 2).Total; math.Abs(fee-16) > 1e-9 {
+		t.Fatalf("bps fee = %v", fee)
+	}
+	if m := marginRate(i, cfg); m != 0.12 {
+		t.Fatalf("margin rate = %v", m)
+	}
+	// 800.1 -> buys round up to 800.5, sells round down to 800.0
+	if p := fillPrice(i, 800.1, 0, SignalBuy); p != 800.5 {
+		t.Fatalf("buy fill = %v", p)
+	}
+	if p := fillPrice(i, 800.1, 0, SignalSell); p != 800 {
+		t.Fatalf("sell fill = %v", p)
+	}
+}
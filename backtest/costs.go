@@ -0,0 +1,200 @@
+package backtest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fees is the cost breakdown of a fill, or of a whole trade (entry + exit).
+type Fees struct {
+	Commission  float64 `json:"commission"`
+	StampDuty   float64 `json:"stamp_duty,omitempty"`
+	TransferFee float64 `json:"transfer_fee,omitempty"`
+	Total       float64 `json:"total"`
+}
+
+func (f Fees) add(o Fees) Fees {
+	return Fees{
+		Commission:  f.Commission + o.Commission,
+		StampDuty:   f.StampDuty + o.StampDuty,
+		TransferFee: f.TransferFee + o.TransferFee,
+		Total:       f.Total + o.Total,
+	}
+}
+
+func (f Fees) scaled(k float64) Fees {
+	return Fees{Commission: f.Commission * k, StampDuty: f.StampDuty * k, TransferFee: f.TransferFee * k, Total: f.Total * k}.rounded()
+}
+
+func (f Fees) rounded() Fees {
+	return Fees{Commission: round2(f.Commission), StampDuty: round2(f.StampDuty), TransferFee: round2(f.TransferFee), Total: round2(f.Total)}
+}
+
+// Fill describes one execution the cost model prices.
+type Fill struct {
+	Inst   Instrument
+	Action SignalAction
+	Time   time.Time
+	Price  float64
+	Qty    float64
+	// Closing is true for sell/cover; CloseToday when the position was opened
+	// on the same trading day.
+	Closing    bool
+	CloseToday bool
+}
+
+func (f Fill) notional() float64 {
+	return f.Price * f.Qty * multiplier(f.Inst)
+}
+
+// CostModel prices fills. RunConfig.Costs selects it; nil means SimpleCosts.
+type CostModel interface {
+	Fees(f Fill) Fees
+}
+
+// SimpleCosts is a symmetric commission: the futures contract spec fee (per lot
+// or bps) when present, otherwise CommissionBps of notional.
+type SimpleCosts struct {
+	CommissionBps float64
+}
+
+func (c SimpleCosts) Fees(f Fill) Fees {
+	fee := futuresCommission(f, c.CommissionBps)
+	return Fees{Commission: fee, Total: fee}
+}
+
+func futuresCommission(f Fill, fallbackBps float64) float64 {
+	if f.Inst.Type == InstrumentTypeFutures && f.Inst.CommissionPerLot > 0 {
+		return f.Inst.CommissionPerLot * f.Qty
+	}
+	bps := fallbackBps
+	if f.Inst.CommissionBps > 0 {
+		bps = f.Inst.CommissionBps
+	}
+	return f.notional() * bps / 10000.0
+}
+
+// stampDutyCutDate is when A-share stamp duty on sells was halved to 0.05%.
+var stampDutyCutDate = time.Date(2023, 8, 28, 0, 0, 0, 0, time.Local)
+
+// ChinaCosts models mainland fees:
+//   - stocks: broker commission with a minimum per fill, stamp duty on sells
+//     only, and a transfer fee on both sides;
+//   - futures: the contract spec fee (per lot or bps), scaled by the product's
+//     close-today ratio when closing a position opened the same day.
+type ChinaCosts struct {
+	StockCommissionBps float64
+	MinCommission      float64
+	// StampDutyBps is charged on sells; nil follows the statutory rate
+	// (10 bps, 5 bps from 2023-08-28).
+	StampDutyBps   *float64
+	TransferFeeBps float64
+	// FuturesCommissionBps applies to products without a contract spec fee.
+	FuturesCommissionBps float64
+}
+
+// DefaultChinaCosts returns current retail defaults: 5 CNY minimum commission
+// and a 0.1 bps transfer fee; commissionBps is the broker rate.
+func DefaultChinaCosts(commissionBps float64) ChinaCosts {
+	return ChinaCosts{
+		StockCommissionBps:   commissionBps,
+		MinCommission:        5,
+		TransferFeeBps:       0.1,
+		FuturesCommissionBps: commissionBps,
+	}
+}
+
+func (c ChinaCosts) Fees(f Fill) Fees {
+	if f.Qty <= 0 || f.Price <= 0 {
+		return Fees{}
+	}
+	if f.Inst.Type == InstrumentTypeFutures {
+		fee := futuresCommission(f, c.FuturesCommissionBps)
+		if f.Closing && f.CloseToday {
+			fee *= f.Inst.closeTodayRatio()
+		}
+		return Fees{Commission: fee, Total: fee}
+	}
+
+	n := f.notional()
+	out := Fees{Commission: n * c.StockCommissionBps / 10000.0}
+	if out.Commission < c.MinCommission {
+		out.Commission = c.MinCommission
+	}
+	if f.Closing {
+		out.StampDuty = n * c.stampDutyBps(f.Time) / 10000.0
+	}
+	out.TransferFee = n * c.TransferFeeBps / 10000.0
+	out.Total = out.Commission + out.StampDuty + out.TransferFee
+	return out
+}
+
+func (c ChinaCosts) stampDutyBps(t time.Time) float64 {
+	if c.StampDutyBps != nil {
+		return *c.StampDutyBps
+	}
+	if t.Before(stampDutyCutDate) {
+		return 10
+	}
+	return 5
+}
+
+type costsYAML struct {
+	// Model: simple (default) | china
+	Model string `yaml:"model"`
+	Stock struct {
+		CommissionBps  *float64 `yaml:"commission_bps"`
+		MinCommission  *float64 `yaml:"min_commission"`
+		StampDutyBps   *float64 `yaml:"stamp_duty_bps"`
+		TransferFeeBps *float64 `yaml:"transfer_fee_bps"`
+	} `yaml:"stock"`
+}
+
+func (y costsYAML) toModel(commissionBps float64) (CostModel, error) {
+	switch strings.ToLower(strings.TrimSpace(y.Model)) {
+	case "", "simple":
+		return SimpleCosts{CommissionBps: commissionBps}, nil
+	case "china":
+	default:
+		return nil, fmt.Errorf("unknown model: %s (simple|china)", y.Model)
+	}
+	c := DefaultChinaCosts(commissionBps)
+	for _, v := range []*float64{y.Stock.CommissionBps, y.Stock.MinCommission, y.Stock.StampDutyBps, y.Stock.TransferFeeBps} {
+		if v != nil && *v < 0 {
+			return nil, fmt.Errorf("stock fees must be >= 0")
+		}
+	}
+	if y.Stock.CommissionBps != nil {
+		c.StockCommissionBps = *y.Stock.CommissionBps
+	}
+	if y.Stock.MinCommission != nil {
+		c.MinCommission = *y.Stock.MinCommission
+	}
+	c.StampDutyBps = y.Stock.StampDutyBps
+	if y.Stock.TransferFeeBps != nil {
+		c.TransferFeeBps = *y.Stock.TransferFeeBps
+	}
+	return c, nil
+}
+
+func (cfg RunConfig) costModel() CostModel {
+	if cfg.Costs != nil {
+		return cfg.Costs
+	}
+	return SimpleCosts{CommissionBps: cfg.CommissionBps}
+}
+
+// fees prices a fill of inst under the configured cost model.
+func (cfg RunConfig) fees(inst Instrument, pos Position, action SignalAction, t time.Time, price, qty float64) Fees {
+	closing := action == SignalSell || action == SignalCover
+	return cfg.costModel().Fees(Fill{
+		Inst:       inst,
+		Action:     action,
+		Time:       t,
+		Price:      price,
+		Qty:        qty,
+		Closing:    closing,
+		CloseToday: closing && sameDay(pos.EntryTime, t),
+	})
+}
@@ -0,0 +1,103 @@
+package backtest
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChinaCostsStock(t *testing.T) {
+	c := DefaultChinaCosts(2.5)
+	inst := Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+
+	// 10_000 notional: commission 2.5 -> minimum 5; no stamp duty on buys
+	buy := c.Fees(Fill{Inst: inst, Action: SignalBuy, Time: day, Price: 10, Qty: 1000})
+	if buy.Commission != 5 || buy.StampDuty != 0 || math.Abs(buy.TransferFee-0.1) > 1e-9 {
+		t.Fatalf("buy fees %#v", buy)
+	}
+
+	// 100_000 notional sell: commission 25, stamp duty 5 bps, transfer 1
+	sell := c.Fees(Fill{Inst: inst, Action: SignalSell, Time: day, Price: 10, Qty: 10000, Closing: true})
+	if math.Abs(sell.Commission-25) > 1e-9 || math.Abs(sell.StampDuty-50) > 1e-9 || math.Abs(sell.Total-76) > 1e-9 {
+		t.Fatalf("sell fees %#v", sell)
+	}
+	// stamp duty was 10 bps before 2023-08-28
+	old := c.Fees(Fill{Inst: inst, Action: SignalSell, Time: day.AddDate(-2, 0, 0), Price: 10, Qty: 10000, Closing: true})
+	if math.Abs(old.StampDuty-100) > 1e-9 {
+		t.Fatalf("pre-2023 stamp duty %#v", old)
+	}
+}
+
+func TestChinaCostsFuturesCloseToday(t *testing.T) {
+	c := DefaultChinaCosts(1)
+	cfg := DefaultRunConfig()
+	ifInst := cfg.FuturesInstrument("nf_IF2503")
+	au := cfg.FuturesInstrument("nf_AU2506")
+
+	// IF: 0.23 bps of 3800*300*1 = 26.22; closing today is 10x
+	f := Fill{Inst: ifInst, Action: SignalSell, Price: 3800, Qty: 1, Closing: true}
+	if fee := c.Fees(f).Total; math.Abs(fee-26.22) > 1e-6 {
+		t.Fatalf("IF fee = %v", fee)
+	}
+	f.CloseToday = true
+	if fee := c.Fees(f).Total; math.Abs(fee-262.2) > 1e-6 {
+		t.Fatalf("IF close-today fee = %v", fee)
+	}
+	// gold: 10 per lot, closing today is free
+	g := Fill{Inst: au, Action: SignalSell, Price: 600, Qty: 2, Closing: true, CloseToday: true}
+	if fee := c.Fees(g).Total; fee != 0 {
+		t.Fatalf("AU close-today fee = %v", fee)
+	}
+	g.CloseToday = false
+	if fee := c.Fees(g).Total; fee != 20 {
+		t.Fatalf("AU fee = %v", fee)
+	}
+}
+
+func TestTradeFeeBreakdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backtest.yaml")
+	yml := "backtest:\n  commission_bps: 3\n  slippage_bps: 0\n  costs:\n    model: china\n    stock:\n      min_commission: 0\n      transfer_fee_bps: 0\n"
+	if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadRunConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	cfg.Strategy = &scriptStrategy{at: map[int]SignalAction{1: SignalBuy, 5: SignalSell}}
+	cfg.InitialCash = 100_000
+	cfg.PositionPct = 0.5
+
+	res := runOne(Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}, flatBars(20), cfg)
+	if len(res.Trades) != 1 {
+		t.Fatalf("trades %#v", res.Trades)
+	}
+	// 5000 shares at 10 both ways: commission 15+15, stamp duty 25 (5 bps, 2024)
+	tr := res.Trades[0]
+	want := Fees{Commission: 30, StampDuty: 25, Total: 55}
+	if tr.Fees != want || tr.NetPnL != -55 {
+		t.Fatalf("trade fees %#v net %v", tr.Fees, tr.NetPnL)
+	}
+
+	if err := os.WriteFile(path, []byte("backtest:\n  costs:\n    model: flat\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRunConfig(path); err == nil {
+		t.Fatalf("expected error for unknown cost model")
+	}
+
+	// china is opt-in; without costs.model the old symmetric commission applies
+	if err := os.WriteFile(path, []byte("backtest:\n  commission_bps: 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadRunConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if c, ok := cfg.Costs.(SimpleCosts); !ok || c.CommissionBps != 3 {
+		t.Fatalf("default costs %#v", cfg.Costs)
+	}
+}
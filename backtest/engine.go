@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"sort"
 	"time"
 
 	"stock/fetcher"
@@ -19,6 +18,7 @@ type Strategy interface {
 type Result struct {
 	Symbol      string   `json:"symbol"`
 	Instrument  string   `json:"instrument"`
+	Adjust      string   `json:"adjust,omitempty"`
 	Trades      []Trade  `json:"trades"`
 	FinalEquity float64  `json:"final_equity"`
 	MaxDDPct    float64  `json:"max_drawdown_pct"`
@@ -26,6 +26,22 @@ type Result struct {
 	TotalTrades int      `json:"total_trades"`
 	EquityCurve []Point  `json:"equity_curve"`
 	Errors      []string `json:"errors,omitempty"`
+
+	// Metrics are the extended statistics (see ComputeMetrics).
+	Metrics *Metrics `json:"metrics,omitempty"`
+
+	// Benchmark is the buy-and-hold comparison (backtest.benchmark).
+	Benchmark *Benchmark `json:"benchmark,omitempty"`
+
+	// Rolls lists the contract switches of a continuous futures series.
+	Rolls []Roll `json:"rolls,omitempty"`
+
+	// Orders refused by the A-share rules (T+1, price limits, suspensions).
+	Unfilled []UnfilledOrder `json:"unfilled_orders,omitempty"`
+
+	// Portfolio runs only (see RunPortfolio).
+	Attribution    []Attribution `json:"attribution,omitempty"`
+	SkippedEntries int           `json:"skipped_entries,omitempty"`
 }
 
 type Point struct {
@@ -34,11 +50,20 @@ type Point struct {
 }
 
 type Runner struct {
-	klineFetcher *fetcher.KLineFetcher
+	source fetcher.BarSource
 }
 
 func NewRunner() *Runner {
-	return &Runner{klineFetcher: fetcher.NewKLineFetcher()}
+	return &Runner{source: fetcher.NewKLineFetcher()}
+}
+
+// NewRunnerWithSource creates a Runner that loads bars from the given source
+// (e.g. a registry-selected source, or a fake source in tests).
+func NewRunnerWithSource(src fetcher.BarSource) *Runner {
+	if src == nil {
+		return NewRunner()
+	}
+	return &Runner{source: src}
 }
 
 // LoadBars loads daily bars for a single instrument using the same logic as backtest/scan.
@@ -51,32 +76,93 @@ func (r *Runner) Run(cfg RunConfig) ([]Result, error) {
 		return nil, fmt.Errorf("no instruments configured")
 	}
 
+	benchBars, benchErr := r.loadBenchmark(cfg)
 	var out []Result
 	for _, inst := range cfg.Instruments {
-		bars, err := r.loadBars(inst, cfg)
+		bars, rolls, err := r.loadSeries(inst, cfg)
 		if err != nil {
 			out = append(out, Result{
 				Symbol:     inst.Symbol,
 				Instrument: string(inst.Type),
+				Adjust:     string(cfg.AdjustFor(inst)),
 				Errors:     []string{err.Error()},
 			})
 			continue
 		}
 		res := runOne(inst, bars, cfg)
+		res.Adjust = string(cfg.AdjustFor(inst))
+		res.Rolls = rolls
+		attachBenchmark(&res, cfg, benchBars, benchErr)
 		out = append(out, res)
 	}
 	return out, nil
 }
 
 func (r *Runner) loadBars(inst Instrument, cfg RunConfig) ([]Bar, error) {
+	bars, _, err := r.loadSeries(inst, cfg)
+	return bars, err
+}
+
+// loadSeries loads the bars of inst inside the configured window. Continuous
+// futures symbols (cfg.Continuous) are stitched from their contracts and also
+// return the roll dates that fall inside the window.
+func (r *Runner) loadSeries(inst Instrument, cfg RunConfig) ([]Bar, []Roll, error) {
+	var bars []Bar
+	var rolls []Roll
+	if spec, ok := cfg.Continuous[inst.Symbol]; ok && inst.Type == InstrumentTypeFutures {
+		series := make(map[string][]Bar, len(spec.Contracts))
+		for _, c := range spec.Contracts {
+			b, err := r.fetchBars(Instrument{Symbol: c, Type: InstrumentTypeFutures}, cfg)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", c, err)
+			}
+			series[c] = b
+		}
+		var err error
+		bars, rolls, err = BuildContinuous(spec, series)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		var err error
+		bars, err = r.fetchBars(inst, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	kept := bars[:0]
+	for _, b := range bars {
+		if cfg.inWindow(b.Time) {
+			kept = append(kept, b)
+		}
+	}
+	bars = kept
+	if len(bars) < 50 {
+		return nil, nil, fmt.Errorf("not enough bars: %d", len(bars))
+	}
+
+	first, last := bars[0].Time.Format(fetcher.DateLayout), bars[len(bars)-1].Time.Format(fetcher.DateLayout)
+	var inWindow []Roll
+	for _, rl := range rolls {
+		if rl.Date >= first && rl.Date <= last {
+			inWindow = append(inWindow, rl)
+		}
+	}
+	return bars, inWindow, nil
+}
+
+// fetchBars fetches and parses all bars of one symbol, sorted by time.
+func (r *Runner) fetchBars(inst Instrument, cfg RunConfig) ([]Bar, error) {
 	var kl []fetcher.KLine
 	var err error
 
+	opt := fetcher.KLineOptions{Adjust: cfg.AdjustFor(inst), Period: cfg.period()}
 	switch inst.Type {
 	case InstrumentTypeStock:
-		kl, err = r.klineFetcher.FetchStockKLine(inst.Symbol, cfg.Days)
+		kl, err = r.source.FetchStockKLine(inst.Symbol, cfg.Days, opt)
 	case InstrumentTypeFutures:
-		kl, err = r.klineFetcher.FetchFuturesKLine(inst.Symbol, cfg.Days)
+		kl, err = r.source.FetchFuturesKLine(inst.Symbol, cfg.Days, opt)
 	default:
 		return nil, fmt.Errorf("unknown instrument type: %s", inst.Type)
 	}
@@ -84,178 +170,92 @@ func (r *Runner) loadBars(inst Instrument, cfg RunConfig) ([]Bar, error) {
 		return nil, err
 	}
 
-	bars := make([]Bar, 0, len(kl))
-	for _, k := range kl {
-		t, err := time.ParseInLocation("2006-01-02", k.Date, time.Local)
-		if err != nil {
-			continue
-		}
-		if !cfg.Start.IsZero() && t.Before(cfg.Start) {
-			continue
-		}
-		if !cfg.End.IsZero() && t.After(cfg.End) {
-			continue
-		}
-		bars = append(bars, Bar{
-			Time:   t,
-			Open:   k.Open,
-			High:   k.High,
-			Low:    k.Low,
-			Close:  k.Close,
-			Volume: k.Volume,
-		})
-	}
-
-	sort.Slice(bars, func(i, j int) bool { return bars[i].Time.Before(bars[j].Time) })
-	if len(bars) < 50 {
-		return nil, fmt.Errorf("not enough bars: %d", len(bars))
-	}
-	return bars, nil
+	return fetcher.KLinesToBars(kl, time.Local), nil
 }
 
 func runOne(inst Instrument, bars []Bar, cfg RunConfig) Result {
-	strategy := cfg.Strategy.Clone()
-
-	cash := cfg.InitialCash
-	var pos Position
-	pos.Side = SideFlat
-
-	var trades []Trade
-	var pending *Signal
-	var lastEntryReason string
-
-	equityCurve := make([]Point, 0, len(bars))
-	peakEquity := cash
-	maxDD := 0.0
-
-	for i := 0; i < len(bars); i++ {
-		bar := bars[i]
-
-		// Execute pending order at next bar open (close-confirm model)
-		if pending != nil {
-			if i == 0 {
-				// can't execute on first bar
-			} else if bars[i].Time.Equal(pending.Time) {
-				// safety: should never happen (pending.Time uses signal bar time)
-			} else if i >= 1 && bars[i-1].Time.Equal(pending.Time) {
-				execPrice := applySlippage(bars[i].Open, cfg.SlippageBps, pending.Action)
-				fee := 0.0
-
-				switch pending.Action {
-				case SignalBuy:
-					if pos.Side == SideFlat && execPrice > 0 {
-						qty := sizeQty(inst, cash, execPrice, cfg.PositionPct, cfg.FuturesMargin)
-						if qty > 0 {
-							notional := execPrice * qty * multiplier(inst)
-							fee = notional * (cfg.CommissionBps / 10000.0)
-							if inst.Type == InstrumentTypeFutures {
-								margin := notional * cfg.FuturesMargin
-								cash -= margin + fee
-								pos = Position{Side: SideLong, Qty: qty, EntryTime: bars[i].Time, EntryPrice: execPrice, EntryFee: fee, Margin: margin}
-							} else {
-								cash -= notional + fee
-								pos = Position{Side: SideLong, Qty: qty, EntryTime: bars[i].Time, EntryPrice: execPrice, EntryFee: fee}
-							}
-							lastEntryReason = pending.Reason
-						}
-					}
-				case SignalSell:
-					if pos.Side == SideLong && execPrice > 0 {
-						notional := execPrice * pos.Qty * multiplier(inst)
-						fee = notional * (cfg.CommissionBps / 10000.0)
-						if inst.Type == InstrumentTypeFutures {
-							cash += pos.Margin + settlePnL(inst, pos, execPrice) - fee
-						} else {
-							cash += notional - fee
-						}
-						trades = append(trades, closeTrade(inst, pos, bars[i].Time, execPrice, fee, lastEntryReason, pending.Reason))
-						pos = Position{Side: SideFlat}
-					}
-				case SignalShort:
-					if inst.Type == InstrumentTypeFutures && inst.AllowShort && pos.Side == SideFlat && execPrice > 0 {
-						qty := sizeQty(inst, cash, execPrice, cfg.PositionPct, cfg.FuturesMargin)
-						if qty > 0 {
-							notional := execPrice * qty * multiplier(inst)
-							fee = notional * (cfg.CommissionBps / 10000.0)
-							margin := notional * cfg.FuturesMargin
-							cash -= margin + fee
-							pos = Position{Side: SideShort, Qty: qty, EntryTime: bars[i].Time, EntryPrice: execPrice, EntryFee: fee, Margin: margin}
-							lastEntryReason = pending.Reason
-						}
-					}
-				case SignalCover:
-					if pos.Side == SideShort && execPrice > 0 {
-						fee = (execPrice * pos.Qty * multiplier(inst)) * (cfg.CommissionBps / 10000.0)
-						cash += pos.Margin + settlePnL(inst, pos, execPrice) - fee
-						trades = append(trades, closeTrade(inst, pos, bars[i].Time, execPrice, fee, lastEntryReason, pending.Reason))
-						pos = Position{Side: SideFlat}
-					}
-				}
-
-				pending = nil
-			}
-		}
-
-		// Generate new signal at close
-		sig := strategy.OnBar(i, bars, pos)
-		if sig != nil && i+1 < len(bars) {
-			pending = sig
-		}
+	sim := NewSimulator(inst, bars, cfg)
+	sim.Run(nil)
+	return sim.Finish()
+}
 
-		equity := cash + markToMarket(inst, pos, bar.Close)
-		equityCurve = append(equityCurve, Point{Time: bar.Time.Format("2006-01-02"), Equity: equity})
+// applyProtective closes pos when its stop or target is reached within bars[i].
+// Exits refused by the A-share rules are recorded in unfilled and re-checked on the next bar.
+func applyProtective(inst Instrument, cfg RunConfig, pos Position, bars []Bar, i int, entryReason string, unfilled *[]UnfilledOrder) (Position, float64, *Trade) {
+	if !cfg.IntrabarExits {
+		return pos, 0, nil
+	}
+	bar := bars[i]
+	exit, raw := protectiveExit(pos, bar, cfg.IntrabarConflict)
+	if exit == nil {
+		return pos, 0, nil
+	}
+	if cause := cfg.protectiveBlocked(inst, bars, i, pos, exit); cause != "" {
+		*unfilled = append(*unfilled, UnfilledOrder{
+			Symbol: inst.Symbol,
+			Time:   cfg.FormatTime(bar.Time),
+			Action: exit.Action,
+			Reason: exit.Reason,
+			Cause:  cause,
+			Retry:  true,
+		})
+		return pos, 0, nil
+	}
+	return executeOrder(inst, cfg, pos, exit, bar.Time, protectiveFillPrice(inst, cfg, exit, raw), sizeInput{}, entryReason)
+}
 
-		if equity > peakEquity {
-			peakEquity = equity
+// executeOrder fills sig at execPrice. size is what a new position is sized
+// against (cfg.Sizing); its budget is the capital allotted to the position
+// (cash * position_pct for a single-instrument run). It returns the resulting
+// position, the cash change and the closed trade, if any; orders that do not
+// apply to the current position leave it unchanged.
+func executeOrder(inst Instrument, cfg RunConfig, pos Position, sig *Signal, t time.Time, execPrice float64, size sizeInput, entryReason string) (Position, float64, *Trade) {
+	if execPrice <= 0 {
+		return pos, 0, nil
+	}
+	switch sig.Action {
+	case SignalBuy, SignalShort:
+		if pos.Side != SideFlat {
+			return pos, 0, nil
 		}
-		if peakEquity > 0 {
-			dd := (peakEquity - equity) / peakEquity
-			if dd > maxDD {
-				maxDD = dd
+		side := SideLong
+		if sig.Action == SignalShort {
+			if inst.Type != InstrumentTypeFutures || !inst.AllowShort {
+				return pos, 0, nil
 			}
+			side = SideShort
 		}
-	}
-
-	// Force close at last close
-	if pos.Side != SideFlat {
-		last := bars[len(bars)-1]
-		exitPrice := last.Close
-		fee := (exitPrice * pos.Qty * multiplier(inst)) * (cfg.CommissionBps / 10000.0)
-		trades = append(trades, closeTrade(inst, pos, last.Time, exitPrice, fee, lastEntryReason, "force_close_end"))
+		qty := cfg.Sizing.qty(inst, cfg, sig, execPrice, size)
+		if qty <= 0 {
+			return pos, 0, nil
+		}
+		notional := execPrice * qty * multiplier(inst)
+		fees := cfg.fees(inst, pos, sig.Action, t, execPrice, qty)
+		fee := fees.Total
+		next := Position{Side: side, Qty: qty, EntryTime: t, EntryPrice: execPrice, EntryFee: fee, EntryFees: fees, Stop: sig.Stop, Target: sig.Target}
 		if inst.Type == InstrumentTypeFutures {
-			cash = cash + pos.Margin + settlePnL(inst, pos, exitPrice) - fee
-		} else {
-			cash = cash + exitPrice*pos.Qty*multiplier(inst) - fee
+			next.Margin = notional * marginRate(inst, cfg)
+			return next, -(next.Margin + fee), nil
 		}
-		pos = Position{Side: SideFlat}
-	}
-
-	finalEquity := cash
-	if len(equityCurve) > 0 {
-		finalEquity = equityCurve[len(equityCurve)-1].Equity
-	}
-
-	win := 0
-	for _, t := range trades {
-		if t.NetPnL > 0 {
-			win++
+		return next, -(notional + fee), nil
+	case SignalSell, SignalCover:
+		want := SideLong
+		if sig.Action == SignalCover {
+			want = SideShort
 		}
+		if pos.Side != want {
+			return pos, 0, nil
+		}
+		fees := cfg.fees(inst, pos, sig.Action, t, execPrice, pos.Qty)
+		fee := fees.Total
+		delta := execPrice*pos.Qty*multiplier(inst) - fee
+		if inst.Type == InstrumentTypeFutures {
+			delta = pos.Margin + settlePnL(inst, pos, execPrice) - fee
+		}
+		trade := closeTrade(inst, pos, t, execPrice, fees, entryReason, sig.Reason, cfg)
+		return Position{Side: SideFlat}, delta, &trade
 	}
-	winRate := 0.0
-	if len(trades) > 0 {
-		winRate = float64(win) / float64(len(trades)) * 100
-	}
-
-	return Result{
-		Symbol:      inst.Symbol,
-		Instrument:  string(inst.Type),
-		Trades:      trades,
-		FinalEquity: round2(finalEquity),
-		MaxDDPct:    round2(maxDD * 100),
-		WinRatePct:  round2(winRate),
-		TotalTrades: len(trades),
-		EquityCurve: equityCurve,
-	}
+	return pos, 0, nil
 }
 
 func WriteResultsJSON(w io.Writer, results []Result) error {
@@ -279,6 +279,30 @@ func applySlippage(price, bps float64, action SignalAction) float64 {
 	}
 }
 
+// fillPrice applies slippage and, for futures, rounds to a valid tick against the trader.
+func fillPrice(inst Instrument, price, bps float64, action SignalAction) float64 {
+	p := applySlippage(price, bps, action)
+	if inst.Type != InstrumentTypeFutures || inst.TickSize <= 0 || p <= 0 {
+		return p
+	}
+	ticks := p / inst.TickSize
+	switch action {
+	case SignalBuy, SignalCover:
+		ticks = math.Ceil(ticks - 1e-9)
+	default:
+		ticks = math.Floor(ticks + 1e-9)
+	}
+	return ticks * inst.TickSize
+}
+
+// marginRate returns the margin fraction of a futures position (contract spec, else run config).
+func marginRate(inst Instrument, cfg RunConfig) float64 {
+	if inst.Type == InstrumentTypeFutures && inst.MarginRate > 0 && inst.MarginRate <= 1 {
+		return inst.MarginRate
+	}
+	return cfg.FuturesMargin
+}
+
 func multiplier(inst Instrument) float64 {
 	if inst.Type == InstrumentTypeFutures && inst.Multiplier > 0 {
 		return inst.Multiplier
@@ -311,6 +335,23 @@ func sizeQty(inst Instrument, cash, price, pct, futuresMargin float64) float64 {
 	}
 }
 
+// riskQty is the largest quantity (whole lots for stocks) losing at most risk
+// over a price move of perUnit.
+func riskQty(inst Instrument, risk, perUnit float64) float64 {
+	if risk <= 0 || perUnit <= 0 {
+		return 0
+	}
+	q := math.Floor(risk / (perUnit * multiplier(inst)))
+	if inst.Type == InstrumentTypeStock {
+		lot := inst.LotSize
+		if lot <= 0 {
+			lot = 100
+		}
+		q = math.Floor(q/float64(lot)) * float64(lot)
+	}
+	return q
+}
+
 func markToMarket(inst Instrument, pos Position, closePrice float64) float64 {
 	if pos.Side == SideFlat || pos.Qty <= 0 || closePrice <= 0 {
 		return 0
@@ -336,9 +377,9 @@ func settlePnL(inst Instrument, pos Position, exitPrice float64) float64 {
 	return (exitPrice - pos.EntryPrice) * d * pos.Qty * multiplier(inst)
 }
 
-func closeTrade(inst Instrument, pos Position, exitTime time.Time, exitPrice float64, exitFee float64, entryReason, exitReason string) Trade {
+func closeTrade(inst Instrument, pos Position, exitTime time.Time, exitPrice float64, exitFees Fees, entryReason, exitReason string, cfg RunConfig) Trade {
 	gross := settlePnL(inst, pos, exitPrice)
-	net := gross - pos.EntryFee - exitFee
+	net := gross - pos.EntryFee - exitFees.Total
 	retPct := 0.0
 	if pos.EntryPrice > 0 {
 		retPct = (exitPrice - pos.EntryPrice) / pos.EntryPrice * 100.0
@@ -349,9 +390,9 @@ func closeTrade(inst Instrument, pos Position, exitTime time.Time, exitPrice flo
 	return Trade{
 		Symbol:      inst.Symbol,
 		Side:        pos.Side,
-		EntryTime:   pos.EntryTime.Format("2006-01-02"),
+		EntryTime:   cfg.FormatTime(pos.EntryTime),
 		EntryPrice:  round2(pos.EntryPrice),
-		ExitTime:    exitTime.Format("2006-01-02"),
+		ExitTime:    cfg.FormatTime(exitTime),
 		ExitPrice:   round2(exitPrice),
 		Qty:         round2(pos.Qty),
 		GrossPnL:    round2(gross),
@@ -359,6 +400,7 @@ func closeTrade(inst Instrument, pos Position, exitTime time.Time, exitPrice flo
 		ReturnPct:   round2(retPct),
 		ReasonEntry: entryReason,
 		ReasonExit:  exitReason,
+		Fees:        pos.EntryFees.add(exitFees).rounded(),
 	}
 }
 
@@ -38,8 +38,9 @@ type LLMBacktestSection struct {
 }
 
 type LLMStrategySection struct {
-	Type   string        `json:"type"`
-	Params TsaiSenParams `json:"params"`
+	Type string `json:"type"`
+	// Params are decoded by the registered strategy (see RegisterStrategy).
+	Params json.RawMessage `json:"params"`
 }
 
 func ParseLLMBacktestConfigJSON(raw []byte) (LLMBacktestConfig, error) {
@@ -118,70 +119,26 @@ func (c LLMBacktestConfig) Validate() error {
 	}
 	for _, f := range bt.Instruments.Futures {
 		sym := config.NormalizeFuturesCode(f)
-		if sym == "" || !strings.HasPrefix(strings.ToLower(sym), "nf_") {
+		lower := strings.ToLower(sym)
+		if sym == "" || !(strings.HasPrefix(lower, "nf_") || strings.HasPrefix(lower, "hf_")) {
 			return fmt.Errorf("invalid futures code: %q", f)
 		}
 	}
 
-	st := strings.TrimSpace(c.Strategy.Type)
-	if st == "" {
+	if strings.TrimSpace(c.Strategy.Type) == "" {
 		return fmt.Errorf("strategy.type required")
 	}
-	if st != "tsai_sen" {
-		return fmt.Errorf("unsupported strategy.type: %s", st)
-	}
+	_, err := c.Strategy.params()
+	return err
+}
 
-	p := c.Strategy.Params.withDefaults()
-	if p.LevelMode != "pivots" && p.LevelMode != "extremes" {
-		return fmt.Errorf("strategy.params.level_mode invalid")
-	}
-	if p.BoxLookback < 10 || p.BoxLookback > 500 {
-		return fmt.Errorf("strategy.params.box_lookback out of range")
-	}
-	if p.PivotN < 1 || p.PivotN > 20 {
-		return fmt.Errorf("strategy.params.pivot_n out of range")
-	}
-	if p.TouchTolPct <= 0 || p.TouchTolPct > 0.05 {
-		return fmt.Errorf("strategy.params.touch_tol_pct out of range")
-	}
-	if p.MinTouches < 2 || p.MinTouches > 50 {
-		return fmt.Errorf("strategy.params.min_touches out of range")
-	}
-	if p.MinRangePct <= 0 || p.MinRangePct > 0.5 {
-		return fmt.Errorf("strategy.params.min_range_pct out of range")
-	}
-	if p.BreakPct <= 0 || p.BreakPct > 0.2 {
-		return fmt.Errorf("strategy.params.break_pct out of range")
-	}
-	if p.ReclaimPct < 0 || p.ReclaimPct > 0.2 {
-		return fmt.Errorf("strategy.params.reclaim_pct out of range")
-	}
-	if p.FlipMaxBars <= 0 || p.FlipMaxBars > 300 {
-		return fmt.Errorf("strategy.params.flip_max_bars out of range")
-	}
-	if p.EntryMode != "reclaim_support" && p.EntryMode != "stabilize_support" && p.EntryMode != "break_resistance" {
-		return fmt.Errorf("strategy.params.entry_mode invalid")
-	}
-	if p.StabilizeBars < 1 || p.StabilizeBars > 60 {
-		return fmt.Errorf("strategy.params.stabilize_bars out of range")
-	}
-	if p.StopBufferPct <= 0 || p.StopBufferPct > 0.2 {
-		return fmt.Errorf("strategy.params.stop_buffer_pct out of range")
-	}
-	if p.TargetMultiple <= 0 || p.TargetMultiple > 10 {
-		return fmt.Errorf("strategy.params.target_multiple out of range")
-	}
-	if p.VolMAN < 1 || p.VolMAN > 300 {
-		return fmt.Errorf("strategy.params.vol_ma_n out of range")
-	}
-	if p.VolRatioMin < 0 || p.VolRatioMin > 50 {
-		return fmt.Errorf("strategy.params.vol_ratio_min out of range")
-	}
-	if p.FakeMaxBars < 1 || p.FakeMaxBars > 300 {
-		return fmt.Errorf("strategy.params.fake_max_bars out of range")
+// params decodes strategy.params with the registered strategy's defaults and validation.
+func (s LLMStrategySection) params() (any, error) {
+	e, err := lookupStrategy(s.Type)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil
+	return e.decodeJSON(s.Params)
 }
 
 type yamlOut struct {
@@ -200,8 +157,8 @@ type yamlOut struct {
 	} `yaml:"backtest"`
 
 	Strategy struct {
-		Type   string        `yaml:"type"`
-		Params TsaiSenParams `yaml:"params"`
+		Type   string `yaml:"type"`
+		Params any    `yaml:"params"`
 	} `yaml:"strategy"`
 }
 
@@ -231,8 +188,12 @@ func (c LLMBacktestConfig) ToYAML() ([]byte, error) {
 	}
 	out.Backtest.Instruments = instruments
 
-	out.Strategy.Type = "tsai_sen"
-	out.Strategy.Params = c.Strategy.Params.withDefaults()
+	params, err := c.Strategy.params()
+	if err != nil {
+		return nil, err
+	}
+	out.Strategy.Type = strings.TrimSpace(c.Strategy.Type)
+	out.Strategy.Params = params
 
 	b, err := yaml.Marshal(out)
 	if err != nil {
@@ -15,7 +15,7 @@ func TestParseLLMBacktestConfigJSON_OK(t *testing.T) {
     "stock_lot_size": 100,
     "futures_multiplier": 1,
     "futures_margin_rate": 1,
-    "instruments": { "stocks": ["sh600000"], "futures": ["nf_I0", "pp2605"] }
+    "instruments": { "stocks": ["sh600000"], "futures": ["nf_I0", "pp2605", "hf_CL"] }
   },
   "strategy": {
     "type": "tsai_sen",
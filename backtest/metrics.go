@@ -0,0 +1,242 @@
+package backtest
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"stock/fetcher"
+)
+
+// tradingDaysPerYear annualizes daily return statistics.
+const tradingDaysPerYear = 252
+
+// Metrics are performance statistics derived from a Result's equity curve and trades.
+// Return statistics use daily equity (the last point of each day, so intraday runs
+// are comparable with daily ones) and a zero risk-free rate.
+type Metrics struct {
+	CAGRPct      float64 `json:"cagr_pct"`
+	AnnualVolPct float64 `json:"annual_vol_pct"`
+	Sharpe       float64 `json:"sharpe"`
+	Sortino      float64 `json:"sortino"`
+	// Calmar is CAGR / max drawdown.
+	Calmar float64 `json:"calmar"`
+
+	// ProfitFactor is gross profit / gross loss of closed trades (0 without losing trades).
+	ProfitFactor float64 `json:"profit_factor"`
+	// Expectancy is the average net P&L per trade.
+	Expectancy      float64 `json:"expectancy"`
+	AvgWin          float64 `json:"avg_win"`
+	AvgLoss         float64 `json:"avg_loss"`
+	AvgWinLossRatio float64 `json:"avg_win_loss_ratio"`
+
+	// LongestDDDays is the longest peak-to-recovery span in calendar days
+	// (an unrecovered drawdown counts up to the last bar).
+	LongestDDDays int `json:"longest_drawdown_days"`
+	// ExposurePct is the share of bars with an open position.
+	ExposurePct    float64        `json:"exposure_pct"`
+	AvgHoldingDays float64        `json:"avg_holding_days"`
+	MonthlyReturns []PeriodReturn `json:"monthly_returns,omitempty"`
+	YearlyReturns  []PeriodReturn `json:"yearly_returns,omitempty"`
+}
+
+// PeriodReturn is the equity return of one calendar month (2024-01) or year (2024).
+type PeriodReturn struct {
+	Period    string  `json:"period"`
+	ReturnPct float64 `json:"return_pct"`
+}
+
+type datedEquity struct {
+	t time.Time
+	v float64
+}
+
+// ComputeMetrics derives Metrics from an equity curve and its closed trades.
+// initialCash is the equity before the first bar; <= 0 uses the first point.
+func ComputeMetrics(curve []Point, trades []Trade, initialCash float64) Metrics {
+	var m Metrics
+	m.tradeStats(trades)
+
+	pts := make([]datedEquity, 0, len(curve))
+	for _, p := range curve {
+		t, err := fetcher.ParseKLineTime(p.Time, time.Local)
+		if err != nil {
+			continue
+		}
+		pts = append(pts, datedEquity{t: t, v: p.Equity})
+	}
+	if len(pts) == 0 {
+		return m
+	}
+	base := initialCash
+	if base <= 0 {
+		base = pts[0].v
+	}
+	if base <= 0 {
+		return m
+	}
+
+	daily := dailyCloses(pts)
+
+	final := daily[len(daily)-1].v
+	years := daily[len(daily)-1].t.Sub(daily[0].t).Hours() / 24 / 365.25
+	if years > 0 && final > 0 {
+		m.CAGRPct = round2((math.Pow(final/base, 1/years) - 1) * 100)
+	}
+
+	rets := dailyReturns(daily, base)
+	if len(rets) > 1 {
+		avg, sd, downside := mean(rets), 0.0, 0.0
+		for _, r := range rets {
+			sd += (r - avg) * (r - avg)
+			if r < 0 {
+				downside += r * r
+			}
+		}
+		sd = math.Sqrt(sd / float64(len(rets)-1))
+		downside = math.Sqrt(downside / float64(len(rets)))
+		ann := math.Sqrt(tradingDaysPerYear)
+		m.AnnualVolPct = round2(sd * ann * 100)
+		if sd > 0 {
+			m.Sharpe = round2(avg / sd * ann)
+		}
+		if downside > 0 {
+			m.Sortino = round2(avg / downside * ann)
+		}
+	}
+
+	maxDD := 0.0
+	peak, peakAt := base, daily[0].t
+	for _, d := range daily {
+		if d.v >= peak {
+			peak, peakAt = d.v, d.t
+			continue
+		}
+		if dd := (peak - d.v) / peak; dd > maxDD {
+			maxDD = dd
+		}
+		if days := int(d.t.Sub(peakAt).Hours() / 24); days > m.LongestDDDays {
+			m.LongestDDDays = days
+		}
+	}
+	if maxDD > 0 {
+		m.Calmar = round2(m.CAGRPct / (maxDD * 100))
+	}
+
+	m.MonthlyReturns = periodReturns(daily, base, "2006-01")
+	m.YearlyReturns = periodReturns(daily, base, "2006")
+	m.ExposurePct = exposurePct(curve, trades)
+	return m
+}
+
+// dailyCloses keeps the last point of each day.
+func dailyCloses(pts []datedEquity) []datedEquity {
+	var daily []datedEquity
+	for _, p := range pts {
+		if n := len(daily); n > 0 && sameDay(daily[n-1].t, p.t) {
+			daily[n-1] = p
+			continue
+		}
+		daily = append(daily, p)
+	}
+	return daily
+}
+
+// dailyReturns returns the day-over-day returns of daily, the first one against base.
+func dailyReturns(daily []datedEquity, base float64) []float64 {
+	rets := make([]float64, 0, len(daily))
+	prev := base
+	for _, d := range daily {
+		if prev > 0 {
+			rets = append(rets, d.v/prev-1)
+		}
+		prev = d.v
+	}
+	return rets
+}
+
+func (m *Metrics) tradeStats(trades []Trade) {
+	if len(trades) == 0 {
+		return
+	}
+	var net, grossWin, grossLoss, holdDays float64
+	wins, losses, held := 0, 0, 0
+	for _, t := range trades {
+		net += t.NetPnL
+		switch {
+		case t.NetPnL > 0:
+			grossWin += t.NetPnL
+			wins++
+		case t.NetPnL < 0:
+			grossLoss -= t.NetPnL
+			losses++
+		}
+		in, err1 := fetcher.ParseKLineTime(t.EntryTime, time.Local)
+		out, err2 := fetcher.ParseKLineTime(t.ExitTime, time.Local)
+		if err1 == nil && err2 == nil {
+			holdDays += out.Sub(in).Hours() / 24
+			held++
+		}
+	}
+	m.Expectancy = round2(net / float64(len(trades)))
+	if grossLoss > 0 {
+		m.ProfitFactor = round2(grossWin / grossLoss)
+	}
+	if wins > 0 {
+		m.AvgWin = round2(grossWin / float64(wins))
+	}
+	if losses > 0 {
+		m.AvgLoss = round2(-grossLoss / float64(losses))
+		if wins > 0 {
+			m.AvgWinLossRatio = round2((grossWin / float64(wins)) / (grossLoss / float64(losses)))
+		}
+	}
+	if held > 0 {
+		m.AvgHoldingDays = round2(holdDays / float64(held))
+	}
+}
+
+// periodReturns chains period-end equity into per-period returns; layout picks
+// the period key (month or year).
+func periodReturns(daily []datedEquity, base float64, layout string) []PeriodReturn {
+	var out []PeriodReturn
+	start := base
+	for i, d := range daily {
+		key := d.t.Format(layout)
+		if i+1 < len(daily) && daily[i+1].t.Format(layout) == key {
+			continue
+		}
+		ret := 0.0
+		if start > 0 {
+			ret = (d.v/start - 1) * 100
+		}
+		out = append(out, PeriodReturn{Period: key, ReturnPct: round2(ret)})
+		start = d.v
+	}
+	return out
+}
+
+// exposurePct counts bars that fall inside a trade: from its entry bar up to,
+// but excluding, its exit bar. Times compare as strings since both come from
+// RunConfig.FormatTime. Trades are swept in entry order: a bar is inside a
+// trade when one enters on it or one entered before it exits after it.
+func exposurePct(curve []Point, trades []Trade) float64 {
+	if len(curve) == 0 || len(trades) == 0 {
+		return 0
+	}
+	sorted := append([]Trade(nil), trades...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].EntryTime < sorted[j].EntryTime })
+	n, next := 0, 0
+	reach := "" // latest exit of the trades entered so far
+	for _, p := range curve {
+		entered := false
+		for ; next < len(sorted) && sorted[next].EntryTime <= p.Time; next++ {
+			entered = entered || sorted[next].EntryTime == p.Time
+			reach = max(reach, sorted[next].ExitTime)
+		}
+		if entered || p.Time < reach {
+			n++
+		}
+	}
+	return round2(float64(n) / float64(len(curve)) * 100)
+}
@@ -0,0 +1,91 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestComputeMetrics(t *testing.T) {
+	curve := []Point{
+		{Time: "2023-12-29", Equity: 100_000},
+		{Time: "2024-01-31", Equity: 110_000},
+		{Time: "2024-02-29", Equity: 99_000},
+		{Time: "2024-03-29", Equity: 104_500},
+		{Time: "2024-12-31", Equity: 121_000},
+	}
+	trades := []Trade{
+		{EntryTime: "2023-12-29", ExitTime: "2024-01-31", NetPnL: 10_000},
+		{EntryTime: "2024-01-31", ExitTime: "2024-02-29", NetPnL: -11_000},
+		{EntryTime: "2024-03-29", ExitTime: "2024-12-31", NetPnL: 22_000},
+	}
+	m := ComputeMetrics(curve, trades, 100_000)
+
+	// 21% over 368 days
+	if want := (math.Pow(1.21, 365.25/368) - 1) * 100; math.Abs(m.CAGRPct-want) > 0.01 {
+		t.Fatalf("cagr %v want %v", m.CAGRPct, want)
+	}
+	if m.ProfitFactor != 2.91 || m.Expectancy != 7000 || m.AvgWin != 16000 || m.AvgLoss != -11000 || m.AvgWinLossRatio != 1.45 {
+		t.Fatalf("trade stats %#v", m)
+	}
+	// peak 2024-01-31, recovered 2024-12-31
+	if m.LongestDDDays != 58 {
+		t.Fatalf("longest dd days = %d", m.LongestDDDays)
+	}
+	if math.Abs(m.Calmar-m.CAGRPct/10) > 0.01 {
+		t.Fatalf("calmar %v", m.Calmar)
+	}
+	// exit bars count as flat: 2024-02-29 and 2024-12-31 are out
+	if m.ExposurePct != 60 {
+		t.Fatalf("exposure %v", m.ExposurePct)
+	}
+	if m.AvgHoldingDays != round2((33+29+277)/3.0) {
+		t.Fatalf("holding days %v", m.AvgHoldingDays)
+	}
+	if m.Sharpe <= 0 || m.Sortino <= m.Sharpe || m.AnnualVolPct <= 0 {
+		t.Fatalf("risk ratios %#v", m)
+	}
+
+	wantMonths := []PeriodReturn{{"2023-12", 0}, {"2024-01", 10}, {"2024-02", -10}, {"2024-03", 5.56}, {"2024-12", 15.79}}
+	if len(m.MonthlyReturns) != len(wantMonths) {
+		t.Fatalf("monthly %#v", m.MonthlyReturns)
+	}
+	for i, w := range wantMonths {
+		if m.MonthlyReturns[i] != w {
+			t.Fatalf("month %d = %#v want %#v", i, m.MonthlyReturns[i], w)
+		}
+	}
+	if y := m.YearlyReturns; len(y) != 2 || y[0] != (PeriodReturn{"2023", 0}) || y[1] != (PeriodReturn{"2024", 21}) {
+		t.Fatalf("yearly %#v", y)
+	}
+}
+
+func TestComputeMetricsIntradayUsesDailyCloses(t *testing.T) {
+	curve := []Point{
+		{Time: "2024-03-01 09:35", Equity: 100},
+		{Time: "2024-03-01 15:00", Equity: 102},
+		{Time: "2024-03-04 09:35", Equity: 90},
+		{Time: "2024-03-04 15:00", Equity: 101},
+	}
+	m := ComputeMetrics(curve, nil, 0)
+	// daily closes 102 -> 101: the intraday dip to 90 does not count
+	if m.LongestDDDays != 3 || len(m.MonthlyReturns) != 1 || m.MonthlyReturns[0].ReturnPct != 1 {
+		t.Fatalf("intraday metrics %#v", m)
+	}
+}
+
+func TestExposurePctOverlappingTrades(t *testing.T) {
+	var curve []Point
+	for d := 1; d <= 10; d++ {
+		curve = append(curve, Point{Time: fmt.Sprintf("2024-01-%02d", d)})
+	}
+	trades := []Trade{
+		{EntryTime: "2024-01-06", ExitTime: "2024-01-08"}, // 06, 07
+		{EntryTime: "2024-01-02", ExitTime: "2024-01-05"}, // 02..04
+		{EntryTime: "2024-01-03", ExitTime: "2024-01-04"}, // inside the previous one
+		{EntryTime: "2024-01-09", ExitTime: "2024-01-09"}, // same-bar round trip
+	}
+	if got := exposurePct(curve, trades); got != 60 {
+		t.Fatalf("exposure %v, want 60", got)
+	}
+}
@@ -0,0 +1,261 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// MonteCarloMethod picks how trade sequences are drawn.
+type MonteCarloMethod string
+
+const (
+	// MonteCarloBootstrap draws len(trades) trades with replacement.
+	MonteCarloBootstrap MonteCarloMethod = "bootstrap"
+	// MonteCarloShuffle permutes the trades: final equity is unchanged
+	// (without slippage), only the path and thus the drawdown vary.
+	MonteCarloShuffle MonteCarloMethod = "shuffle"
+)
+
+// ParseMonteCarloMethod maps a user string to a method (default bootstrap).
+func ParseMonteCarloMethod(s string) (MonteCarloMethod, error) {
+	switch m := MonteCarloMethod(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return MonteCarloBootstrap, nil
+	case MonteCarloBootstrap, MonteCarloShuffle:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown monte carlo method %q (bootstrap|shuffle)", s)
+	}
+}
+
+// MonteCarloSpec configures a Monte Carlo run over a trade list.
+type MonteCarloSpec struct {
+	Method      MonteCarloMethod
+	Simulations int
+	Seed        int64
+	// SlippagePct adds a random extra cost of U(0, SlippagePct)% of the traded
+	// notional per side to every simulated trade.
+	SlippagePct float64
+	// RuinDDPct is the drawdown counted as ruin.
+	RuinDDPct float64
+	// Percentiles reported for final equity and max drawdown.
+	Percentiles []float64
+}
+
+// DefaultMonteCarloSpec: 5000 bootstrap runs, ruin at a 50% drawdown.
+func DefaultMonteCarloSpec() MonteCarloSpec {
+	return MonteCarloSpec{
+		Method:      MonteCarloBootstrap,
+		Simulations: 5000,
+		Seed:        1,
+		RuinDDPct:   50,
+		Percentiles: []float64{5, 25, 50, 75, 95},
+	}
+}
+
+// PercentileValue is one point of a distribution.
+type PercentileValue struct {
+	Percentile float64 `json:"percentile"`
+	Value      float64 `json:"value"`
+}
+
+// HistogramBin counts samples in [Lo, Hi).
+type HistogramBin struct {
+	Lo    float64 `json:"lo"`
+	Hi    float64 `json:"hi"`
+	Count int     `json:"count"`
+}
+
+// MonteCarloResult summarizes the simulated distributions for one result.
+type MonteCarloResult struct {
+	Symbol      string           `json:"symbol"`
+	Method      MonteCarloMethod `json:"method"`
+	Simulations int              `json:"simulations"`
+	Trades      int              `json:"trades"`
+	InitialCash float64          `json:"initial_cash"`
+	SlippagePct float64          `json:"slippage_pct"`
+	RuinDDPct   float64          `json:"ruin_dd_pct"`
+	// ActualFinalEquity / ActualMaxDDPct replay the trades in their real order.
+	ActualFinalEquity float64 `json:"actual_final_equity"`
+	ActualMaxDDPct    float64 `json:"actual_max_dd_pct"`
+
+	FinalEquity     []PercentileValue `json:"final_equity"`
+	MaxDDPct        []PercentileValue `json:"max_dd_pct"`
+	MeanFinalEquity float64           `json:"mean_final_equity"`
+	// ProbLossPct is the share of runs ending below the initial cash.
+	ProbLossPct float64 `json:"prob_loss_pct"`
+	// RiskOfRuinPct is the share of runs whose drawdown reached RuinDDPct.
+	RiskOfRuinPct float64 `json:"risk_of_ruin_pct"`
+
+	FinalEquityHistogram []HistogramBin `json:"final_equity_histogram,omitempty"`
+	MaxDDHistogram       []HistogramBin `json:"max_dd_histogram,omitempty"`
+	Error                string         `json:"error,omitempty"`
+}
+
+const monteCarloBins = 30
+
+// tradeStep is a closed trade as a return on the equity before it, plus the
+// traded notional as a fraction of that equity (for slippage).
+type tradeStep struct {
+	ret    float64
+	weight float64
+}
+
+// MonteCarlo resamples (or shuffles) the trades' returns on equity and
+// compounds them from initialCash, spec.Simulations times.
+func MonteCarlo(symbol string, trades []Trade, initialCash float64, spec MonteCarloSpec) MonteCarloResult {
+	if spec.Simulations <= 0 {
+		spec.Simulations = DefaultMonteCarloSpec().Simulations
+	}
+	if len(spec.Percentiles) == 0 {
+		spec.Percentiles = DefaultMonteCarloSpec().Percentiles
+	}
+	if spec.Method == "" {
+		spec.Method = MonteCarloBootstrap
+	}
+	out := MonteCarloResult{
+		Symbol:      symbol,
+		Method:      spec.Method,
+		Simulations: spec.Simulations,
+		Trades:      len(trades),
+		InitialCash: round2(initialCash),
+		SlippagePct: spec.SlippagePct,
+		RuinDDPct:   spec.RuinDDPct,
+	}
+	if initialCash <= 0 {
+		out.Error = "initial cash must be > 0"
+		return out
+	}
+	steps := tradeSteps(trades, initialCash)
+	if len(steps) == 0 {
+		out.Error = "no closed trades"
+		return out
+	}
+
+	final, dd := replay(steps, initialCash, 0, nil)
+	out.ActualFinalEquity = round2(final)
+	out.ActualMaxDDPct = round2(dd)
+
+	rng := rand.New(rand.NewSource(spec.Seed))
+	finals := make([]float64, spec.Simulations)
+	dds := make([]float64, spec.Simulations)
+	seq := make([]tradeStep, len(steps))
+	losses, ruined := 0, 0
+	for s := 0; s < spec.Simulations; s++ {
+		switch spec.Method {
+		case MonteCarloShuffle:
+			for i, j := range rng.Perm(len(steps)) {
+				seq[i] = steps[j]
+			}
+		default:
+			for i := range seq {
+				seq[i] = steps[rng.Intn(len(steps))]
+			}
+		}
+		finals[s], dds[s] = replay(seq, initialCash, spec.SlippagePct, rng)
+		if finals[s] < initialCash {
+			losses++
+		}
+		if spec.RuinDDPct > 0 && dds[s] >= spec.RuinDDPct {
+			ruined++
+		}
+	}
+
+	n := float64(spec.Simulations)
+	out.MeanFinalEquity = round2(mean(finals))
+	out.ProbLossPct = round2(float64(losses) / n * 100)
+	out.RiskOfRuinPct = round2(float64(ruined) / n * 100)
+	sort.Float64s(finals)
+	sort.Float64s(dds)
+	for _, p := range spec.Percentiles {
+		out.FinalEquity = append(out.FinalEquity, PercentileValue{Percentile: p, Value: round2(percentile(finals, p))})
+		out.MaxDDPct = append(out.MaxDDPct, PercentileValue{Percentile: p, Value: round2(percentile(dds, p))})
+	}
+	out.FinalEquityHistogram = histogram(finals, monteCarloBins)
+	out.MaxDDHistogram = histogram(dds, monteCarloBins)
+	return out
+}
+
+// tradeSteps converts trades (in exit order) into returns on the running
+// equity of closed trades.
+func tradeSteps(trades []Trade, initialCash float64) []tradeStep {
+	sorted := append([]Trade(nil), trades...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ExitTime < sorted[j].ExitTime })
+	eq := initialCash
+	steps := make([]tradeStep, 0, len(sorted))
+	for _, t := range sorted {
+		if eq <= 0 {
+			break
+		}
+		// notional from the price return when available (covers contract multipliers)
+		notional := t.Qty * t.EntryPrice
+		if math.Abs(t.ReturnPct) >= 0.01 {
+			notional = math.Abs(t.GrossPnL / (t.ReturnPct / 100))
+		}
+		steps = append(steps, tradeStep{ret: t.NetPnL / eq, weight: notional / eq})
+		eq += t.NetPnL
+	}
+	return steps
+}
+
+// replay compounds steps from initialCash and returns the final equity and
+// max drawdown (%). With slippagePct > 0 each step pays a random extra cost.
+func replay(steps []tradeStep, initialCash, slippagePct float64, rng *rand.Rand) (float64, float64) {
+	eq, peak, maxDD := initialCash, initialCash, 0.0
+	for _, s := range steps {
+		r := s.ret
+		if slippagePct > 0 && rng != nil {
+			// entry and exit side
+			r -= (rng.Float64() + rng.Float64()) * slippagePct / 100 * s.weight
+		}
+		eq *= 1 + r
+		if eq <= 0 {
+			return 0, 100
+		}
+		if eq > peak {
+			peak = eq
+		}
+		if dd := (peak - eq) / peak * 100; dd > maxDD {
+			maxDD = dd
+		}
+	}
+	return eq, maxDD
+}
+
+// percentile interpolates linearly in sorted xs (p in 0..100).
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := math.Max(0, math.Min(1, p/100)) * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+// histogram buckets sorted xs into n equal-width bins.
+func histogram(sorted []float64, n int) []HistogramBin {
+	if len(sorted) == 0 || n <= 0 {
+		return nil
+	}
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	if hi <= lo {
+		return []HistogramBin{{Lo: round2(lo), Hi: round2(hi), Count: len(sorted)}}
+	}
+	width := (hi - lo) / float64(n)
+	bins := make([]HistogramBin, n)
+	for i := range bins {
+		bins[i] = HistogramBin{Lo: round2(lo + float64(i)*width), Hi: round2(lo + float64(i+1)*width)}
+	}
+	for _, x := range sorted {
+		i := int((x - lo) / width)
+		if i >= n {
+			i = n - 1
+		}
+		bins[i].Count++
+	}
+	return bins
+}
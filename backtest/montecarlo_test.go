@@ -0,0 +1,74 @@
+package backtest
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestMonteCarlo(t *testing.T) {
+	var trades []Trade
+	pnls := []float64{5000, -3000, 8000, -2000, -4000, 6000, 1000, -1500}
+	for i, p := range pnls {
+		trades = append(trades, Trade{
+			Symbol: "sh600000", ExitTime: fmt.Sprintf("2024-01-%02d", i+10),
+			Qty: 1000, EntryPrice: 100, GrossPnL: p, NetPnL: p, ReturnPct: p / 1000,
+		})
+	}
+	spec := DefaultMonteCarloSpec()
+	spec.Method = MonteCarloShuffle
+	spec.Simulations = 500
+	res := MonteCarlo("sh600000", trades, 100000, spec)
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	// shuffling only reorders multiplicative steps: final equity is fixed
+	for _, p := range res.FinalEquity {
+		if math.Abs(p.Value-res.ActualFinalEquity) > 0.01 {
+			t.Fatalf("shuffle p%.0f final %v != actual %v", p.Percentile, p.Value, res.ActualFinalEquity)
+		}
+	}
+	if math.Abs(res.ActualFinalEquity-109500) > 0.01 {
+		t.Fatalf("actual final %v", res.ActualFinalEquity)
+	}
+	for i := 1; i < len(res.MaxDDPct); i++ {
+		if res.MaxDDPct[i].Value < res.MaxDDPct[i-1].Value {
+			t.Fatalf("percentiles not monotonic: %+v", res.MaxDDPct)
+		}
+	}
+
+	spec.Method = MonteCarloBootstrap
+	a := MonteCarlo("sh600000", trades, 100000, spec)
+	b := MonteCarlo("sh600000", trades, 100000, spec)
+	if a.MeanFinalEquity != b.MeanFinalEquity || a.RiskOfRuinPct != b.RiskOfRuinPct {
+		t.Fatalf("same seed must reproduce: %v vs %v", a.MeanFinalEquity, b.MeanFinalEquity)
+	}
+	n := 0
+	for _, bin := range a.FinalEquityHistogram {
+		n += bin.Count
+	}
+	if n != spec.Simulations {
+		t.Fatalf("histogram holds %d samples, want %d", n, spec.Simulations)
+	}
+
+	spec.SlippagePct = 1
+	c := MonteCarlo("sh600000", trades, 100000, spec)
+	if c.MeanFinalEquity >= a.MeanFinalEquity {
+		t.Fatalf("slippage must lower mean final equity: %v >= %v", c.MeanFinalEquity, a.MeanFinalEquity)
+	}
+
+	spec.RuinDDPct = 1
+	if r := MonteCarlo("sh600000", trades, 100000, spec); r.RiskOfRuinPct <= 0 {
+		t.Fatalf("a 1%% ruin threshold must be hit: %v", r.RiskOfRuinPct)
+	}
+
+	svg, err := RenderHistogramSVG("final equity", a.FinalEquityHistogram, []ChartLine{{Price: a.ActualFinalEquity, Label: "actual"}}, SVGChartOptions{})
+	if err != nil || !bytes.Contains(svg, []byte("<rect")) {
+		t.Fatalf("svg: %v", err)
+	}
+
+	if r := MonteCarlo("x", nil, 100000, spec); r.Error == "" {
+		t.Fatalf("no trades must report an error")
+	}
+}
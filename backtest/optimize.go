@@ -0,0 +1,500 @@
+package backtest
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"stock/fetcher"
+)
+
+// Objective ranks optimizer trials; higher scores are better.
+type Objective string
+
+const (
+	ObjectiveSharpe       Objective = "sharpe"
+	ObjectiveSortino      Objective = "sortino"
+	ObjectiveCalmar       Objective = "calmar"
+	ObjectiveCAGR         Objective = "cagr"
+	ObjectiveNetPnL       Objective = "net_pnl"
+	ObjectiveProfitFactor Objective = "profit_factor"
+)
+
+func ParseObjective(s string) (Objective, error) {
+	switch o := Objective(strings.ToLower(strings.TrimSpace(s))); o {
+	case "":
+		return ObjectiveSharpe, nil
+	case ObjectiveSharpe, ObjectiveSortino, ObjectiveCalmar, ObjectiveCAGR, ObjectiveNetPnL, ObjectiveProfitFactor:
+		return o, nil
+	default:
+		return "", fmt.Errorf("unknown objective: %s (sharpe|sortino|calmar|cagr|net_pnl|profit_factor)", s)
+	}
+}
+
+// Search modes.
+const (
+	SearchGrid   = "grid"
+	SearchRandom = "random"
+)
+
+// maxGridTrials guards against accidental huge Cartesian products.
+const maxGridTrials = 100_000
+
+// ParamRange is the search space of one strategy parameter. In YAML it is a
+// list of values ([40, 60, 80]), a range ({min: 0.005, max: 0.02, step: 0.005})
+// or a single fixed value. A range without step is continuous (random mode only).
+type ParamRange struct {
+	Values   []any
+	Min, Max float64
+}
+
+func (p *ParamRange) UnmarshalYAML(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		if err := n.Decode(&p.Values); err != nil {
+			return err
+		}
+		if len(p.Values) == 0 {
+			return fmt.Errorf("line %d: empty value list", n.Line)
+		}
+		return nil
+	case yaml.MappingNode:
+		var r struct {
+			Min  *float64 `yaml:"min"`
+			Max  *float64 `yaml:"max"`
+			Step float64  `yaml:"step"`
+		}
+		if err := n.Decode(&r); err != nil {
+			return err
+		}
+		if r.Min == nil || r.Max == nil || *r.Max < *r.Min || r.Step < 0 {
+			return fmt.Errorf("line %d: range needs min <= max and step >= 0", n.Line)
+		}
+		p.Min, p.Max = *r.Min, *r.Max
+		if r.Step > 0 {
+			p.Values = stepValues(*r.Min, *r.Max, r.Step)
+		}
+		return nil
+	default:
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+		p.Values = []any{v}
+		return nil
+	}
+}
+
+// stepValues expands min..max by step; integral ranges yield ints so they
+// decode into int params.
+func stepValues(min, max, step float64) []any {
+	integral := min == math.Trunc(min) && step == math.Trunc(step)
+	var out []any
+	for k := 0; ; k++ {
+		v := min + float64(k)*step
+		if v > max+step*1e-9 {
+			break
+		}
+		if integral {
+			out = append(out, int(math.Round(v)))
+		} else {
+			out = append(out, math.Round(v*1e10)/1e10)
+		}
+	}
+	return out
+}
+
+func (p ParamRange) continuous() bool { return len(p.Values) == 0 }
+
+// OptimizeSpec describes a parameter search (the `optimize` section).
+type OptimizeSpec struct {
+	// Mode: grid (Cartesian product, default) | random (Samples draws).
+	Mode      string
+	Objective Objective
+	Samples   int
+	Seed      int64
+	// Workers runs trials in parallel (0 = number of CPUs).
+	Workers int
+	Params  map[string]ParamRange
+}
+
+type optimizeYAML struct {
+	Optimize struct {
+		Mode      string                `yaml:"mode"`
+		Objective string                `yaml:"objective"`
+		Samples   int                   `yaml:"samples"`
+		Seed      int64                 `yaml:"seed"`
+		Workers   int                   `yaml:"workers"`
+		Params    map[string]ParamRange `yaml:"params"`
+	} `yaml:"optimize"`
+}
+
+// LoadOptimizeSpec reads the `optimize` section of a YAML file (a dedicated
+// spec file or backtest.yaml itself).
+func LoadOptimizeSpec(path string) (OptimizeSpec, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return OptimizeSpec{}, fmt.Errorf("read optimize spec: %w", err)
+	}
+	var y optimizeYAML
+	if err := yaml.Unmarshal(raw, &y); err != nil {
+		return OptimizeSpec{}, fmt.Errorf("parse yaml: %w", err)
+	}
+	o := y.Optimize
+	obj, err := ParseObjective(o.Objective)
+	if err != nil {
+		return OptimizeSpec{}, fmt.Errorf("invalid optimize.objective: %w", err)
+	}
+	spec := OptimizeSpec{
+		Mode:      strings.ToLower(strings.TrimSpace(o.Mode)),
+		Objective: obj,
+		Samples:   o.Samples,
+		Seed:      o.Seed,
+		Workers:   o.Workers,
+		Params:    o.Params,
+	}
+	if spec.Mode == "" {
+		spec.Mode = SearchGrid
+	}
+	return spec, spec.validate()
+}
+
+func (s OptimizeSpec) validate() error {
+	if len(s.Params) == 0 {
+		return fmt.Errorf("optimize.params is empty")
+	}
+	switch s.Mode {
+	case SearchGrid:
+		for name, p := range s.Params {
+			if p.continuous() {
+				return fmt.Errorf("optimize.params.%s: grid mode needs a value list or step", name)
+			}
+		}
+		if n := s.gridSize(); n > maxGridTrials {
+			return fmt.Errorf("grid has %d combinations (max %d); use mode: random", n, maxGridTrials)
+		}
+	case SearchRandom:
+		if s.Samples <= 0 {
+			return fmt.Errorf("optimize.samples must be > 0 in random mode")
+		}
+	default:
+		return fmt.Errorf("unknown optimize.mode: %s (grid|random)", s.Mode)
+	}
+	return nil
+}
+
+// ParamNames returns the searched parameter names, sorted.
+func (s OptimizeSpec) ParamNames() []string {
+	names := make([]string, 0, len(s.Params))
+	for name := range s.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s OptimizeSpec) gridSize() int {
+	n := 1
+	for _, p := range s.Params {
+		n *= len(p.Values)
+		if n > maxGridTrials {
+			return n
+		}
+	}
+	return n
+}
+
+// Combinations returns the parameter sets to evaluate: the Cartesian product
+// in grid mode (last name varies fastest), or Samples seeded draws.
+func (s OptimizeSpec) Combinations() []map[string]any {
+	names := s.ParamNames()
+	if s.Mode == SearchRandom {
+		rng := rand.New(rand.NewSource(s.Seed))
+		out := make([]map[string]any, s.Samples)
+		for i := range out {
+			m := make(map[string]any, len(names))
+			for _, name := range names {
+				p := s.Params[name]
+				if p.continuous() {
+					m[name] = math.Round((p.Min+rng.Float64()*(p.Max-p.Min))*1e6) / 1e6
+				} else {
+					m[name] = p.Values[rng.Intn(len(p.Values))]
+				}
+			}
+			out[i] = m
+		}
+		return out
+	}
+
+	out := []map[string]any{{}}
+	for _, name := range names {
+		var next []map[string]any
+		for _, base := range out {
+			for _, v := range s.Params[name].Values {
+				m := make(map[string]any, len(base)+1)
+				for k, x := range base {
+					m[k] = x
+				}
+				m[name] = v
+				next = append(next, m)
+			}
+		}
+		out = next
+	}
+	return out
+}
+
+// OptimizeTrial is one evaluated parameter set. Multi-instrument runs are
+// aggregated: P&L and trades are summed, ratios averaged, drawdown is the worst.
+type OptimizeTrial struct {
+	Params       map[string]any `json:"params"`
+	Score        float64        `json:"score"`
+	NetPnL       float64        `json:"net_pnl"`
+	CAGRPct      float64        `json:"cagr_pct"`
+	Sharpe       float64        `json:"sharpe"`
+	Sortino      float64        `json:"sortino"`
+	Calmar       float64        `json:"calmar"`
+	ProfitFactor float64        `json:"profit_factor"`
+	MaxDDPct     float64        `json:"max_drawdown_pct"`
+	WinRatePct   float64        `json:"win_rate_pct"`
+	Trades       int            `json:"total_trades"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// Optimize evaluates every combination of spec on cfg (strategy.params
+// overlaid with the combination) and returns the trials best first; failed
+// trials sort last. Bars are fetched once and shared by all trials.
+func (r *Runner) Optimize(cfg RunConfig, spec OptimizeSpec) ([]OptimizeTrial, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	if len(cfg.Instruments) == 0 {
+		return nil, fmt.Errorf("no instruments configured")
+	}
+	combos := spec.Combinations()
+	// reject typos in parameter names up front
+	if _, err := newStrategy(cfg.StrategyType, mergeParams(cfg.StrategyParams, combos[0]), true); err != nil {
+		return nil, err
+	}
+
+	runner := r.memoized()
+	cfg.Benchmark = nil
+	trials := make([]OptimizeTrial, len(combos))
+	workers := spec.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				trials[i] = runner.runTrial(cfg, combos[i], spec.Objective)
+			}
+		}()
+	}
+	for i := range combos {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.SliceStable(trials, func(i, j int) bool {
+		if (trials[i].Error == "") != (trials[j].Error == "") {
+			return trials[i].Error == ""
+		}
+		return trials[i].Score > trials[j].Score
+	})
+	return trials, nil
+}
+
+func (r *Runner) runTrial(cfg RunConfig, combo map[string]any, obj Objective) OptimizeTrial {
+	params := mergeParams(cfg.StrategyParams, combo)
+	strategy, err := NewStrategy(cfg.StrategyType, params)
+	if err == nil {
+		err = cfg.checkTimeframes(strategy)
+	}
+	if err != nil {
+		return OptimizeTrial{Params: combo, Error: err.Error()}
+	}
+	cfg.Strategy = strategy
+	cfg.StrategyParams = params
+
+	results, err := r.runAll(cfg)
+	if err != nil {
+		return OptimizeTrial{Params: combo, Error: err.Error()}
+	}
+	t := scoreResults(results, cfg.InitialCash, obj)
+	t.Params = combo
+	return t
+}
+
+// runAll runs cfg as a portfolio or per instrument, as configured.
+func (r *Runner) runAll(cfg RunConfig) ([]Result, error) {
+	if cfg.Portfolio {
+		res, err := r.RunPortfolio(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return []Result{res}, nil
+	}
+	return r.Run(cfg)
+}
+
+// scoreResults aggregates the results of one run into a trial scored by obj.
+func scoreResults(results []Result, initialCash float64, obj Objective) OptimizeTrial {
+	var t OptimizeTrial
+	n, wins := 0, 0
+	for _, res := range results {
+		if len(res.Errors) > 0 || res.Metrics == nil {
+			continue
+		}
+		n++
+		m := res.Metrics
+		t.NetPnL += res.FinalEquity - initialCash
+		t.CAGRPct += m.CAGRPct
+		t.Sharpe += m.Sharpe
+		t.Sortino += m.Sortino
+		t.Calmar += m.Calmar
+		t.ProfitFactor += m.ProfitFactor
+		t.MaxDDPct = math.Max(t.MaxDDPct, res.MaxDDPct)
+		t.Trades += res.TotalTrades
+		for _, tr := range res.Trades {
+			if tr.NetPnL > 0 {
+				wins++
+			}
+		}
+	}
+	if n == 0 {
+		t.Error = "no instrument produced a result"
+		if len(results) > 0 && len(results[0].Errors) > 0 {
+			t.Error = results[0].Errors[0]
+		}
+		return t
+	}
+	k := float64(n)
+	t.NetPnL = round2(t.NetPnL)
+	t.CAGRPct = round2(t.CAGRPct / k)
+	t.Sharpe = round2(t.Sharpe / k)
+	t.Sortino = round2(t.Sortino / k)
+	t.Calmar = round2(t.Calmar / k)
+	t.ProfitFactor = round2(t.ProfitFactor / k)
+	if t.Trades > 0 {
+		t.WinRatePct = round2(float64(wins) / float64(t.Trades) * 100)
+	}
+
+	switch obj {
+	case ObjectiveNetPnL:
+		t.Score = t.NetPnL
+	case ObjectiveCalmar:
+		t.Score = t.Calmar
+	case ObjectiveCAGR:
+		t.Score = t.CAGRPct
+	case ObjectiveSortino:
+		t.Score = t.Sortino
+	case ObjectiveProfitFactor:
+		t.Score = t.ProfitFactor
+	default:
+		t.Score = t.Sharpe
+	}
+	return t
+}
+
+func mergeParams(base, over map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		out[k] = v
+	}
+	return out
+}
+
+// SetStrategyParamsYAML returns the backtest.yaml document raw with
+// strategy.params replaced by params, keeping the rest (and comments) as is.
+func SetStrategyParamsYAML(raw []byte, params map[string]any) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("parse yaml: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("backtest config is not a mapping")
+	}
+	var paramsNode yaml.Node
+	if err := paramsNode.Encode(params); err != nil {
+		return nil, err
+	}
+	strategy := mappingValue(doc.Content[0], "strategy")
+	*mappingValue(strategy, "params") = paramsNode
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mappingValue returns the value node of key in mapping m, adding the key
+// with an empty mapping when missing.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	return v
+}
+
+// memoSource caches fetched bars so repeated runs (optimizer trials) hit the
+// underlying source once per series.
+type memoSource struct {
+	src   fetcher.BarSource
+	mu    sync.Mutex
+	lines map[string][]fetcher.KLine
+}
+
+func (m *memoSource) FetchStockKLine(code string, days int, opt fetcher.KLineOptions) ([]fetcher.KLine, error) {
+	return m.fetch("s", code, days, opt, m.src.FetchStockKLine)
+}
+
+func (m *memoSource) FetchFuturesKLine(code string, days int, opt fetcher.KLineOptions) ([]fetcher.KLine, error) {
+	return m.fetch("f", code, days, opt, m.src.FetchFuturesKLine)
+}
+
+func (m *memoSource) fetch(kind, code string, days int, opt fetcher.KLineOptions, load func(string, int, fetcher.KLineOptions) ([]fetcher.KLine, error)) ([]fetcher.KLine, error) {
+	key := fmt.Sprintf("%s|%s|%d|%s|%s", kind, code, days, opt.Adjust, opt.Period)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if kl, ok := m.lines[key]; ok {
+		return kl, nil
+	}
+	kl, err := load(code, days, opt)
+	if err != nil {
+		return nil, err
+	}
+	m.lines[key] = kl
+	return kl, nil
+}
+
+// memoized returns a Runner sharing r's source through a fetch cache.
+func (r *Runner) memoized() *Runner {
+	if _, ok := r.source.(*memoSource); ok {
+		return r
+	}
+	return &Runner{source: &memoSource{src: r.source, lines: map[string][]fetcher.KLine{}}}
+}
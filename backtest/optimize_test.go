@@ -0,0 +1,139 @@
+package backtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stock/fetcher"
+)
+
+func TestLoadOptimizeSpecGrid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "optimize.yaml")
+	yml := `optimize:
+  objective: calmar
+  params:
+    box_lookback: {min: 40, max: 80, step: 20}
+    break_pct: {min: 0.005, max: 0.015, step: 0.005}
+    entry_mode: [reclaim_support, break_resistance]
+`
+	if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := LoadOptimizeSpec(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if spec.Mode != SearchGrid || spec.Objective != ObjectiveCalmar {
+		t.Fatalf("spec %#v", spec)
+	}
+	if v := spec.Params["box_lookback"].Values; len(v) != 3 || v[0] != 40 || v[2] != 80 {
+		t.Fatalf("int range %#v", v)
+	}
+	if v := spec.Params["break_pct"].Values; len(v) != 3 || v[1] != 0.01 || v[2] != 0.015 {
+		t.Fatalf("float range %#v", v)
+	}
+	combos := spec.Combinations()
+	if len(combos) != 18 {
+		t.Fatalf("grid size %d", len(combos))
+	}
+	// last name varies fastest
+	if combos[0]["entry_mode"] != "reclaim_support" || combos[1]["entry_mode"] != "break_resistance" {
+		t.Fatalf("combo order %v %v", combos[0], combos[1])
+	}
+
+	if err := os.WriteFile(path, []byte("optimize:\n  params:\n    break_pct: {min: 0.005, max: 0.02}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOptimizeSpec(path); err == nil {
+		t.Fatalf("continuous range must be rejected in grid mode")
+	}
+	if err := os.WriteFile(path, []byte("optimize:\n  mode: random\n  samples: 5\n  seed: 7\n  params:\n    break_pct: {min: 0.005, max: 0.02}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec, err = LoadOptimizeSpec(path)
+	if err != nil {
+		t.Fatalf("random: %v", err)
+	}
+	a, b := spec.Combinations(), spec.Combinations()
+	for i := range a {
+		v := a[i]["break_pct"].(float64)
+		if v < 0.005 || v > 0.02 || v != b[i]["break_pct"] {
+			t.Fatalf("random sample %d = %v (repeat %v)", i, v, b[i]["break_pct"])
+		}
+	}
+}
+
+type countingSource struct {
+	*fetcher.MemoryBarSource
+	calls int32
+}
+
+func (s *countingSource) FetchStockKLine(code string, days int, opt fetcher.KLineOptions) ([]fetcher.KLine, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return s.MemoryBarSource.FetchStockKLine(code, days, opt)
+}
+
+func TestRunnerOptimize(t *testing.T) {
+	src := &countingSource{MemoryBarSource: fetcher.NewMemoryBarSource()}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	var kl []fetcher.KLine
+	for i := 0; i < 120; i++ {
+		c := 10 + float64(i%20)*0.1
+		kl = append(kl, fetcher.KLine{Date: start.AddDate(0, 0, i).Format("2006-01-02"), Open: c, High: c + 0.2, Low: c - 0.2, Close: c, Volume: 100})
+	}
+	src.Set("sh600000", kl)
+
+	cfg := DefaultRunConfig()
+	cfg.Instruments = []Instrument{{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}}
+	cfg.StrategyParams = map[string]any{"vol_ma_n": 10}
+	spec := OptimizeSpec{
+		Mode:      SearchGrid,
+		Objective: ObjectiveNetPnL,
+		Workers:   4,
+		Params:    map[string]ParamRange{"box_lookback": {Values: []any{20, 40, 60}}, "target_multiple": {Values: []any{1.0, 2.0}}},
+	}
+	trials, err := NewRunnerWithSource(src).Optimize(cfg, spec)
+	if err != nil {
+		t.Fatalf("optimize: %v", err)
+	}
+	if len(trials) != 6 {
+		t.Fatalf("trials %d", len(trials))
+	}
+	for i, tr := range trials {
+		if tr.Error != "" {
+			t.Fatalf("trial %d: %s", i, tr.Error)
+		}
+		if i > 0 && tr.Score > trials[i-1].Score {
+			t.Fatalf("trials not ranked: %v > %v", tr.Score, trials[i-1].Score)
+		}
+	}
+	if n := atomic.LoadInt32(&src.calls); n != 1 {
+		t.Fatalf("bars fetched %d times, want once", n)
+	}
+
+	spec.Params = map[string]ParamRange{"box_lookbak": {Values: []any{20}}}
+	if _, err := NewRunnerWithSource(src).Optimize(cfg, spec); err == nil || !strings.Contains(err.Error(), "box_lookbak") {
+		t.Fatalf("expected unknown param error, got %v", err)
+	}
+}
+
+func TestSetStrategyParamsYAML(t *testing.T) {
+	raw := []byte("# my config\nbacktest:\n  days: 500 # bars\nstrategy:\n  type: tsai_sen\n  params:\n    box_lookback: 60\n")
+	out, err := SetStrategyParamsYAML(raw, map[string]any{"box_lookback": 40, "break_pct": 0.01})
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	s := string(out)
+	for _, want := range []string{"# my config", "days: 500 # bars", "type: tsai_sen", "box_lookback: 40", "break_pct: 0.01"} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("missing %q in:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "box_lookback: 60") {
+		t.Fatalf("old params kept:\n%s", s)
+	}
+}
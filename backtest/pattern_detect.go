@@ -3,6 +3,8 @@ package backtest
 import (
 	"math"
 	"sort"
+
+	"stock/indicators"
 )
 
 type pivotKind uint8
@@ -44,36 +46,13 @@ func collectPivotsAll(bars []Bar, start, end, n int) []pivotPoint {
 	return out
 }
 
+// Pattern detection ignores pivots at a non-positive price.
 func isPivotLowBar(bars []Bar, idx, n int) bool {
-	x := bars[idx].Low
-	if x <= 0 {
-		return false
-	}
-	for k := idx - n; k <= idx+n; k++ {
-		if k == idx {
-			continue
-		}
-		if bars[k].Low < x {
-			return false
-		}
-	}
-	return true
+	return bars[idx].Low > 0 && indicators.PivotLow(bars, idx, n)
 }
 
 func isPivotHighBar(bars []Bar, idx, n int) bool {
-	x := bars[idx].High
-	if x <= 0 {
-		return false
-	}
-	for k := idx - n; k <= idx+n; k++ {
-		if k == idx {
-			continue
-		}
-		if bars[k].High > x {
-			return false
-		}
-	}
-	return true
+	return bars[idx].High > 0 && indicators.PivotHigh(bars, idx, n)
 }
 
 func approxEqual(a, b, tolPct float64) bool {
@@ -0,0 +1,198 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// PortfolioSymbol is the Result.Symbol of a portfolio run.
+const PortfolioSymbol = "portfolio"
+
+// Attribution is one instrument's contribution to a portfolio run.
+type Attribution struct {
+	Symbol     string  `json:"symbol"`
+	Instrument string  `json:"instrument"`
+	Trades     int     `json:"trades"`
+	WinRatePct float64 `json:"win_rate_pct"`
+	NetPnL     float64 `json:"net_pnl"`
+	// ContributionPct is NetPnL relative to the initial cash.
+	ContributionPct float64 `json:"contribution_pct"`
+}
+
+// RunPortfolio backtests all instruments on one account: a single cash balance
+// and margin pool, walked bar by bar over the union of all bar times.
+//
+// Each new position is allotted equity * position_pct / slots (capped at the
+// free cash), where slots is max_positions or, when unset, the number of
+// instruments. Entries beyond max_positions are skipped and counted. On each
+// bar, exits fill before entries so freed capital and slots can be reused.
+func (r *Runner) RunPortfolio(cfg RunConfig) (Result, error) {
+	if len(cfg.Instruments) == 0 {
+		return Result{}, fmt.Errorf("no instruments configured")
+	}
+
+	res := Result{Symbol: PortfolioSymbol, Instrument: PortfolioSymbol}
+	var legs []*leg
+	for _, inst := range cfg.Instruments {
+		bars, rolls, err := r.loadSeries(inst, cfg)
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", inst.Symbol, err))
+			continue
+		}
+		res.Rolls = append(res.Rolls, rolls...)
+		l := newLeg(inst, bars, cfg)
+		legs = append(legs, &l)
+	}
+	if len(legs) == 0 {
+		return res, nil
+	}
+
+	slots := cfg.MaxPositions
+	if slots <= 0 {
+		slots = len(legs)
+	}
+
+	seen := map[int64]time.Time{}
+	for _, l := range legs {
+		for _, b := range l.bars {
+			seen[b.Time.Unix()] = b.Time
+		}
+	}
+	times := make([]time.Time, 0, len(seen))
+	for _, t := range seen {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	cash := cfg.InitialCash
+	equityCurve := make([]Point, 0, len(times))
+	peakEquity := cash
+	maxDD := 0.0
+
+	equity := func() float64 {
+		e := cash
+		for _, l := range legs {
+			e += l.value()
+		}
+		return e
+	}
+	// closed trades of all legs, for kelly sizing
+	closedTrades := func() []Trade {
+		if cfg.Sizing.Model != SizingKelly {
+			return nil
+		}
+		var out []Trade
+		for _, l := range legs {
+			out = append(out, l.trades...)
+		}
+		return out
+	}
+	openPositions := func() int {
+		n := 0
+		for _, l := range legs {
+			if l.pos.Side != SideFlat {
+				n++
+			}
+		}
+		return n
+	}
+
+	for _, t := range times {
+		var active []*leg
+		for _, l := range legs {
+			if l.next < len(l.bars) && l.bars[l.next].Time.Equal(t) {
+				active = append(active, l)
+			}
+		}
+		if t.Before(cfg.TradeFrom) {
+			for _, l := range active {
+				l.warmup()
+			}
+			continue
+		}
+
+		// Fill pending orders at this bar's open: exits first, then entries.
+		for _, exits := range []bool{true, false} {
+			e := equity()
+			budgetBase := e * cfg.PositionPct / float64(slots)
+			for _, l := range active {
+				if !l.due() {
+					continue
+				}
+				opening := l.pending.Action == SignalBuy || l.pending.Action == SignalShort
+				if opening == exits {
+					continue
+				}
+				if opening && l.pos.Side == SideFlat && openPositions() >= slots {
+					res.SkippedEntries++
+					l.pending = nil
+					continue
+				}
+				size := sizeInput{budget: math.Min(cash, budgetBase), equity: e, atr: atrAt(l.atr, l.next-1), trades: closedTrades()}
+				cash += l.fillPending(size, &res.Unfilled)
+			}
+		}
+
+		// Intrabar stop/target, then signals at the close; force-close a
+		// position when its series ends.
+		for _, l := range active {
+			cashDelta, _ := l.closeBar(&res.Unfilled)
+			cash += cashDelta
+			if l.next == len(l.bars) {
+				cash += l.forceClose()
+			}
+		}
+
+		e := equity()
+		equityCurve = append(equityCurve, Point{Time: cfg.FormatTime(t), Equity: e})
+		if e > peakEquity {
+			peakEquity = e
+		}
+		if peakEquity > 0 {
+			if dd := (peakEquity - e) / peakEquity; dd > maxDD {
+				maxDD = dd
+			}
+		}
+	}
+
+	wins := 0
+	for _, l := range legs {
+		a := Attribution{Symbol: l.inst.Symbol, Instrument: string(l.inst.Type), Trades: len(l.trades)}
+		w := 0
+		for _, tr := range l.trades {
+			a.NetPnL += tr.NetPnL
+			if tr.NetPnL > 0 {
+				w++
+			}
+		}
+		if len(l.trades) > 0 {
+			a.WinRatePct = round2(float64(w) / float64(len(l.trades)) * 100)
+		}
+		if cfg.InitialCash > 0 {
+			a.ContributionPct = round2(a.NetPnL / cfg.InitialCash * 100)
+		}
+		a.NetPnL = round2(a.NetPnL)
+		res.Attribution = append(res.Attribution, a)
+		res.Trades = append(res.Trades, l.trades...)
+		wins += w
+	}
+	sort.SliceStable(res.Trades, func(i, j int) bool { return res.Trades[i].ExitTime < res.Trades[j].ExitTime })
+
+	res.TotalTrades = len(res.Trades)
+	if res.TotalTrades > 0 {
+		res.WinRatePct = round2(float64(wins) / float64(res.TotalTrades) * 100)
+	}
+	res.FinalEquity = round2(cash)
+	if n := len(equityCurve); n > 0 {
+		res.FinalEquity = round2(equityCurve[n-1].Equity)
+	}
+	res.MaxDDPct = round2(maxDD * 100)
+	res.EquityCurve = equityCurve
+	metrics := ComputeMetrics(equityCurve, res.Trades, cfg.InitialCash)
+	res.Metrics = &metrics
+	benchBars, benchErr := r.loadBenchmark(cfg)
+	attachBenchmark(&res, cfg, benchBars, benchErr)
+	return res, nil
+}
@@ -0,0 +1,116 @@
+package backtest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"stock/fetcher"
+)
+
+// scriptStrategy emits fixed actions at given bar indexes.
+type scriptStrategy struct {
+	at map[int]SignalAction
+}
+
+func (s *scriptStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
+	if a, ok := s.at[i]; ok {
+		return &Signal{Time: bars[i].Time, Action: a, Reason: string(a)}
+	}
+	return nil
+}
+
+func (s *scriptStrategy) Clone() Strategy { return &scriptStrategy{at: s.at} }
+
+func TestRunPortfolioSharedCash(t *testing.T) {
+	src := fetcher.NewMemoryBarSource()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	series := func(from int, price func(i int) float64) []fetcher.KLine {
+		var kl []fetcher.KLine
+		for i := from; i < 60; i++ {
+			p := price(i)
+			kl = append(kl, fetcher.KLine{Date: start.AddDate(0, 0, i).Format(fetcher.DateLayout), Open: p, High: p, Low: p, Close: p, Volume: 100})
+		}
+		return kl
+	}
+	src.Set("sh600000", series(0, func(i int) float64 { return 10 + float64(i)*0.1 }))
+	src.Set("sz000001", series(0, func(int) float64 { return 20 }))
+	src.Set("sh600519", series(5, func(int) float64 { return 50 })) // starts later
+
+	cfg := DefaultRunConfig()
+	cfg.InitialCash = 100_000
+	cfg.SlippageBps = 0
+	cfg.CommissionBps = 0
+	cfg.MaxPositions = 2
+	cfg.Strategy = &scriptStrategy{at: map[int]SignalAction{0: SignalBuy, 30: SignalSell}}
+	for _, s := range []string{"sh600000", "sz000001", "sh600519"} {
+		cfg.Instruments = append(cfg.Instruments, Instrument{Symbol: s, Type: InstrumentTypeStock, LotSize: 100})
+	}
+
+	res, err := NewRunnerWithSource(src).RunPortfolio(cfg)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors: %v", res.Errors)
+	}
+	if len(res.EquityCurve) != 60 || res.EquityCurve[0].Equity != 100_000 {
+		t.Fatalf("expected one shared account over 60 bars, got %d points starting at %v", len(res.EquityCurve), res.EquityCurve[0].Equity)
+	}
+	// the two early symbols take both slots; sh600519's entry (its bar 0 = day 5) is skipped
+	if res.SkippedEntries != 1 {
+		t.Fatalf("skipped entries = %d", res.SkippedEntries)
+	}
+	if len(res.Attribution) != 3 || res.Attribution[2].Trades != 0 {
+		t.Fatalf("unexpected attribution %#v", res.Attribution)
+	}
+	// sh600000: 50_000 budget at 10.1 -> 4900 shares, exit at day 31 close 13.1
+	a := res.Attribution[0]
+	if a.Trades != 1 || a.NetPnL != 14_700 || a.ContributionPct != 14.7 {
+		t.Fatalf("sh600000 attribution %#v", a)
+	}
+	if res.FinalEquity != 114_700 || res.TotalTrades != 2 {
+		t.Fatalf("final equity %v, trades %d", res.FinalEquity, res.TotalTrades)
+	}
+}
+
+// A one-instrument portfolio steps the same leg as runOne, so every engine
+// feature (A-share gate, intrabar exits, sizing) gives the same result.
+func TestRunPortfolioMatchesRunOne(t *testing.T) {
+	src := fetcher.NewMemoryBarSource()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	var kl []fetcher.KLine
+	for i := 0; i < 60; i++ {
+		k := fetcher.KLine{Date: start.AddDate(0, 0, i).Format(fetcher.DateLayout), Open: 10, High: 10.2, Low: 9.8, Close: 10, Volume: 100}
+		if i == 2 || i == 5 {
+			k.Low = 9.0 // stop touched on the entry day (T+1 refuses it) and again later
+		}
+		kl = append(kl, k)
+	}
+	src.Set("sh600000", kl)
+
+	cfg := DefaultRunConfig()
+	cfg.AShareRules = true
+	cfg.IntrabarExits = true
+	cfg.InitialCash = 100_000
+	cfg.Strategy = &levelStrategy{}
+	cfg.Instruments = []Instrument{{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}}
+	runner := NewRunnerWithSource(src)
+
+	single, err := runner.Run(cfg)
+	if err != nil || len(single) != 1 {
+		t.Fatalf("run: %v %v", single, err)
+	}
+	port, err := runner.RunPortfolio(cfg)
+	if err != nil {
+		t.Fatalf("portfolio: %v", err)
+	}
+	want := single[0]
+	if len(want.Trades) != 1 || want.Trades[0].ExitTime != "2024-01-06" || len(want.Unfilled) != 1 {
+		t.Fatalf("run one: trades %#v unfilled %#v errors %v", want.Trades, want.Unfilled, want.Errors)
+	}
+	if !reflect.DeepEqual(port.Trades, want.Trades) || !reflect.DeepEqual(port.Unfilled, want.Unfilled) ||
+		!reflect.DeepEqual(port.EquityCurve, want.EquityCurve) {
+		t.Fatalf("portfolio %#v %#v\nrun one %#v %#v", port.Trades, port.Unfilled, want.Trades, want.Unfilled)
+	}
+}
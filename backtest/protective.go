@@ -0,0 +1,128 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Exit reasons of protective orders (Trade.ReasonExit).
+const (
+	ReasonStopGap   = "stop_gap"   // opened beyond the stop: filled at the open
+	ReasonStopHit   = "stop_hit"   // low/high touched the stop: filled at the stop
+	ReasonTargetGap = "target_gap" // opened beyond the target: filled at the open
+	ReasonTargetHit = "target_hit" // low/high touched the target: filled at the target
+)
+
+// IntrabarConflict decides which protective order fills when one bar touches both.
+type IntrabarConflict string
+
+const (
+	// ConflictStop assumes the stop was hit first (conservative default).
+	ConflictStop IntrabarConflict = "stop"
+	// ConflictTarget assumes the target was hit first.
+	ConflictTarget IntrabarConflict = "target"
+	// ConflictNearest assumes the level closer to the open was hit first.
+	ConflictNearest IntrabarConflict = "nearest"
+)
+
+// ParseIntrabarConflict parses backtest.intrabar_conflict; empty means ConflictStop.
+func ParseIntrabarConflict(s string) (IntrabarConflict, error) {
+	switch c := IntrabarConflict(strings.ToLower(strings.TrimSpace(s))); c {
+	case "":
+		return ConflictStop, nil
+	case ConflictStop, ConflictTarget, ConflictNearest:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown intrabar conflict rule: %s (stop|target|nearest)", s)
+	}
+}
+
+// withLevels attaches protective stop/target levels to an entry signal.
+func withLevels(sig *Signal, stop, target float64) *Signal {
+	sig.Stop = stop
+	sig.Target = target
+	return sig
+}
+
+// protectiveExit checks the stop/target of pos against bar. It returns the
+// exit signal and raw fill price (before slippage), or nil. An open beyond a
+// level fills at the open, also on the bar the position was opened: its open
+// is the entry itself, so the level is hit as soon as the position exists.
+func protectiveExit(pos Position, bar Bar, conflict IntrabarConflict) (*Signal, float64) {
+	if pos.Side == SideFlat || (pos.Stop <= 0 && pos.Target <= 0) {
+		return nil, 0
+	}
+	long := pos.Side == SideLong
+	action := SignalSell
+	if !long {
+		action = SignalCover
+	}
+	exit := func(reason string, price float64) (*Signal, float64) {
+		return &Signal{Time: bar.Time, Action: action, Reason: reason}, price
+	}
+	// beyond reports whether price is at or past level in the given direction.
+	stopBeyond := func(price float64) bool {
+		if pos.Stop <= 0 {
+			return false
+		}
+		if long {
+			return price <= pos.Stop
+		}
+		return price >= pos.Stop
+	}
+	targetBeyond := func(price float64) bool {
+		if pos.Target <= 0 {
+			return false
+		}
+		if long {
+			return price >= pos.Target
+		}
+		return price <= pos.Target
+	}
+
+	if bar.Open > 0 {
+		if stopBeyond(bar.Open) {
+			return exit(ReasonStopGap, bar.Open)
+		}
+		if targetBeyond(bar.Open) {
+			return exit(ReasonTargetGap, bar.Open)
+		}
+	}
+
+	adverse, favorable := bar.Low, bar.High
+	if !long {
+		adverse, favorable = bar.High, bar.Low
+	}
+	stopTouched := stopBeyond(adverse)
+	targetTouched := targetBeyond(favorable)
+	switch {
+	case stopTouched && targetTouched:
+		stopFirst := true
+		switch conflict {
+		case ConflictTarget:
+			stopFirst = false
+		case ConflictNearest:
+			stopFirst = math.Abs(bar.Open-pos.Stop) <= math.Abs(pos.Target-bar.Open)
+		}
+		if stopFirst {
+			return exit(ReasonStopHit, pos.Stop)
+		}
+		return exit(ReasonTargetHit, pos.Target)
+	case stopTouched:
+		return exit(ReasonStopHit, pos.Stop)
+	case targetTouched:
+		return exit(ReasonTargetHit, pos.Target)
+	}
+	return nil, 0
+}
+
+// protectiveFillPrice applies costs to a protective fill: stops are market
+// orders (slippage, tick rounding against the trader); targets are limit
+// orders filled at the level, or at the better open on a gap.
+func protectiveFillPrice(inst Instrument, cfg RunConfig, sig *Signal, raw float64) float64 {
+	if sig.Reason == ReasonTargetHit || sig.Reason == ReasonTargetGap {
+		return raw
+	}
+	return fillPrice(inst, raw, cfg.SlippageBps, sig.Action)
+}
@@ -0,0 +1,124 @@
+package backtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProtectiveExit(t *testing.T) {
+	long := Position{Side: SideLong, Qty: 1, EntryPrice: 100, Stop: 95, Target: 110}
+	short := Position{Side: SideShort, Qty: 1, EntryPrice: 100, Stop: 105, Target: 90}
+
+	cases := []struct {
+		name     string
+		pos      Position
+		bar      Bar
+		conflict IntrabarConflict
+		reason   string
+		price    float64
+	}{
+		{"long gap below stop", long, Bar{Open: 93, High: 97, Low: 92, Close: 96}, ConflictStop, ReasonStopGap, 93},
+		{"long touch stop", long, Bar{Open: 99, High: 100, Low: 94, Close: 96}, ConflictStop, ReasonStopHit, 95},
+		{"long gap above target", long, Bar{Open: 112, High: 113, Low: 111, Close: 112}, ConflictStop, ReasonTargetGap, 112},
+		{"long touch target", long, Bar{Open: 105, High: 111, Low: 104, Close: 109}, ConflictStop, ReasonTargetHit, 110},
+		{"both touched: stop first", long, Bar{Open: 100, High: 111, Low: 94, Close: 100}, ConflictStop, ReasonStopHit, 95},
+		{"both touched: target first", long, Bar{Open: 100, High: 111, Low: 94, Close: 100}, ConflictTarget, ReasonTargetHit, 110},
+		{"both touched: nearest", long, Bar{Open: 108, High: 111, Low: 94, Close: 100}, ConflictNearest, ReasonTargetHit, 110},
+		{"short gap above stop", short, Bar{Open: 107, High: 108, Low: 104, Close: 106}, ConflictStop, ReasonStopGap, 107},
+		{"short touch target", short, Bar{Open: 95, High: 96, Low: 89, Close: 92}, ConflictStop, ReasonTargetHit, 90},
+		{"untouched", long, Bar{Open: 100, High: 105, Low: 97, Close: 101}, ConflictStop, "", 0},
+	}
+	for _, tc := range cases {
+		sig, price := protectiveExit(tc.pos, tc.bar, tc.conflict)
+		if tc.reason == "" {
+			if sig != nil {
+				t.Errorf("%s: unexpected exit %#v", tc.name, sig)
+			}
+			continue
+		}
+		if sig == nil || sig.Reason != tc.reason || price != tc.price {
+			t.Errorf("%s: got %#v at %v, want %s at %v", tc.name, sig, price, tc.reason, tc.price)
+		}
+	}
+}
+
+func TestRunOneFillsStopIntrabar(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	var bars []Bar
+	for i := 0; i < 20; i++ {
+		bars = append(bars, Bar{Time: start.AddDate(0, 0, i), Open: 10, High: 10.2, Low: 9.8, Close: 10, Volume: 100})
+	}
+	bars[5].Low = 9.0 // touches the stop; closes back at 10
+
+	cfg := DefaultRunConfig()
+	cfg.IntrabarExits = true
+	cfg.SlippageBps = 0
+	cfg.CommissionBps = 0
+	cfg.Strategy = &levelStrategy{}
+	inst := Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}
+
+	res := runOne(inst, bars, cfg)
+	if len(res.Trades) != 1 {
+		t.Fatalf("expected 1 trade, got %#v", res.Trades)
+	}
+	tr := res.Trades[0]
+	if tr.ReasonExit != ReasonStopHit || tr.ExitPrice != 9.5 || tr.ExitTime != "2024-01-06" {
+		t.Fatalf("unexpected trade %#v", tr)
+	}
+
+	cfg.IntrabarExits = false
+	res = runOne(inst, bars, cfg)
+	if len(res.Trades) != 1 || res.Trades[0].ReasonExit != "force_close_end" {
+		t.Fatalf("intrabar exits disabled: %#v", res.Trades)
+	}
+}
+
+func TestRunOneEntryBarOpensBeyondLevel(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	cfg := DefaultRunConfig()
+	cfg.IntrabarExits = true
+	cfg.SlippageBps = 0
+	cfg.CommissionBps = 0
+	cfg.Costs = SimpleCosts{}
+	cfg.Strategy = &levelStrategy{}
+	inst := Instrument{Symbol: "nf_I0", Type: InstrumentTypeFutures, Multiplier: 1, MarginRate: 1}
+
+	for _, tc := range []struct {
+		name   string
+		entry  Bar
+		reason string
+	}{
+		// the entry at the 9.0 open is already below the 9.5 stop
+		{"stop", Bar{Open: 9.0, High: 9.2, Low: 8.8, Close: 9.1}, ReasonStopGap},
+		// the entry at the 12.5 open is already above the 12 target
+		{"target", Bar{Open: 12.5, High: 12.8, Low: 12.3, Close: 12.6}, ReasonTargetGap},
+	} {
+		var bars []Bar
+		for i := 0; i < 10; i++ {
+			bars = append(bars, Bar{Time: start.AddDate(0, 0, i), Open: 10, High: 10.2, Low: 9.8, Close: 10, Volume: 100})
+		}
+		tc.entry.Time, tc.entry.Volume = bars[2].Time, 100
+		bars[2] = tc.entry
+
+		res := runOne(inst, bars, cfg)
+		if len(res.Trades) != 1 {
+			t.Fatalf("%s: trades %#v", tc.name, res.Trades)
+		}
+		tr := res.Trades[0]
+		if tr.ReasonExit != tc.reason || tr.ExitPrice != tc.entry.Open || tr.ExitTime != "2024-01-03" || tr.NetPnL != 0 {
+			t.Fatalf("%s: must exit at the entry open, got %#v", tc.name, tr)
+		}
+	}
+}
+
+// levelStrategy buys once on bar 1 with a stop at 9.5 and a target at 12.
+type levelStrategy struct{}
+
+func (s *levelStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
+	if i == 1 && pos.Side == SideFlat {
+		return withLevels(&Signal{Time: bars[i].Time, Action: SignalBuy, Reason: "test"}, 9.5, 12)
+	}
+	return nil
+}
+
+func (s *levelStrategy) Clone() Strategy { return &levelStrategy{} }
@@ -0,0 +1,131 @@
+package backtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultStrategyType is used when strategy.type is empty.
+const DefaultStrategyType = "tsai_sen"
+
+// StrategyDef registers a strategy.type: its params struct P (decoded from
+// strategy.params by yaml/json tags), how unset params get defaults, how they
+// are validated and how the strategy is built.
+type StrategyDef[P any] struct {
+	Name string
+	// Defaults fills unset (zero) params; nil keeps them as decoded.
+	Defaults func(P) P
+	// Validate checks params after defaults; nil accepts anything.
+	Validate func(P) error
+	New      func(P) Strategy
+}
+
+// LevelsProvider is implemented by strategies that track support/resistance;
+// scans and charts draw them.
+type LevelsProvider interface {
+	Levels(bars []Bar, i int) (support, resistance float64)
+}
+
+// PlanProvider is implemented by strategies that plan a stop/target with
+// their entry signals; ok is false when there is no plan for the signal at t.
+type PlanProvider interface {
+	Plan(t time.Time) (stop, target float64, ok bool)
+}
+
+// VolumeFilter is implemented by strategies filtering on volume / MA(volume, n);
+// charts use n for the volume MA.
+type VolumeFilter interface {
+	VolumeMAN() int
+}
+
+type strategyEntry struct {
+	build      func(params map[string]any, strict bool) (Strategy, error)
+	decodeJSON func(raw []byte) (any, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]strategyEntry{}
+)
+
+// RegisterStrategy adds d to the strategy registry; it panics on an empty or
+// duplicate name (registration happens in init functions).
+func RegisterStrategy[P any](d StrategyDef[P]) {
+	name := strings.TrimSpace(d.Name)
+	if name == "" || d.New == nil {
+		panic("backtest: RegisterStrategy needs a name and New")
+	}
+	finish := func(p P) (P, error) {
+		if d.Defaults != nil {
+			p = d.Defaults(p)
+		}
+		if d.Validate != nil {
+			if err := d.Validate(p); err != nil {
+				return p, fmt.Errorf("strategy %s: %w", name, err)
+			}
+		}
+		return p, nil
+	}
+	e := strategyEntry{
+		build: func(params map[string]any, strict bool) (Strategy, error) {
+			var p P
+			if err := decodeParams(params, &p, strict); err != nil {
+				return nil, err
+			}
+			p, err := finish(p)
+			if err != nil {
+				return nil, err
+			}
+			return d.New(p), nil
+		},
+		decodeJSON: func(raw []byte) (any, error) {
+			var p P
+			if len(bytes.TrimSpace(raw)) > 0 {
+				dec := json.NewDecoder(bytes.NewReader(raw))
+				dec.DisallowUnknownFields()
+				if err := dec.Decode(&p); err != nil {
+					return nil, fmt.Errorf("strategy.params: %w", err)
+				}
+			}
+			return finish(p)
+		},
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("backtest: strategy registered twice: " + name)
+	}
+	registry[name] = e
+}
+
+// StrategyNames lists the registered strategy types, sorted.
+func StrategyNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupStrategy(typ string) (strategyEntry, error) {
+	typ = strings.TrimSpace(typ)
+	if typ == "" {
+		typ = DefaultStrategyType
+	}
+	registryMu.RLock()
+	e, ok := registry[typ]
+	registryMu.RUnlock()
+	if !ok {
+		return strategyEntry{}, fmt.Errorf("unknown strategy.type: %s (registered: %s)", typ, strings.Join(StrategyNames(), ", "))
+	}
+	return e, nil
+}
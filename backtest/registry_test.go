@@ -0,0 +1,103 @@
+package backtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testRegistryParams struct {
+	Level float64 `yaml:"level" json:"level"`
+}
+
+// levelsPlanStrategy buys on the last bar and reports fixed levels and a plan.
+type levelsPlanStrategy struct{ p testRegistryParams }
+
+func (s *levelsPlanStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
+	if i == len(bars)-1 && pos.Side == SideFlat {
+		return &Signal{Time: bars[i].Time, Action: SignalBuy, Reason: "test"}
+	}
+	return nil
+}
+func (s *levelsPlanStrategy) Clone() Strategy { return &levelsPlanStrategy{p: s.p} }
+func (s *levelsPlanStrategy) Levels(bars []Bar, i int) (float64, float64) {
+	return s.p.Level, s.p.Level * 2
+}
+func (s *levelsPlanStrategy) Plan(t time.Time) (float64, float64, bool) {
+	return s.p.Level * 0.9, s.p.Level * 3, true
+}
+
+func init() {
+	RegisterStrategy(StrategyDef[testRegistryParams]{
+		Name: "test_levels",
+		Defaults: func(p testRegistryParams) testRegistryParams {
+			if p.Level == 0 {
+				p.Level = 5
+			}
+			return p
+		},
+		Validate: func(p testRegistryParams) error {
+			if p.Level < 0 {
+				return fmt.Errorf("strategy.params.level must be >= 0")
+			}
+			return nil
+		},
+		New: func(p testRegistryParams) Strategy { return &levelsPlanStrategy{p: p} },
+	})
+}
+
+func TestStrategyRegistry(t *testing.T) {
+	names := strings.Join(StrategyNames(), ",")
+	for _, n := range []string{"tsai_sen", "patterns", "test_levels"} {
+		if !strings.Contains(names, n) {
+			t.Fatalf("%s not registered: %s", n, names)
+		}
+	}
+	if s, err := NewStrategy("", nil); err != nil || s.(*TsaiSenStrategy) == nil {
+		t.Fatalf("default type: %v %v", s, err)
+	}
+	s, err := NewStrategy("test_levels", nil)
+	if err != nil || s.(*levelsPlanStrategy).p.Level != 5 {
+		t.Fatalf("defaults not applied: %v %v", s, err)
+	}
+	if _, err := NewStrategy("test_levels", map[string]any{"level": -1}); err == nil {
+		t.Fatalf("validation error expected")
+	}
+	if _, err := newStrategy("test_levels", map[string]any{"levle": 1}, true); err == nil {
+		t.Fatalf("strict decode must reject unknown params")
+	}
+	if _, err := NewStrategy("nope", nil); err == nil || !strings.Contains(err.Error(), "test_levels") {
+		t.Fatalf("unknown type error should list registered names: %v", err)
+	}
+	if _, err := NewStrategy("tsai_sen", map[string]any{"entry_mode": "bogus"}); err == nil {
+		t.Fatalf("tsai_sen params must be validated on load")
+	}
+
+	// scans read levels and plans through the provider interfaces
+	cfg := DefaultRunConfig()
+	cfg.Strategy = s
+	sc := scanOne(Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}, flatBars(5), cfg)
+	if sc.Support != 5 || sc.Resistance != 10 || sc.NextAction != SignalBuy || sc.SuggestedStop != 4.5 || sc.SuggestedTarget != 15 {
+		t.Fatalf("scan result %+v", sc)
+	}
+}
+
+func TestLLMConfigUsesRegistry(t *testing.T) {
+	raw := []byte(`{
+  "backtest": {"days": 100, "initial_cash": 1, "position_pct": 1, "slippage_bps": 0, "commission_bps": 0, "stock_lot_size": 100, "futures_multiplier": 1, "futures_margin_rate": 1, "instruments": {"stocks": ["sh600000"], "futures": []}},
+  "strategy": {"type": "test_levels", "params": {"level": 7}}
+}`)
+	cfg, err := ParseLLMBacktestConfigJSON(raw)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	y, err := cfg.ToYAML()
+	if err != nil || !strings.Contains(string(y), "type: test_levels") || !strings.Contains(string(y), "level: 7") {
+		t.Fatalf("yaml %s (%v)", y, err)
+	}
+	bad := strings.Replace(string(raw), `"level": 7`, `"levle": 7`, 1)
+	if _, err := ParseLLMBacktestConfigJSON([]byte(bad)); err == nil {
+		t.Fatalf("unknown param must be rejected")
+	}
+}
@@ -0,0 +1,751 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"stock/indicators"
+)
+
+// Expression language of the rules strategy (strategy.type: rules), e.g.
+//
+//	close > sma(close, 20) and rsi(14) < 70
+//	cross_below(close, boll_mid()) or atr(14) > 0.05 * close
+//
+// Operators by precedence (low to high): or/||, and/&&, not/!,
+// comparisons (< <= > >= == !=), + -, * /, unary -. Bar fields: open, high,
+// low, close, volume, oi. Function period arguments must be number literals;
+// trailing ones may be omitted (defaults in ruleFuncs).
+//
+// An expression is evaluated over a whole bar series at once. Every field and
+// function only looks at the current and earlier bars, so value i is exactly
+// what would be known at the close of bar i. Numbers are NaN until an
+// indicator is ready; comparisons with NaN are unknown and an unknown rule
+// does not fire.
+
+type exprKind uint8
+
+const (
+	kindNum exprKind = iota + 1
+	kindBool
+)
+
+func (k exprKind) String() string {
+	if k == kindBool {
+		return "condition"
+	}
+	return "number"
+}
+
+// ruleExpr is a compiled (sub)expression. Booleans evaluate to 1/0/NaN.
+type ruleExpr struct {
+	kind  exprKind
+	eval  func(bars []Bar) []float64
+	konst *float64 // number literal (function period arguments)
+}
+
+// compileRule parses src; it must be a condition.
+func compileRule(src string) (*ruleExpr, error) {
+	toks, err := lexRule(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &ruleParser{toks: toks}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	if e.kind != kindBool {
+		return nil, fmt.Errorf("expression is a number, want a condition (e.g. close > sma(close, 20))")
+	}
+	return e, nil
+}
+
+// fires reports whether a condition value is true.
+func fires(v float64) bool { return v == 1 }
+
+// ---- lexer ----
+
+type tokKind uint8
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type ruleToken struct {
+	kind tokKind
+	text string
+	pos  int
+	num  float64
+}
+
+func lexRule(src string) ([]ruleToken, error) {
+	var toks []ruleToken
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			v, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("col %d: bad number %q", i+1, src[i:j])
+			}
+			toks = append(toks, ruleToken{kind: tokNum, text: src[i:j], pos: i, num: v})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, ruleToken{kind: tokIdent, text: strings.ToLower(src[i:j]), pos: i})
+			i = j
+		case c == '(':
+			toks = append(toks, ruleToken{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			toks = append(toks, ruleToken{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			toks = append(toks, ruleToken{kind: tokComma, text: ",", pos: i})
+			i++
+		default:
+			op := ""
+			for _, cand := range []string{"<=", ">=", "==", "!=", "&&", "||", "<", ">", "+", "-", "*", "/", "!"} {
+				if strings.HasPrefix(src[i:], cand) {
+					op = cand
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("col %d: unexpected character %q", i+1, c)
+			}
+			toks = append(toks, ruleToken{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, ruleToken{kind: tokEOF, text: "end of expression", pos: len(src)}), nil
+}
+
+// ---- parser ----
+
+type ruleParser struct {
+	toks []ruleToken
+	i    int
+}
+
+func (p *ruleParser) peek() ruleToken { return p.toks[p.i] }
+
+func (p *ruleParser) next() ruleToken {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *ruleParser) errorf(t ruleToken, format string, args ...any) error {
+	return fmt.Errorf("col %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+// accept consumes the next token if it is one of the operators/keywords in ops.
+func (p *ruleParser) accept(ops ...string) (ruleToken, bool) {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return t, false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return p.next(), true
+		}
+	}
+	return t, false
+}
+
+func (p *ruleParser) expect(k exprKind, t ruleToken, e *ruleExpr) error {
+	if e.kind != k {
+		return p.errorf(t, "%q needs a %s operand, got a %s", t.text, k, e.kind)
+	}
+	return nil
+}
+
+func (p *ruleParser) parseOr() (*ruleExpr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept("or", "||")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(kindBool, t, l); err != nil {
+			return nil, err
+		}
+		if err := p.expect(kindBool, t, r); err != nil {
+			return nil, err
+		}
+		l = zipExpr(kindBool, l, r, func(a, b float64) float64 {
+			switch {
+			case a == 1 || b == 1:
+				return 1
+			case math.IsNaN(a) || math.IsNaN(b):
+				return math.NaN()
+			}
+			return 0
+		})
+	}
+}
+
+func (p *ruleParser) parseAnd() (*ruleExpr, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept("and", "&&")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(kindBool, t, l); err != nil {
+			return nil, err
+		}
+		if err := p.expect(kindBool, t, r); err != nil {
+			return nil, err
+		}
+		l = zipExpr(kindBool, l, r, func(a, b float64) float64 {
+			switch {
+			case a == 0 || b == 0:
+				return 0
+			case math.IsNaN(a) || math.IsNaN(b):
+				return math.NaN()
+			}
+			return 1
+		})
+	}
+}
+
+func (p *ruleParser) parseNot() (*ruleExpr, error) {
+	t, ok := p.accept("not", "!")
+	if !ok {
+		return p.parseCmp()
+	}
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(kindBool, t, x); err != nil {
+		return nil, err
+	}
+	return mapExpr(kindBool, x, func(v float64) float64 {
+		if math.IsNaN(v) {
+			return v
+		}
+		return 1 - v
+	}), nil
+}
+
+func (p *ruleParser) parseCmp() (*ruleExpr, error) {
+	l, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	t, ok := p.accept("<", "<=", ">", ">=", "==", "!=")
+	if !ok {
+		return l, nil
+	}
+	r, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(kindNum, t, l); err != nil {
+		return nil, err
+	}
+	if err := p.expect(kindNum, t, r); err != nil {
+		return nil, err
+	}
+	if nt, chained := p.accept("<", "<=", ">", ">=", "==", "!="); chained {
+		return nil, p.errorf(nt, "comparisons cannot be chained; use and")
+	}
+	var cmp func(a, b float64) bool
+	switch t.text {
+	case "<":
+		cmp = func(a, b float64) bool { return a < b }
+	case "<=":
+		cmp = func(a, b float64) bool { return a <= b }
+	case ">":
+		cmp = func(a, b float64) bool { return a > b }
+	case ">=":
+		cmp = func(a, b float64) bool { return a >= b }
+	case "==":
+		cmp = func(a, b float64) bool { return a == b }
+	default:
+		cmp = func(a, b float64) bool { return a != b }
+	}
+	return zipExpr(kindBool, l, r, func(a, b float64) float64 {
+		if math.IsNaN(a) || math.IsNaN(b) {
+			return math.NaN()
+		}
+		return boolValue(cmp(a, b))
+	}), nil
+}
+
+func (p *ruleParser) parseAdd() (*ruleExpr, error) {
+	l, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept("+", "-")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(kindNum, t, l); err != nil {
+			return nil, err
+		}
+		if err := p.expect(kindNum, t, r); err != nil {
+			return nil, err
+		}
+		if t.text == "+" {
+			l = zipExpr(kindNum, l, r, func(a, b float64) float64 { return a + b })
+		} else {
+			l = zipExpr(kindNum, l, r, func(a, b float64) float64 { return a - b })
+		}
+	}
+}
+
+func (p *ruleParser) parseMul() (*ruleExpr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept("*", "/")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(kindNum, t, l); err != nil {
+			return nil, err
+		}
+		if err := p.expect(kindNum, t, r); err != nil {
+			return nil, err
+		}
+		if t.text == "*" {
+			l = zipExpr(kindNum, l, r, func(a, b float64) float64 { return a * b })
+		} else {
+			l = zipExpr(kindNum, l, r, func(a, b float64) float64 {
+				if b == 0 {
+					return math.NaN()
+				}
+				return a / b
+			})
+		}
+	}
+}
+
+func (p *ruleParser) parseUnary() (*ruleExpr, error) {
+	t, ok := p.accept("-")
+	if !ok {
+		return p.parsePrimary()
+	}
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(kindNum, t, x); err != nil {
+		return nil, err
+	}
+	if x.konst != nil {
+		return numLiteral(-*x.konst), nil
+	}
+	return mapExpr(kindNum, x, func(v float64) float64 { return -v }), nil
+}
+
+func (p *ruleParser) parsePrimary() (*ruleExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNum:
+		return numLiteral(t.num), nil
+	case tokLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokRParen {
+			return nil, p.errorf(c, "expected ) but found %q", c.text)
+		}
+		return e, nil
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.parseCall(t)
+		}
+		switch t.text {
+		case "true", "false":
+			v := boolValue(t.text == "true")
+			return &ruleExpr{kind: kindBool, eval: func(bars []Bar) []float64 { return constSeries(len(bars), v) }}, nil
+		}
+		field, ok := ruleFields[t.text]
+		if !ok {
+			return nil, p.errorf(t, "unknown name %q (fields: open, high, low, close, volume, oi)", t.text)
+		}
+		return &ruleExpr{kind: kindNum, eval: func(bars []Bar) []float64 {
+			out := make([]float64, len(bars))
+			for i, b := range bars {
+				out[i] = field(b)
+			}
+			return out
+		}}, nil
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+func (p *ruleParser) parseCall(name ruleToken) (*ruleExpr, error) {
+	fn, ok := ruleFuncs[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %s()", name.text)
+	}
+	p.next() // (
+	var args []*ruleExpr
+	if p.peek().kind != tokRParen {
+		for {
+			a, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if c := p.next(); c.kind != tokRParen {
+		return nil, p.errorf(c, "expected ) but found %q", c.text)
+	}
+
+	if len(args) < fn.series || len(args) > fn.series+len(fn.consts) {
+		return nil, p.errorf(name, "%s() takes %s", name.text, fn.usage())
+	}
+	for _, a := range args[:fn.series] {
+		if a.kind != kindNum {
+			return nil, p.errorf(name, "%s() takes %s", name.text, fn.usage())
+		}
+	}
+	consts := make([]float64, len(fn.consts))
+	for k, spec := range fn.consts {
+		j := fn.series + k
+		if j >= len(args) {
+			if spec.def == 0 {
+				return nil, p.errorf(name, "%s() takes %s", name.text, fn.usage())
+			}
+			consts[k] = spec.def
+			continue
+		}
+		a := args[j]
+		if a.konst == nil {
+			return nil, p.errorf(name, "%s(): %s must be a number literal", name.text, spec.name)
+		}
+		v := *a.konst
+		if v < spec.min || (spec.integer && v != math.Trunc(v)) || v > 10000 {
+			return nil, p.errorf(name, "%s(): %s out of range: %v", name.text, spec.name, v)
+		}
+		consts[k] = v
+	}
+	series := args[:fn.series]
+	return &ruleExpr{kind: fn.kind, eval: func(bars []Bar) []float64 {
+		xs := make([][]float64, len(series))
+		for k, a := range series {
+			xs[k] = a.eval(bars)
+		}
+		return fn.eval(bars, xs, consts)
+	}}, nil
+}
+
+// ---- building blocks ----
+
+var ruleFields = map[string]func(Bar) float64{
+	"open":   func(b Bar) float64 { return b.Open },
+	"high":   func(b Bar) float64 { return b.High },
+	"low":    func(b Bar) float64 { return b.Low },
+	"close":  func(b Bar) float64 { return b.Close },
+	"volume": func(b Bar) float64 { return float64(b.Volume) },
+	"oi":     func(b Bar) float64 { return float64(b.OpenInterest) },
+}
+
+func numLiteral(v float64) *ruleExpr {
+	return &ruleExpr{kind: kindNum, konst: &v, eval: func(bars []Bar) []float64 { return constSeries(len(bars), v) }}
+}
+
+func constSeries(n int, v float64) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = v
+	}
+	return out
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func mapExpr(k exprKind, x *ruleExpr, f func(float64) float64) *ruleExpr {
+	return &ruleExpr{kind: k, eval: func(bars []Bar) []float64 {
+		out := x.eval(bars)
+		for i, v := range out {
+			out[i] = f(v)
+		}
+		return out
+	}}
+}
+
+func zipExpr(k exprKind, l, r *ruleExpr, f func(a, b float64) float64) *ruleExpr {
+	return &ruleExpr{kind: k, eval: func(bars []Bar) []float64 {
+		a, b := l.eval(bars), r.eval(bars)
+		for i := range a {
+			a[i] = f(a[i], b[i])
+		}
+		return a
+	}}
+}
+
+type constArg struct {
+	name    string
+	def     float64 // 0 = required
+	min     float64
+	integer bool
+}
+
+var (
+	argPeriod = constArg{name: "n", min: 1, integer: true}
+	argK      = constArg{name: "k", def: 2, min: 0}
+)
+
+func period(def float64) constArg {
+	a := argPeriod
+	a.def = def
+	return a
+}
+
+func named(a constArg, name string) constArg {
+	a.name = name
+	return a
+}
+
+type ruleFunc struct {
+	kind   exprKind
+	series int // leading expression arguments
+	consts []constArg
+	eval   func(bars []Bar, xs [][]float64, c []float64) []float64
+}
+
+func (f ruleFunc) usage() string {
+	var parts []string
+	for k := 0; k < f.series; k++ {
+		parts = append(parts, "x"+strconv.Itoa(k+1))
+	}
+	for _, c := range f.consts {
+		if c.def != 0 {
+			parts = append(parts, fmt.Sprintf("[%s=%v]", c.name, c.def))
+		} else {
+			parts = append(parts, c.name)
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// onValid applies a series function to xs after its leading NaNs (an indicator
+// of an indicator, e.g. sma(rsi(14), 5), starts once the inner one is ready).
+func onValid(xs []float64, f func([]float64) []float64) []float64 {
+	start := 0
+	for start < len(xs) && math.IsNaN(xs[start]) {
+		start++
+	}
+	out := constSeries(len(xs), math.NaN())
+	copy(out[start:], f(xs[start:]))
+	return out
+}
+
+// window returns, for each i, agg over xs[i-n+1..i] (NaN until n values).
+func window(xs []float64, n int, agg func(a, b float64) float64) []float64 {
+	out := constSeries(len(xs), math.NaN())
+	for i := n - 1; i < len(xs); i++ {
+		v := xs[i-n+1]
+		for j := i - n + 2; j <= i; j++ {
+			v = agg(v, xs[j])
+		}
+		out[i] = v
+	}
+	return out
+}
+
+func cross(a, b []float64, above bool) []float64 {
+	out := constSeries(len(a), math.NaN())
+	for i := 1; i < len(a); i++ {
+		if math.IsNaN(a[i-1]) || math.IsNaN(b[i-1]) || math.IsNaN(a[i]) || math.IsNaN(b[i]) {
+			continue
+		}
+		if above {
+			out[i] = boolValue(a[i-1] <= b[i-1] && a[i] > b[i])
+		} else {
+			out[i] = boolValue(a[i-1] >= b[i-1] && a[i] < b[i])
+		}
+	}
+	return out
+}
+
+func closes(bars []Bar) []float64 { return indicators.Closes(bars) }
+
+var ruleFuncs = map[string]ruleFunc{
+	"sma": {kind: kindNum, series: 1, consts: []constArg{argPeriod}, eval: func(_ []Bar, xs [][]float64, c []float64) []float64 {
+		return onValid(xs[0], func(v []float64) []float64 { return indicators.SMASeries(v, int(c[0])) })
+	}},
+	"ema": {kind: kindNum, series: 1, consts: []constArg{argPeriod}, eval: func(_ []Bar, xs [][]float64, c []float64) []float64 {
+		return onValid(xs[0], func(v []float64) []float64 { return indicators.EMASeries(v, int(c[0])) })
+	}},
+	"highest": {kind: kindNum, series: 1, consts: []constArg{argPeriod}, eval: func(_ []Bar, xs [][]float64, c []float64) []float64 {
+		return window(xs[0], int(c[0]), math.Max)
+	}},
+	"lowest": {kind: kindNum, series: 1, consts: []constArg{argPeriod}, eval: func(_ []Bar, xs [][]float64, c []float64) []float64 {
+		return window(xs[0], int(c[0]), math.Min)
+	}},
+	// ref(x, n): x n bars ago
+	"ref": {kind: kindNum, series: 1, consts: []constArg{argPeriod}, eval: func(_ []Bar, xs [][]float64, c []float64) []float64 {
+		n := int(c[0])
+		out := constSeries(len(xs[0]), math.NaN())
+		for i := n; i < len(out); i++ {
+			out[i] = xs[0][i-n]
+		}
+		return out
+	}},
+	"abs": {kind: kindNum, series: 1, eval: func(_ []Bar, xs [][]float64, _ []float64) []float64 {
+		for i, v := range xs[0] {
+			xs[0][i] = math.Abs(v)
+		}
+		return xs[0]
+	}},
+	"max": {kind: kindNum, series: 2, eval: func(_ []Bar, xs [][]float64, _ []float64) []float64 {
+		for i := range xs[0] {
+			xs[0][i] = math.Max(xs[0][i], xs[1][i])
+		}
+		return xs[0]
+	}},
+	"min": {kind: kindNum, series: 2, eval: func(_ []Bar, xs [][]float64, _ []float64) []float64 {
+		for i := range xs[0] {
+			xs[0][i] = math.Min(xs[0][i], xs[1][i])
+		}
+		return xs[0]
+	}},
+	// cross_above(a, b): a closes above b on this bar after being at or below it
+	"cross_above": {kind: kindBool, series: 2, eval: func(_ []Bar, xs [][]float64, _ []float64) []float64 {
+		return cross(xs[0], xs[1], true)
+	}},
+	"cross_below": {kind: kindBool, series: 2, eval: func(_ []Bar, xs [][]float64, _ []float64) []float64 {
+		return cross(xs[0], xs[1], false)
+	}},
+
+	"rsi": {kind: kindNum, consts: []constArg{period(14)}, eval: func(bars []Bar, _ [][]float64, c []float64) []float64 {
+		return indicators.RSISeries(closes(bars), int(c[0]))
+	}},
+	"macd":        macdFunc(0),
+	"macd_signal": macdFunc(1),
+	"macd_hist":   macdFunc(2),
+	"kdj_k":       kdjFunc(0),
+	"kdj_d":       kdjFunc(1),
+	"kdj_j":       kdjFunc(2),
+	"boll_upper":  bollFunc(0),
+	"boll_mid":    bollFunc(1),
+	"boll_lower":  bollFunc(2),
+	"atr": {kind: kindNum, consts: []constArg{period(14)}, eval: func(bars []Bar, _ [][]float64, c []float64) []float64 {
+		return indicators.ATRSeries(bars, int(c[0]))
+	}},
+	"adx":      adxFunc(0),
+	"plus_di":  adxFunc(1),
+	"minus_di": adxFunc(2),
+	"obv": {kind: kindNum, eval: func(bars []Bar, _ [][]float64, _ []float64) []float64 {
+		return indicators.OBVSeries(bars)
+	}},
+	"vwap": {kind: kindNum, consts: []constArg{period(20)}, eval: func(bars []Bar, _ [][]float64, c []float64) []float64 {
+		return indicators.VWAPSeries(bars, int(c[0]))
+	}},
+	"donchian_upper": donchianFunc(0),
+	"donchian_lower": donchianFunc(2),
+}
+
+func pick3(k int, a, b, c []float64) []float64 {
+	return [][]float64{a, b, c}[k]
+}
+
+func macdFunc(k int) ruleFunc {
+	return ruleFunc{kind: kindNum, consts: []constArg{named(period(12), "fast"), named(period(26), "slow"), named(period(9), "signal")},
+		eval: func(bars []Bar, _ [][]float64, c []float64) []float64 {
+			dif, dea, hist := indicators.MACDSeries(closes(bars), int(c[0]), int(c[1]), int(c[2]))
+			return pick3(k, dif, dea, hist)
+		}}
+}
+
+func kdjFunc(k int) ruleFunc {
+	return ruleFunc{kind: kindNum, consts: []constArg{period(9), named(period(3), "m1"), named(period(3), "m2")},
+		eval: func(bars []Bar, _ [][]float64, c []float64) []float64 {
+			kk, d, j := indicators.KDJSeries(bars, int(c[0]), int(c[1]), int(c[2]))
+			return pick3(k, kk, d, j)
+		}}
+}
+
+func bollFunc(k int) ruleFunc {
+	return ruleFunc{kind: kindNum, consts: []constArg{period(20), argK},
+		eval: func(bars []Bar, _ [][]float64, c []float64) []float64 {
+			up, mid, lo := indicators.BollingerSeries(closes(bars), int(c[0]), c[1])
+			return pick3(k, up, mid, lo)
+		}}
+}
+
+func adxFunc(k int) ruleFunc {
+	return ruleFunc{kind: kindNum, consts: []constArg{period(14)},
+		eval: func(bars []Bar, _ [][]float64, c []float64) []float64 {
+			adx, pdi, mdi := indicators.ADXSeries(bars, int(c[0]))
+			return pick3(k, adx, pdi, mdi)
+		}}
+}
+
+func donchianFunc(k int) ruleFunc {
+	return ruleFunc{kind: kindNum, consts: []constArg{period(20)},
+		eval: func(bars []Bar, _ [][]float64, c []float64) []float64 {
+			up, mid, lo := indicators.DonchianSeries(bars, int(c[0]))
+			return pick3(k, up, mid, lo)
+		}}
+}
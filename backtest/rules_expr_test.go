@@ -0,0 +1,114 @@
+package backtest
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func closeBars(closes ...float64) []Bar {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	bars := make([]Bar, len(closes))
+	for i, c := range closes {
+		bars[i] = Bar{Time: start.AddDate(0, 0, i), Open: c, High: c + 0.1, Low: c - 0.1, Close: c, Volume: 100}
+	}
+	return bars
+}
+
+func TestCompileRuleErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		"close >":                  "col 8",
+		"close + 1":                "want a condition",
+		"foo > 1":                  `unknown name "foo"`,
+		"bar(close) > 1":           "unknown function",
+		"sma(close) > 1":           "sma() takes (x1, n)",
+		"sma(close, 2.5) > 1":      "out of range",
+		"sma(close, close) > 1":    "number literal",
+		"ref(close, -1) > 1":       "out of range",
+		"1 < close < 2":            "cannot be chained",
+		"close > 1 and 2":          `"and" needs a condition`,
+		"(close > 1":               "expected )",
+		"close > 1 $":              "unexpected character",
+		"rsi(14) > 50 close":       `unexpected "close"`,
+		"not close":                `"not" needs a condition`,
+		"cross_above(close) > 1":   "cross_above() takes",
+		"boll_upper(20, 2, 1) > 1": "boll_upper() takes ([n=20], [k=2])",
+	} {
+		_, err := compileRule(src)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: error %v, want %q", src, err, want)
+		}
+	}
+}
+
+func TestRuleEvaluation(t *testing.T) {
+	bars := closeBars(1, 2, 3, 4, 5, 4, 3, 2, 1)
+	nan := math.NaN()
+	for src, want := range map[string][]float64{
+		"close > sma(close, 3)":                      {nan, nan, 1, 1, 1, 0, 0, 0, 0},
+		"cross_below(close, sma(close, 3))":          {nan, nan, nan, 0, 0, 1, 0, 0, 0},
+		"highest(close, 3) - lowest(close,3) >= 2":   {nan, nan, 1, 1, 1, 0, 1, 1, 1},
+		"not (ref(close, 1) < close) or false":       {nan, 0, 0, 0, 0, 1, 1, 1, 1},
+		"close > 2 or close > sma(close, 3)":         {nan, nan, 1, 1, 1, 1, 1, 0, 0},
+		"close >= 2 AND -close / (close - 1) < -1.5": {0, 1, 0, 0, 0, 0, 0, 1, 0},
+		"sma(close - 1, 2) == 3.5 || true":           {1, 1, 1, 1, 1, 1, 1, 1, 1},
+		"rsi(2) > 50":                                {nan, nan, 1, 1, 1, 0, 0, 0, 0},
+	} {
+		e, err := compileRule(src)
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		got := e.eval(bars)
+		for i := range want {
+			if !(got[i] == want[i] || math.IsNaN(got[i]) && math.IsNaN(want[i])) {
+				t.Errorf("%q: got %v, want %v", src, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestRulesStrategy(t *testing.T) {
+	if _, err := NewStrategy("rules", map[string]any{"exit": "close > 1"}); err == nil {
+		t.Fatalf("entry required")
+	}
+	if _, err := NewStrategy("rules", map[string]any{"entry": "close > sma(close)"}); err == nil || !strings.Contains(err.Error(), "strategy.params.entry") {
+		t.Fatalf("compile error should name the param: %v", err)
+	}
+	s, err := NewStrategy("rules", map[string]any{
+		"entry":    "cross_above(close, sma(close, 3))",
+		"exit":     "cross_below(close, sma(close, 3))",
+		"stop_pct": 0.5,
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	cfg := DefaultRunConfig()
+	cfg.Strategy = s
+	cfg.SlippageBps = 0
+	cfg.CommissionBps = 0
+	inst := Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}
+	// cross above at bar 4, cross below at bar 8
+	bars := closeBars(10, 9, 8, 8, 10, 11, 12, 12, 9, 9, 9)
+
+	sim := NewSimulator(inst, bars, cfg)
+	var snaps []Snapshot
+	sim.Run(func(s Snapshot) { snaps = append(snaps, s) })
+	if sig := snaps[4].Signal; sig == nil || sig.Action != SignalBuy || sig.Stop != 5 {
+		t.Fatalf("bar 4 signal %+v", sig)
+	}
+	if stop, _, ok := sim.Strategy().(PlanProvider).Plan(bars[4].Time); !ok || stop != 5 {
+		t.Fatalf("plan %v %v", stop, ok)
+	}
+	res := sim.Finish()
+	if len(res.Trades) != 1 || res.Trades[0].EntryPrice != 11 || res.Trades[0].ExitPrice != 9 || res.Trades[0].ReasonExit != "rules_exit" {
+		t.Fatalf("trades %+v", res.Trades)
+	}
+
+	// clones evaluate independently on other series
+	c := s.Clone()
+	if sig := c.OnBar(4, closeBars(10, 9, 8, 8, 7), Position{Side: SideFlat}); sig != nil {
+		t.Fatalf("clone fired on other bars: %+v", sig)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"stock/indicators"
 )
 
 type ScanResult struct {
@@ -26,100 +28,38 @@ type ScanResult struct {
 	SuggestedStop   float64      `json:"suggested_stop,omitempty"`
 	SuggestedTarget float64      `json:"suggested_target,omitempty"`
 
+	// Indicators at the latest bar (MA/MACD/RSI/KDJ/BOLL/ATR/ADX/...).
+	Indicators *indicators.Snapshot `json:"indicators,omitempty"`
+
 	ChartPath string `json:"chart_path,omitempty"`
 
 	Errors []string `json:"errors,omitempty"`
 }
 
 func scanOne(inst Instrument, bars []Bar, cfg RunConfig) ScanResult {
-	strategy := cfg.Strategy.Clone()
-
-	cash := cfg.InitialCash
-	var pos Position
-	pos.Side = SideFlat
-
-	var pending *Signal
-	var lastSignal *Signal
-
-	for i := 0; i < len(bars); i++ {
-		// Execute pending order at next bar open (close-confirm model)
-		if pending != nil && i >= 1 && bars[i-1].Time.Equal(pending.Time) {
-			execPrice := applySlippage(bars[i].Open, cfg.SlippageBps, pending.Action)
-			fee := 0.0
-
-			switch pending.Action {
-			case SignalBuy:
-				if pos.Side == SideFlat && execPrice > 0 {
-					qty := sizeQty(inst, cash, execPrice, cfg.PositionPct, cfg.FuturesMargin)
-					if qty > 0 {
-						notional := execPrice * qty * multiplier(inst)
-						fee = notional * (cfg.CommissionBps / 10000.0)
-						if inst.Type == InstrumentTypeFutures {
-							margin := notional * cfg.FuturesMargin
-							cash -= margin + fee
-							pos = Position{Side: SideLong, Qty: qty, EntryTime: bars[i].Time, EntryPrice: execPrice, EntryFee: fee, Margin: margin}
-						} else {
-							cash -= notional + fee
-							pos = Position{Side: SideLong, Qty: qty, EntryTime: bars[i].Time, EntryPrice: execPrice, EntryFee: fee}
-						}
-					}
-				}
-			case SignalSell:
-				if pos.Side == SideLong && execPrice > 0 {
-					notional := execPrice * pos.Qty * multiplier(inst)
-					fee = notional * (cfg.CommissionBps / 10000.0)
-					if inst.Type == InstrumentTypeFutures {
-						cash += pos.Margin + settlePnL(inst, pos, execPrice) - fee
-					} else {
-						cash += notional - fee
-					}
-					pos = Position{Side: SideFlat}
-				}
-			case SignalShort:
-				if inst.Type == InstrumentTypeFutures && inst.AllowShort && pos.Side == SideFlat && execPrice > 0 {
-					qty := sizeQty(inst, cash, execPrice, cfg.PositionPct, cfg.FuturesMargin)
-					if qty > 0 {
-						notional := execPrice * qty * multiplier(inst)
-						fee = notional * (cfg.CommissionBps / 10000.0)
-						margin := notional * cfg.FuturesMargin
-						cash -= margin + fee
-						pos = Position{Side: SideShort, Qty: qty, EntryTime: bars[i].Time, EntryPrice: execPrice, EntryFee: fee, Margin: margin}
-					}
-				}
-			case SignalCover:
-				if pos.Side == SideShort && execPrice > 0 {
-					fee = (execPrice * pos.Qty * multiplier(inst)) * (cfg.CommissionBps / 10000.0)
-					cash += pos.Margin + settlePnL(inst, pos, execPrice) - fee
-					pos = Position{Side: SideFlat}
-				}
-			}
-
-			pending = nil
-		}
-
-		sig := strategy.OnBar(i, bars, pos)
-		if sig != nil {
-			lastSignal = sig
-			if i+1 < len(bars) {
-				pending = sig
-			}
-		}
-	}
+	// scan only reports the state after the latest bar
+	sim := NewSimulator(inst, bars, cfg)
+	var snap Snapshot
+	sim.Run(func(s Snapshot) { snap = s })
+	strategy := sim.Strategy()
+	pos := snap.Position
+	lastSignal := snap.Signal
 
 	last := bars[len(bars)-1]
 	out := ScanResult{
 		Symbol:       inst.Symbol,
 		Instrument:   string(inst.Type),
-		LastDate:     last.Time.Format("2006-01-02"),
+		LastDate:     cfg.FormatTime(last.Time),
 		LastClose:    round2(last.Close),
 		PositionSide: pos.Side,
 		PositionQty:  round2(pos.Qty),
 	}
+	ind := indicators.Latest(bars)
+	out.Indicators = &ind
 
 	// Key levels at the latest bar (for context/overlay)
-	switch st := strategy.(type) {
-	case *TsaiSenStrategy:
-		sup, res := levels(bars, len(bars)-1, st.p)
+	if lp, ok := strategy.(LevelsProvider); ok {
+		sup, res := lp.Levels(bars, len(bars)-1)
 		if sup > 0 {
 			out.Support = round2(sup)
 		}
@@ -128,7 +68,7 @@ func scanOne(inst Instrument, bars []Bar, cfg RunConfig) ScanResult {
 		}
 	}
 	if pos.Side != SideFlat {
-		out.EntryDate = pos.EntryTime.Format("2006-01-02")
+		out.EntryDate = cfg.FormatTime(pos.EntryTime)
 		out.EntryPrice = round2(pos.EntryPrice)
 	}
 	// only care about latest bar's signal (next open execution)
@@ -137,20 +77,13 @@ func scanOne(inst Instrument, bars []Bar, cfg RunConfig) ScanResult {
 		out.Reason = lastSignal.Reason
 
 		// Best-effort stop/target extraction.
-		switch st := strategy.(type) {
-		case *PatternsStrategy:
-			if st.pendingPlan != nil {
-				out.SuggestedStop = round2(st.pendingPlan.stop)
-				out.SuggestedTarget = round2(st.pendingPlan.target)
-			}
-		case *TsaiSenStrategy:
-			if st.lastPlan != nil && st.lastPlan.time.Equal(last.Time) {
-				out.SuggestedStop = round2(st.lastPlan.stop)
-				out.SuggestedTarget = round2(st.lastPlan.target)
+		if pp, ok := strategy.(PlanProvider); ok {
+			if stop, target, ok := pp.Plan(last.Time); ok {
+				out.SuggestedStop = round2(stop)
+				out.SuggestedTarget = round2(target)
 			}
 		}
 	}
-	_ = cash // keep signature aligned with backtest simulation; future use: equity display
 	return out
 }
 
@@ -218,7 +151,7 @@ func (r *Runner) Scan(cfg RunConfig) ([]ScanResult, error) {
 			if res.NextAction != "" {
 				last := bars[len(bars)-1]
 				points = append(points, ChartPoint{
-					Date:  last.Time.Format("2006-01-02"),
+					Date:  cfg.FormatTime(last.Time),
 					Price: last.Close,
 					Label: string(res.NextAction),
 					Color: "#a78bfa",
@@ -0,0 +1,252 @@
+package backtest
+
+// Simulator steps one instrument through its bars with the close-confirm
+// model: signals are generated at a bar's close and filled at the next bar's
+// open (subject to the A-share rules), protective stop/target orders fill
+// intrabar. Backtests (runOne) and scans (scanOne) both drive it, so every
+// execution rule applies to both; RunPortfolio drives the same leg steps over
+// a shared account.
+type Simulator struct {
+	leg
+	cash     float64
+	unfilled []UnfilledOrder
+	curve    []Point
+	peak     float64
+	maxDD    float64
+}
+
+// leg is the per-instrument execution state: the strategy, its position and
+// pending order, and the trades it closed. Its steps return cash changes and
+// leave the account (cash, slots, equity) to the caller.
+type leg struct {
+	inst     Instrument
+	bars     []Bar
+	cfg      RunConfig
+	strategy Strategy
+	feed     timeframeFeed
+	atr      []float64 // for atr sizing
+
+	next        int // index of the next bar to process
+	pos         Position
+	pending     *Signal
+	entryReason string
+	trades      []Trade
+}
+
+func newLeg(inst Instrument, bars []Bar, cfg RunConfig) leg {
+	return leg{
+		inst:     inst,
+		bars:     bars,
+		cfg:      cfg,
+		strategy: cfg.Strategy.Clone(),
+		feed:     newTimeframeFeed(cfg),
+		atr:      cfg.sizingATR(bars),
+		pos:      Position{Side: SideFlat},
+	}
+}
+
+// warmup shows bars[next] to the strategy without trading (before cfg.TradeFrom).
+func (l *leg) warmup() {
+	l.feed.onBar(l.strategy, l.next, l.bars, l.pos)
+	l.next++
+}
+
+// due reports whether the pending order fills at the open of bars[next], i.e.
+// it was signalled at the previous bar's close.
+func (l *leg) due() bool {
+	i := l.next
+	return l.pending != nil && i >= 1 && l.bars[i-1].Time.Equal(l.pending.Time)
+}
+
+// fillPending executes the due pending order at the open of bars[next]; an
+// order refused by the A-share rules is recorded in unfilled and kept when it
+// is retried. size is what an entry is sized against. Returns the cash change.
+func (l *leg) fillPending(size sizeInput, unfilled *[]UnfilledOrder) float64 {
+	i := l.next
+	keep, ok := l.cfg.gateOrder(l.inst, l.bars, i, l.pending, l.pos, unfilled)
+	if !ok {
+		l.pending = keep
+		return 0
+	}
+	sig := l.pending
+	l.pending = nil
+	execPrice := fillPrice(l.inst, l.bars[i].Open, l.cfg.SlippageBps, sig.Action)
+	next, cashDelta, trade := executeOrder(l.inst, l.cfg, l.pos, sig, l.bars[i].Time, execPrice, size, l.entryReason)
+	if l.pos.Side == SideFlat && next.Side != SideFlat {
+		l.entryReason = sig.Reason
+	}
+	return l.apply(next, cashDelta, trade)
+}
+
+// closeBar fills protective stop/target orders within bars[next], then lets
+// the strategy signal at its close (pending for the next bar's open) and
+// advances. Returns the cash change and the signal.
+func (l *leg) closeBar(unfilled *[]UnfilledOrder) (float64, *Signal) {
+	i := l.next
+	var cashDelta float64
+	if next, d, trade := applyProtective(l.inst, l.cfg, l.pos, l.bars, i, l.entryReason, unfilled); trade != nil {
+		cashDelta = l.apply(next, d, trade)
+	}
+	sig := l.feed.onBar(l.strategy, i, l.bars, l.pos)
+	if sig != nil && i+1 < len(l.bars) {
+		l.pending = sig
+	}
+	l.next++
+	return cashDelta, sig
+}
+
+// forceClose closes an open position at the last processed close.
+func (l *leg) forceClose() float64 {
+	if l.pos.Side == SideFlat || l.next == 0 {
+		return 0
+	}
+	last := l.bars[l.next-1]
+	exit := &Signal{Time: last.Time, Action: SignalSell, Reason: "force_close_end"}
+	if l.pos.Side == SideShort {
+		exit.Action = SignalCover
+	}
+	return l.apply(executeOrder(l.inst, l.cfg, l.pos, exit, last.Time, last.Close, sizeInput{}, l.entryReason))
+}
+
+// value marks the open position at the last processed close.
+func (l *leg) value() float64 {
+	if l.next == 0 {
+		return 0
+	}
+	return markToMarket(l.inst, l.pos, l.bars[l.next-1].Close)
+}
+
+func (l *leg) apply(next Position, cashDelta float64, trade *Trade) float64 {
+	if trade != nil {
+		l.trades = append(l.trades, *trade)
+	}
+	l.pos = next
+	return cashDelta
+}
+
+// Snapshot is the simulator state after the close of bars[Index].
+type Snapshot struct {
+	Index int
+	Bar   Bar
+	// Warmup bars (before cfg.TradeFrom) only feed the strategy.
+	Warmup   bool
+	Cash     float64
+	Equity   float64
+	Position Position
+	// Signal is the strategy's signal at this close (nil if none); it fills at
+	// the next bar's open, so a signal on the last bar stays unexecuted.
+	Signal *Signal
+	// Trades closed during this bar.
+	Trades []Trade
+}
+
+// NewSimulator prepares a run of cfg.Strategy (cloned) over bars.
+func NewSimulator(inst Instrument, bars []Bar, cfg RunConfig) *Simulator {
+	return &Simulator{
+		leg:   newLeg(inst, bars, cfg),
+		cash:  cfg.InitialCash,
+		curve: make([]Point, 0, len(bars)),
+		peak:  cfg.InitialCash,
+	}
+}
+
+// Strategy returns the simulator's strategy instance (for inspecting its state).
+func (s *Simulator) Strategy() Strategy { return s.strategy }
+
+// Step processes the next bar; ok is false once all bars are done.
+func (s *Simulator) Step() (snap Snapshot, ok bool) {
+	i := s.next
+	if i >= len(s.bars) {
+		return Snapshot{}, false
+	}
+	bar := s.bars[i]
+	if bar.Time.Before(s.cfg.TradeFrom) {
+		// warm-up: the strategy sees the bar, its signals are dropped
+		s.warmup()
+		return Snapshot{Index: i, Bar: bar, Warmup: true, Cash: s.cash, Equity: s.cash, Position: s.pos}, true
+	}
+	closed := len(s.trades)
+
+	// Execute pending order at next bar open (close-confirm model)
+	if s.due() {
+		s.cash += s.fillPending(s.sizeInput(i-1), &s.unfilled)
+	}
+
+	// Protective stop/target orders intrabar, then a new signal at the close
+	cashDelta, sig := s.closeBar(&s.unfilled)
+	s.cash += cashDelta
+
+	equity := s.cash + s.value()
+	s.curve = append(s.curve, Point{Time: s.cfg.FormatTime(bar.Time), Equity: equity})
+	if equity > s.peak {
+		s.peak = equity
+	}
+	if s.peak > 0 {
+		if dd := (s.peak - equity) / s.peak; dd > s.maxDD {
+			s.maxDD = dd
+		}
+	}
+	return Snapshot{
+		Index:    i,
+		Bar:      bar,
+		Cash:     s.cash,
+		Equity:   equity,
+		Position: s.pos,
+		Signal:   sig,
+		Trades:   s.trades[closed:len(s.trades):len(s.trades)],
+	}, true
+}
+
+// sizeInput is the state an entry signalled at bars[signal] is sized against;
+// entries fill only when flat, so equity is the cash.
+func (s *Simulator) sizeInput(signal int) sizeInput {
+	return sizeInput{budget: s.cash * s.cfg.PositionPct, equity: s.cash, atr: atrAt(s.atr, signal), trades: s.trades}
+}
+
+// Run steps through the remaining bars, passing each snapshot to onBar (may be nil).
+func (s *Simulator) Run(onBar func(Snapshot)) {
+	for {
+		snap, ok := s.Step()
+		if !ok {
+			return
+		}
+		if onBar != nil {
+			onBar(snap)
+		}
+	}
+}
+
+// Finish force-closes an open position at the last close and summarizes the run.
+func (s *Simulator) Finish() Result {
+	s.cash += s.forceClose()
+
+	finalEquity := s.cash
+	if len(s.curve) > 0 {
+		finalEquity = s.curve[len(s.curve)-1].Equity
+	}
+
+	win := 0
+	for _, t := range s.trades {
+		if t.NetPnL > 0 {
+			win++
+		}
+	}
+	winRate := 0.0
+	if len(s.trades) > 0 {
+		winRate = float64(win) / float64(len(s.trades)) * 100
+	}
+
+	metrics := ComputeMetrics(s.curve, s.trades, s.cfg.InitialCash)
+	return Result{
+		Symbol:      s.inst.Symbol,
+		Instrument:  string(s.inst.Type),
+		Trades:      s.trades,
+		FinalEquity: round2(finalEquity),
+		MaxDDPct:    round2(s.maxDD * 100),
+		WinRatePct:  round2(winRate),
+		TotalTrades: len(s.trades),
+		EquityCurve: s.curve,
+		Metrics:     &metrics,
+		Unfilled:    s.unfilled,
+	}
+}
@@ -0,0 +1,62 @@
+package backtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulatorSnapshots(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	var bars []Bar
+	for i := 0; i < 20; i++ {
+		bars = append(bars, Bar{Time: start.AddDate(0, 0, i), Open: 10, High: 10.2, Low: 9.8, Close: 10, Volume: 100})
+	}
+	bars[5].Low = 9.0 // touches the stop
+
+	cfg := DefaultRunConfig()
+	cfg.IntrabarExits = true
+	cfg.SlippageBps = 0
+	cfg.CommissionBps = 0
+	cfg.Strategy = &levelStrategy{}
+	inst := Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}
+
+	sim := NewSimulator(inst, bars, cfg)
+	var snaps []Snapshot
+	sim.Run(func(s Snapshot) { snaps = append(snaps, s) })
+	if len(snaps) != len(bars) {
+		t.Fatalf("snapshots %d", len(snaps))
+	}
+	if snaps[1].Signal == nil || snaps[1].Signal.Action != SignalBuy || snaps[1].Position.Side != SideFlat {
+		t.Fatalf("bar 1: %+v", snaps[1])
+	}
+	if snaps[2].Position.Side != SideLong || snaps[2].Position.EntryPrice != 10 {
+		t.Fatalf("bar 2 should hold the filled entry: %+v", snaps[2].Position)
+	}
+	if len(snaps[5].Trades) != 1 || snaps[5].Trades[0].ReasonExit != ReasonStopHit || snaps[5].Trades[0].ReasonEntry != "test" {
+		t.Fatalf("bar 5 trades %+v", snaps[5].Trades)
+	}
+	if snaps[5].Position.Side != SideFlat || snaps[5].Equity != snaps[5].Cash {
+		t.Fatalf("flat after stop: %+v", snaps[5])
+	}
+	res := sim.Finish()
+	if len(res.Trades) != 1 || len(res.EquityCurve) != len(bars) || res.FinalEquity != round2(snaps[19].Equity) {
+		t.Fatalf("result %+v", res)
+	}
+
+	// scans read the same state
+	if sc := scanOne(inst, bars[:2], cfg); sc.NextAction != SignalBuy || sc.SuggestedStop != 0 {
+		t.Fatalf("scan at signal bar %+v", sc)
+	}
+	if sc := scanOne(inst, bars[:4], cfg); sc.PositionSide != SideLong || sc.EntryDate != "2024-01-03" {
+		t.Fatalf("scan while holding %+v", sc)
+	}
+	if sc := scanOne(inst, bars[:8], cfg); sc.PositionSide != SideFlat {
+		t.Fatalf("scan must apply the intrabar stop %+v", sc)
+	}
+
+	cfg.TradeFrom = bars[3].Time
+	sim = NewSimulator(inst, bars, cfg)
+	if s, _ := sim.Step(); !s.Warmup || s.Equity != cfg.InitialCash {
+		t.Fatalf("warm-up snapshot %+v", s)
+	}
+}
@@ -0,0 +1,203 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"stock/indicators"
+)
+
+// Position sizing models (backtest.sizing.model).
+const (
+	SizingPercent       = "percent"        // the whole budget (cash * position_pct), the default
+	SizingFixedLots     = "fixed_lots"     // a fixed number of lots (stocks) or contracts (futures)
+	SizingFixedNotional = "fixed_notional" // a fixed notional per entry
+	SizingRisk          = "risk"           // lose risk_pct of equity at the strategy's stop
+	SizingATR           = "atr"            // lose risk_pct of equity on an atr_mult * ATR move
+	SizingKelly         = "kelly"          // a capped fraction of Kelly from the closed trades
+)
+
+// Sizing picks the quantity of new positions. Every model is capped by the
+// position budget (cash * position_pct, or the portfolio slot), and a
+// signal's own RiskPct (e.g. turtle's risk_pct) caps it further.
+type Sizing struct {
+	Model string `yaml:"model"`
+
+	// Lots per entry (fixed_lots): stock lots of LotSize shares, or contracts.
+	Lots float64 `yaml:"lots"`
+	// Notional per entry (fixed_notional): price * qty * multiplier.
+	Notional float64 `yaml:"notional"`
+
+	// RiskPct is the fraction of equity lost at the stop (risk) or on an
+	// ATRMult * ATR(ATRN) move from the fill (atr); default 1%.
+	RiskPct float64 `yaml:"risk_pct"`
+	ATRN    int     `yaml:"atr_n"`
+	ATRMult float64 `yaml:"atr_mult"`
+
+	// KellyFraction scales the Kelly fraction of the closed trades (default
+	// half Kelly); the result, a fraction of the budget, is capped at KellyCap
+	// and is KellyCap itself until KellyMinTrades trades have closed.
+	KellyFraction  float64 `yaml:"kelly_fraction"`
+	KellyCap       float64 `yaml:"kelly_cap"`
+	KellyMinTrades int     `yaml:"kelly_min_trades"`
+}
+
+// normalized applies defaults and validates the fields of backtest.sizing
+// the selected model reads; the others are ignored.
+func (z Sizing) normalized() (Sizing, error) {
+	z.Model = strings.ToLower(strings.TrimSpace(z.Model))
+	if z.Model == "" {
+		z.Model = SizingPercent
+	}
+	if z.RiskPct == 0 {
+		z.RiskPct = 0.01
+	}
+	if z.ATRN == 0 {
+		z.ATRN = 20
+	}
+	if z.ATRMult == 0 {
+		z.ATRMult = 2
+	}
+	if z.KellyFraction == 0 {
+		z.KellyFraction = 0.5
+	}
+	if z.KellyCap == 0 {
+		z.KellyCap = 0.25
+	}
+	if z.KellyMinTrades == 0 {
+		z.KellyMinTrades = 20
+	}
+
+	switch z.Model {
+	case SizingPercent:
+	case SizingFixedLots:
+		if z.Lots <= 0 {
+			return z, fmt.Errorf("lots must be > 0")
+		}
+	case SizingFixedNotional:
+		if z.Notional <= 0 {
+			return z, fmt.Errorf("notional must be > 0")
+		}
+	case SizingRisk:
+		return z, z.validateRisk()
+	case SizingATR:
+		if err := z.validateRisk(); err != nil {
+			return z, err
+		}
+		if z.ATRN < 1 || z.ATRN > 250 {
+			return z, fmt.Errorf("atr_n out of range")
+		}
+		if z.ATRMult <= 0 || z.ATRMult > 20 {
+			return z, fmt.Errorf("atr_mult out of range")
+		}
+	case SizingKelly:
+		if z.KellyFraction <= 0 || z.KellyFraction > 1 {
+			return z, fmt.Errorf("kelly_fraction must be in (0,1]")
+		}
+		if z.KellyCap <= 0 || z.KellyCap > 1 {
+			return z, fmt.Errorf("kelly_cap must be in (0,1]")
+		}
+		if z.KellyMinTrades < 1 {
+			return z, fmt.Errorf("kelly_min_trades must be >= 1")
+		}
+	default:
+		return z, fmt.Errorf("unknown model: %s (percent|fixed_lots|fixed_notional|risk|atr|kelly)", z.Model)
+	}
+	return z, nil
+}
+
+func (z Sizing) validateRisk() error {
+	if z.RiskPct <= 0 || z.RiskPct > 0.2 {
+		return fmt.Errorf("risk_pct must be in (0,0.2]")
+	}
+	return nil
+}
+
+// sizeInput is the account state an entry is sized against.
+type sizeInput struct {
+	budget float64 // capital allotted to the position
+	equity float64 // account equity before the fill
+	atr    float64 // ATR(atr_n) at the signal bar (atr model)
+	trades []Trade // trades closed so far (kelly model)
+}
+
+// qty sizes an entry of sig filling at price; 0 skips it (risk without a
+// stop, atr before the ATR is ready, kelly without an edge).
+func (z Sizing) qty(inst Instrument, cfg RunConfig, sig *Signal, price float64, in sizeInput) float64 {
+	margin := marginRate(inst, cfg)
+	qty := sizeQty(inst, in.budget, price, 1, margin)
+	switch z.Model {
+	case SizingFixedLots:
+		lots := z.Lots
+		if inst.Type == InstrumentTypeStock {
+			lot := inst.LotSize
+			if lot <= 0 {
+				lot = 100
+			}
+			lots *= float64(lot)
+		}
+		qty = math.Min(qty, lots)
+	case SizingFixedNotional:
+		qty = math.Min(qty, riskQty(inst, z.Notional, price))
+	case SizingRisk:
+		if sig.Stop <= 0 {
+			return 0
+		}
+		qty = math.Min(qty, riskQty(inst, in.equity*z.RiskPct, math.Abs(price-sig.Stop)))
+	case SizingATR:
+		if !(in.atr > 0) {
+			return 0
+		}
+		qty = math.Min(qty, riskQty(inst, in.equity*z.RiskPct, z.ATRMult*in.atr))
+	case SizingKelly:
+		qty = sizeQty(inst, in.budget, price, z.kelly(in.trades), margin)
+	}
+	if sig.RiskPct > 0 && sig.Stop > 0 {
+		qty = math.Min(qty, riskQty(inst, in.budget*sig.RiskPct, math.Abs(price-sig.Stop)))
+	}
+	return qty
+}
+
+// kelly returns KellyFraction * (W - (1-W)/R) capped at KellyCap, where W is
+// the win rate and R the average win over the average loss of trades.
+func (z Sizing) kelly(trades []Trade) float64 {
+	if len(trades) < z.KellyMinTrades {
+		return z.KellyCap
+	}
+	var wins, won, lost float64
+	for _, t := range trades {
+		if t.NetPnL > 0 {
+			wins++
+			won += t.NetPnL
+		} else {
+			lost -= t.NetPnL
+		}
+	}
+	if wins == 0 {
+		return 0
+	}
+	f := 1.0 // no losses: bet the cap
+	if lost > 0 {
+		w := wins / float64(len(trades))
+		r := (won / wins) / (lost / (float64(len(trades)) - wins))
+		f = w - (1-w)/r
+	}
+	return math.Max(0, math.Min(z.KellyCap, z.KellyFraction*f))
+}
+
+// sizingATR is the ATR series entries of bars are sized with (atr model only).
+func (cfg RunConfig) sizingATR(bars []Bar) []float64 {
+	if cfg.Sizing.Model != SizingATR {
+		return nil
+	}
+	return indicators.ATRSeries(bars, cfg.Sizing.ATRN)
+}
+
+// atrAt returns atr[i], or 0 when it is not available.
+func atrAt(atr []float64, i int) float64 {
+	if i < 0 || i >= len(atr) {
+		return 0
+	}
+	return atr[i]
+}
@@ -0,0 +1,102 @@
+package backtest
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSizingModels(t *testing.T) {
+	stock := Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}
+	cfg := DefaultRunConfig()
+	in := sizeInput{budget: 1_000_000, equity: 1_000_000, atr: 0.25}
+	buy := &Signal{Action: SignalBuy}
+	withStop := &Signal{Action: SignalBuy, Stop: 9.5}
+
+	var wins []Trade
+	for k := 0; k < 20; k++ {
+		pnl := 300.0
+		if k%2 == 0 {
+			pnl = -100 // 10 wins of 300, 10 losses of 100: Kelly 0.5 - 0.5/3 = 1/3
+		}
+		wins = append(wins, Trade{NetPnL: pnl})
+	}
+	losses := make([]Trade, 20)
+	for k := range losses {
+		losses[k].NetPnL = -1
+	}
+
+	for _, c := range []struct {
+		name   string
+		sizing Sizing
+		sig    *Signal
+		in     sizeInput
+		want   float64
+	}{
+		{"percent", Sizing{}, buy, in, 100_000},
+		{"fixed lots", Sizing{Model: SizingFixedLots, Lots: 3}, buy, in, 300},
+		{"fixed lots capped by budget", Sizing{Model: SizingFixedLots, Lots: 3}, buy, sizeInput{budget: 2_500}, 200},
+		{"fixed notional", Sizing{Model: SizingFixedNotional, Notional: 50_000}, buy, in, 5_000},
+		{"risk", Sizing{Model: SizingRisk}, withStop, in, 20_000},
+		{"risk without stop", Sizing{Model: SizingRisk}, buy, in, 0},
+		{"atr", Sizing{Model: SizingATR}, buy, in, 20_000},
+		{"atr not ready", Sizing{Model: SizingATR}, buy, sizeInput{budget: 1_000_000, equity: 1_000_000, atr: math.NaN()}, 0},
+		{"kelly before min trades", Sizing{Model: SizingKelly}, buy, in, 25_000},
+		{"half kelly", Sizing{Model: SizingKelly}, buy, sizeInput{budget: 1_000_000, trades: wins}, 16_600},
+		{"kelly without edge", Sizing{Model: SizingKelly}, buy, sizeInput{budget: 1_000_000, trades: losses}, 0},
+		{"signal risk caps the model", Sizing{}, &Signal{Action: SignalBuy, Stop: 9.5, RiskPct: 0.02}, in, 40_000},
+	} {
+		z, err := c.sizing.normalized()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := z.qty(stock, cfg, c.sig, 10, c.in); got != c.want {
+			t.Errorf("%s: qty %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	fut := Instrument{Symbol: "nf_RB0", Type: InstrumentTypeFutures, Multiplier: 10, MarginRate: 0.1}
+	z, _ := Sizing{Model: SizingRisk}.normalized()
+	// 10_000 at risk over 50 points * 10 per contract
+	if got := z.qty(fut, cfg, &Signal{Action: SignalShort, Stop: 3550}, 3500, in); got != 20 {
+		t.Fatalf("futures risk qty %v", got)
+	}
+
+	// fields of other models are not validated
+	if _, err := (Sizing{Model: SizingPercent, RiskPct: 0.5, KellyCap: 2, ATRN: -1}).normalized(); err != nil {
+		t.Fatalf("percent with unused fields: %v", err)
+	}
+	for _, bad := range []Sizing{{Model: "martingale"}, {Model: SizingFixedLots}, {Model: SizingFixedNotional}, {Model: SizingRisk, RiskPct: 0.5}, {Model: SizingKelly, KellyCap: 2}, {Model: SizingATR, ATRMult: 50}} {
+		if _, err := bad.normalized(); err == nil {
+			t.Errorf("%+v: want error", bad)
+		}
+	}
+}
+
+func TestRiskSizingRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backtest.yaml")
+	yml := "backtest:\n  initial_cash: 100000\n  slippage_bps: 0\n  sizing:\n    model: risk\n    risk_pct: 0.01\n"
+	if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadRunConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Strategy, err = NewStrategy("test_script", map[string]any{"buy": []int{1}, "sell": []int{5}, "stop": 9.5}); err != nil {
+		t.Fatal(err)
+	}
+	res := runOne(Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}, flatBars(20), cfg)
+	// 1% of 100k lost on the 0.5 move from the 10 open to the 9.5 stop
+	if len(res.Trades) != 1 || res.Trades[0].Qty != 2_000 {
+		t.Fatalf("trades %+v", res.Trades)
+	}
+
+	if err := os.WriteFile(path, []byte("backtest:\n  sizing:\n    model: fixed_lots\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRunConfig(path); err == nil {
+		t.Fatal("expected error for fixed_lots without lots")
+	}
+}
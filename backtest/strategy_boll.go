@@ -0,0 +1,140 @@
+package backtest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"stock/indicators"
+)
+
+// BollParams: Bollinger band strategy in one of two modes.
+//   - reversion: buy a close back inside after closing below the lower band,
+//     exit at the middle band (shorts mirror it at the upper band)
+//   - breakout: buy a close above the upper band, exit on a close below the
+//     middle band (shorts mirror it at the lower band)
+type BollParams struct {
+	N    int     `yaml:"n" json:"n"`
+	K    float64 `yaml:"k" json:"k"`
+	Mode string  `yaml:"mode" json:"mode"`
+	// Short also trades the mirrored setup (futures with allow_short only).
+	Short bool `yaml:"short" json:"short"`
+}
+
+func (p BollParams) withDefaults() BollParams {
+	if p.N <= 0 {
+		p.N = 20
+	}
+	if p.K <= 0 {
+		p.K = 2
+	}
+	p.Mode = strings.ToLower(strings.TrimSpace(p.Mode))
+	if p.Mode == "" {
+		p.Mode = "reversion"
+	}
+	return p
+}
+
+func (p BollParams) validate() error {
+	if p.N < 2 || p.N > 500 {
+		return fmt.Errorf("strategy.params.n out of range")
+	}
+	if p.K > 10 {
+		return fmt.Errorf("strategy.params.k out of range")
+	}
+	if p.Mode != "reversion" && p.Mode != "breakout" {
+		return fmt.Errorf("strategy.params.mode must be reversion or breakout")
+	}
+	return nil
+}
+
+func init() {
+	RegisterStrategy(StrategyDef[BollParams]{
+		Name:     "boll",
+		Defaults: BollParams.withDefaults,
+		Validate: BollParams.validate,
+		New:      func(p BollParams) Strategy { return NewBollStrategy(p) },
+	})
+}
+
+type BollStrategy struct {
+	p BollParams
+
+	computed       barsKey
+	up, mid, lower []float64
+	lastPlan       *tradePlan
+}
+
+func NewBollStrategy(p BollParams) *BollStrategy {
+	return &BollStrategy{p: p.withDefaults()}
+}
+
+func (s *BollStrategy) Clone() Strategy { return NewBollStrategy(s.p) }
+
+// Levels returns the lower and upper band at bar i.
+func (s *BollStrategy) Levels(bars []Bar, i int) (support, resistance float64) {
+	up, _, lo := indicators.BollingerSeries(indicators.Closes(bars[:i+1]), s.p.N, s.p.K)
+	if !indicators.Valid(up[i]) {
+		return 0, 0
+	}
+	return lo[i], up[i]
+}
+
+// Plan returns the middle band as the target of a reversion entry at t.
+func (s *BollStrategy) Plan(t time.Time) (stop, target float64, ok bool) {
+	if s.lastPlan == nil || !s.lastPlan.time.Equal(t) || s.lastPlan.target <= 0 {
+		return 0, 0, false
+	}
+	return 0, s.lastPlan.target, true
+}
+
+func (s *BollStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
+	if s.computed.update(bars) {
+		s.up, s.mid, s.lower = indicators.BollingerSeries(indicators.Closes(bars), s.p.N, s.p.K)
+	}
+	if i < 1 || !indicators.Valid(s.mid[i-1]) {
+		return nil
+	}
+	bar := bars[i]
+	c, prev := bar.Close, bars[i-1].Close
+	switch pos.Side {
+	case SideLong:
+		if (s.p.Mode == "reversion" && c >= s.mid[i]) || (s.p.Mode == "breakout" && c < s.mid[i]) {
+			return &Signal{Time: bar.Time, Action: SignalSell, Reason: "boll_" + s.p.Mode + "_exit"}
+		}
+	case SideShort:
+		if (s.p.Mode == "reversion" && c <= s.mid[i]) || (s.p.Mode == "breakout" && c > s.mid[i]) {
+			return &Signal{Time: bar.Time, Action: SignalCover, Reason: "boll_" + s.p.Mode + "_exit"}
+		}
+	case SideFlat:
+		var long, short bool
+		if s.p.Mode == "reversion" {
+			long = prev < s.lower[i-1] && c >= s.lower[i] && c < s.mid[i]
+			short = prev > s.up[i-1] && c <= s.up[i] && c > s.mid[i]
+		} else {
+			long = prev <= s.up[i-1] && c > s.up[i]
+			short = prev >= s.lower[i-1] && c < s.lower[i]
+		}
+		switch {
+		case long:
+			return s.entry(bar, SignalBuy, i)
+		case short && s.p.Short:
+			return s.entry(bar, SignalShort, i)
+		}
+	}
+	return nil
+}
+
+func (s *BollStrategy) entry(bar Bar, action SignalAction, i int) *Signal {
+	side, dir := SideLong, "up"
+	if action == SignalShort {
+		side, dir = SideShort, "down"
+	}
+	sig := &Signal{Time: bar.Time, Action: action, Reason: "boll_" + s.p.Mode + "_" + dir}
+	plan := &tradePlan{time: bar.Time, side: side, reason: sig.Reason}
+	if s.p.Mode == "reversion" {
+		plan.target = s.mid[i]
+	}
+	s.lastPlan = plan
+	return sig
+}
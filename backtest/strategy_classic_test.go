@@ -0,0 +1,158 @@
+package backtest
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"stock/indicators"
+)
+
+// runSignals feeds bars to s, flipping the position as soon as a signal fires,
+// and returns the signals by bar index.
+func runSignals(s Strategy, bars []Bar) map[int]*Signal {
+	out := map[int]*Signal{}
+	pos := Position{Side: SideFlat}
+	for i := range bars {
+		sig := s.OnBar(i, bars, pos)
+		if sig == nil {
+			continue
+		}
+		out[i] = sig
+		switch sig.Action {
+		case SignalBuy:
+			pos = Position{Side: SideLong, Stop: sig.Stop}
+		case SignalShort:
+			pos = Position{Side: SideShort, Stop: sig.Stop}
+		default:
+			pos = Position{Side: SideFlat}
+		}
+	}
+	return out
+}
+
+func wantSignals(t *testing.T, name string, got map[int]*Signal, want map[int]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: %d signals, want %d", name, len(got), len(want))
+	}
+	for i, w := range want {
+		sig := got[i]
+		if sig == nil || string(sig.Action)+" "+sig.Reason != w {
+			t.Errorf("%s: bar %d signal %+v, want %s", name, i, sig, w)
+		}
+	}
+}
+
+func TestClassicStrategyParams(t *testing.T) {
+	for typ, params := range map[string]map[string]any{
+		"ma_cross":   {"fast": 30, "slow": 10},
+		"macd_cross": {"fast": 26, "slow": 12},
+		"turtle":     {"risk_pct": 0.5},
+		"boll":       {"mode": "squeeze"},
+	} {
+		if _, err := NewStrategy(typ, params); err == nil {
+			t.Errorf("%s %v: validation error expected", typ, params)
+		}
+		if _, err := NewStrategy(typ, nil); err != nil {
+			t.Errorf("%s defaults: %v", typ, err)
+		}
+	}
+	s, _ := NewStrategy("ma_cross", map[string]any{"ma_type": "EMA"})
+	if p := s.(*MACrossStrategy).p; p.Fast != 10 || p.Slow != 30 || p.MAType != "ema" {
+		t.Fatalf("ma_cross defaults %+v", p)
+	}
+}
+
+func TestMACrossStrategy(t *testing.T) {
+	bars := closeBars(10, 10, 10, 10, 10, 11, 12, 13, 14, 13, 12, 11, 10, 9, 9, 10, 11)
+	long := NewMACrossStrategy(MACrossParams{Fast: 2, Slow: 4})
+	wantSignals(t, "long only", runSignals(long, bars), map[int]string{
+		5: "buy ma_cross_up", 10: "sell ma_cross_down", 16: "buy ma_cross_up",
+	})
+	short := NewMACrossStrategy(MACrossParams{Fast: 2, Slow: 4, Short: true})
+	wantSignals(t, "short enabled", runSignals(short, bars), map[int]string{
+		5: "buy ma_cross_up", 10: "sell ma_cross_down", 16: "buy ma_cross_up",
+	})
+	// a short opens on a down cross while flat and covers on the next up cross
+	down := closeBars(10, 10, 10, 10, 10, 9, 8, 7, 8, 9, 10, 11)
+	wantSignals(t, "short", runSignals(short, down), map[int]string{5: "short ma_cross_down", 9: "cover ma_cross_up"})
+}
+
+func TestMACDCrossStrategy(t *testing.T) {
+	closes := make([]float64, 160)
+	for i := range closes {
+		closes[i] = 20 + 3*math.Sin(float64(i)/8) + float64(i)*0.02
+	}
+	bars := closeBars(closes...)
+	dif, _, _ := indicators.MACDSeries(closes, 12, 26, 9)
+
+	plain := runSignals(NewMACDCrossStrategy(MACDCrossParams{}), bars)
+	filtered := runSignals(NewMACDCrossStrategy(MACDCrossParams{ZeroFilter: true}), bars)
+	if len(plain) < 4 || len(filtered) == 0 || len(filtered) >= len(plain) {
+		t.Fatalf("signals: plain %d, zero filter %d", len(plain), len(filtered))
+	}
+	for i, sig := range filtered {
+		if sig.Action == SignalBuy && dif[i] <= 0 {
+			t.Fatalf("zero filter bought below zero at %d (dif %v)", i, dif[i])
+		}
+		if !strings.HasPrefix(sig.Reason, "macd_cross_") {
+			t.Fatalf("reason %q", sig.Reason)
+		}
+	}
+}
+
+func TestTurtleStrategy(t *testing.T) {
+	closes := make([]float64, 0, 40)
+	for i := 0; i < 25; i++ {
+		closes = append(closes, 10)
+	}
+	closes = append(closes, 11, 11.2, 11.4, 11.3, 11.1, 11.2, 11.3, 11.2, 11.3, 11.4, 11.3, 10.8)
+	bars := closeBars(closes...)
+
+	s := NewTurtleStrategy(TurtleParams{RiskPct: 0.01})
+	sigs := runSignals(s, bars)
+	wantSignals(t, "turtle", sigs, map[int]string{25: "buy turtle_breakout_up", 36: "sell turtle_exit"})
+	// ATR(20) is 0.2 on the flat bars; the breakout bar's true range is 1.1
+	n := (0.2*19 + 1.1) / 20
+	if entry := sigs[25]; math.Abs(entry.Stop-(11-2*n)) > 1e-9 || entry.RiskPct != 0.02 {
+		t.Fatalf("entry %+v", entry)
+	}
+	if sup, res := s.Levels(bars, 25); sup != 9.9 || res != 10.1 {
+		t.Fatalf("levels %v %v", sup, res)
+	}
+
+	// the engine sizes the entry so the stop loses 2% of the budget
+	cfg := DefaultRunConfig()
+	cfg.Strategy = s
+	cfg.SlippageBps = 0
+	inst := Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}
+	res := runOne(inst, bars, cfg)
+	if len(res.Trades) != 1 {
+		t.Fatalf("trades %+v", res.Trades)
+	}
+	tr := res.Trades[0]
+	want := math.Floor(cfg.InitialCash*0.02/(tr.EntryPrice-(11-2*n))/100) * 100
+	if tr.Qty != want || tr.Qty*tr.EntryPrice > cfg.InitialCash/2 {
+		t.Fatalf("qty %v, want %v", tr.Qty, want)
+	}
+}
+
+func TestBollStrategy(t *testing.T) {
+	flat := func(tail ...float64) []Bar {
+		closes := make([]float64, 25, 25+len(tail))
+		for i := range closes {
+			closes[i] = 10
+		}
+		return closeBars(append(closes, tail...)...)
+	}
+	rev := NewBollStrategy(BollParams{})
+	bars := flat(9, 9.8, 10)
+	wantSignals(t, "reversion", runSignals(rev, bars), map[int]string{26: "buy boll_reversion_up", 27: "sell boll_reversion_exit"})
+	if _, target, ok := rev.Plan(bars[26].Time); !ok || math.Abs(target-9.94) > 1e-9 {
+		t.Fatalf("plan target %v %v", target, ok)
+	}
+
+	brk := NewBollStrategy(BollParams{Mode: "breakout"})
+	wantSignals(t, "breakout", runSignals(brk, flat(11, 10)), map[int]string{25: "buy boll_breakout_up", 26: "sell boll_breakout_exit"})
+}
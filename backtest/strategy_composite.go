@@ -0,0 +1,306 @@
+package backtest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Combiners of the composite strategy.
+const (
+	CombineAll      = "all"      // every child holds the side
+	CombineAny      = "any"      // at least one child holds it and none the opposite side
+	CombineMajority = "majority" // more than half of the children hold it
+	CombineFilter   = "filter"   // the first child trades; the others must hold its side to enter
+)
+
+// CompositeChild is one strategy of a composite; Name defaults to Type.
+type CompositeChild struct {
+	Name   string         `yaml:"name" json:"name"`
+	Type   string         `yaml:"type" json:"type"`
+	Params map[string]any `yaml:"params" json:"params"`
+}
+
+// CompositeParams combines child strategies. Each child runs on its own as if
+// its signals filled at once (a virtual position per child, with its
+// stop/target checked on later bars); the combiner turns the children's sides
+// into one target side. The composite enters when the target side turns long
+// or short and exits when it no longer matches the position (filter: when the
+// first child exits).
+type CompositeParams struct {
+	// Combine: all (default) | any | majority | filter
+	Combine    string           `yaml:"combine" json:"combine"`
+	Strategies []CompositeChild `yaml:"strategies" json:"strategies"`
+}
+
+func (p CompositeParams) withDefaults() CompositeParams {
+	p.Combine = strings.ToLower(strings.TrimSpace(p.Combine))
+	if p.Combine == "" {
+		p.Combine = CombineAll
+	}
+	return p
+}
+
+func (p CompositeParams) validate() error {
+	switch p.Combine {
+	case CombineAll, CombineAny, CombineMajority, CombineFilter:
+	default:
+		return fmt.Errorf("strategy.params.combine must be all, any, majority or filter")
+	}
+	_, err := p.children()
+	return err
+}
+
+func (p CompositeParams) children() ([]*compositeChild, error) {
+	if len(p.Strategies) < 2 {
+		return nil, fmt.Errorf("strategy.params.strategies needs at least 2 strategies")
+	}
+	seen := map[string]bool{}
+	out := make([]*compositeChild, 0, len(p.Strategies))
+	for k, c := range p.Strategies {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			name = strings.TrimSpace(c.Type)
+		}
+		if name == "" {
+			name = DefaultStrategyType
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("strategy.params.strategies[%d]: duplicate name %s (set name)", k, name)
+		}
+		seen[name] = true
+		s, err := NewStrategy(c.Type, c.Params)
+		if err != nil {
+			return nil, fmt.Errorf("strategy.params.strategies[%d] (%s): %w", k, name, err)
+		}
+		out = append(out, newCompositeChild(name, s))
+	}
+	return out, nil
+}
+
+func init() {
+	RegisterStrategy(StrategyDef[CompositeParams]{
+		Name:     "composite",
+		Defaults: CompositeParams.withDefaults,
+		Validate: CompositeParams.validate,
+		New: func(p CompositeParams) Strategy {
+			s, err := NewCompositeStrategy(p)
+			if err != nil {
+				panic(err) // unreachable: Validate built the children
+			}
+			return s
+		},
+	})
+}
+
+type compositeChild struct {
+	name     string
+	strategy Strategy
+	pos      Position // virtual position
+	entry    *Signal  // signal that opened pos
+	exit     string   // exit reason at the current bar, if pos closed
+}
+
+func newCompositeChild(name string, s Strategy) *compositeChild {
+	return &compositeChild{name: name, strategy: s, pos: Position{Side: SideFlat}}
+}
+
+// step runs the child on bar ctx.I and updates its virtual position.
+func (c *compositeChild) step(ctx BarContext) {
+	bar := ctx.Bars[ctx.I]
+	c.exit = ""
+	if exit, _ := protectiveExit(c.pos, bar, ConflictStop); exit != nil {
+		c.pos, c.entry, c.exit = Position{Side: SideFlat}, nil, exit.Reason
+	}
+	var sig *Signal
+	if cs, ok := c.strategy.(ContextStrategy); ok {
+		sig = cs.OnBarContext(ctx, c.pos)
+	} else {
+		sig = c.strategy.OnBar(ctx.I, ctx.Bars, c.pos)
+	}
+	if sig == nil {
+		return
+	}
+	switch {
+	case c.pos.Side == SideFlat && (sig.Action == SignalBuy || sig.Action == SignalShort):
+		side := SideLong
+		if sig.Action == SignalShort {
+			side = SideShort
+		}
+		c.pos = Position{Side: side, Qty: 1, EntryTime: bar.Time, EntryPrice: bar.Close, Stop: sig.Stop, Target: sig.Target}
+		c.entry = sig
+	case c.pos.Side == SideLong && sig.Action == SignalSell, c.pos.Side == SideShort && sig.Action == SignalCover:
+		c.pos, c.entry, c.exit = Position{Side: SideFlat}, nil, sig.Reason
+	}
+}
+
+type CompositeStrategy struct {
+	p        CompositeParams
+	children []*compositeChild
+
+	lastEntry Side // combined entry side at the previous bar
+	lastPlan  *tradePlan
+	feed      timeframeFeed // for direct OnBar calls (the engine calls OnBarContext)
+}
+
+// NewCompositeStrategy builds the child strategies of p.
+func NewCompositeStrategy(p CompositeParams) (*CompositeStrategy, error) {
+	p = p.withDefaults()
+	children, err := p.children()
+	if err != nil {
+		return nil, err
+	}
+	return &CompositeStrategy{p: p, children: children, lastEntry: SideFlat}, nil
+}
+
+func (s *CompositeStrategy) Clone() Strategy {
+	children := make([]*compositeChild, len(s.children))
+	for k, c := range s.children {
+		children[k] = newCompositeChild(c.name, c.strategy.Clone())
+	}
+	return &CompositeStrategy{p: s.p, children: children, lastEntry: SideFlat}
+}
+
+// Levels returns the first child's levels, if it tracks any.
+func (s *CompositeStrategy) Levels(bars []Bar, i int) (support, resistance float64) {
+	if lp, ok := s.children[0].strategy.(LevelsProvider); ok {
+		return lp.Levels(bars, i)
+	}
+	return 0, 0
+}
+
+// Plan returns the stop/target carried by the entry signal at t.
+func (s *CompositeStrategy) Plan(t time.Time) (stop, target float64, ok bool) {
+	if s.lastPlan == nil || !s.lastPlan.time.Equal(t) || (s.lastPlan.stop <= 0 && s.lastPlan.target <= 0) {
+		return 0, 0, false
+	}
+	return s.lastPlan.stop, s.lastPlan.target, true
+}
+
+// VolumeMAN returns the volume MA window of the first child filtering on volume.
+func (s *CompositeStrategy) VolumeMAN() int {
+	for _, c := range s.children {
+		if vf, ok := c.strategy.(VolumeFilter); ok {
+			return vf.VolumeMAN()
+		}
+	}
+	return 0
+}
+
+// HigherTimeframes returns the higher timeframes the children read.
+func (s *CompositeStrategy) HigherTimeframes() []Timeframe {
+	var out []Timeframe
+	for _, c := range s.children {
+		if u, ok := c.strategy.(HigherTimeframeUser); ok {
+			out = append(out, u.HigherTimeframes()...)
+		}
+	}
+	return out
+}
+
+func (s *CompositeStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
+	return s.OnBarContext(s.feed.context(i, bars), pos)
+}
+
+// OnBarContext steps the children, passing the context on to those that use it.
+func (s *CompositeStrategy) OnBarContext(ctx BarContext, pos Position) *Signal {
+	for _, c := range s.children {
+		c.step(ctx)
+	}
+	entry, hold := s.sides()
+	prev := s.lastEntry
+	s.lastEntry = entry
+
+	bar := ctx.Bars[ctx.I]
+	switch {
+	case pos.Side != SideFlat && hold != pos.Side:
+		action := SignalSell
+		if pos.Side == SideShort {
+			action = SignalCover
+		}
+		var reasons []string
+		for _, c := range s.children {
+			if c.exit != "" {
+				reasons = append(reasons, c.name+":"+c.exit)
+			}
+		}
+		return &Signal{Time: bar.Time, Action: action, Reason: s.p.Combine + "_exit(" + strings.Join(reasons, ",") + ")"}
+	case pos.Side == SideFlat && entry != SideFlat && entry != prev:
+		return s.open(bar, entry)
+	}
+	return nil
+}
+
+// sides combines the children's virtual sides into the side to enter and the
+// side to keep holding (they differ only for filter).
+func (s *CompositeStrategy) sides() (entry, hold Side) {
+	if s.p.Combine == CombineFilter {
+		primary := s.children[0].pos.Side
+		for _, c := range s.children[1:] {
+			if c.pos.Side != primary {
+				return SideFlat, primary
+			}
+		}
+		return primary, primary
+	}
+	long, short := 0, 0
+	for _, c := range s.children {
+		switch c.pos.Side {
+		case SideLong:
+			long++
+		case SideShort:
+			short++
+		}
+	}
+	n := len(s.children)
+	side := SideFlat
+	switch s.p.Combine {
+	case CombineAll:
+		if long == n {
+			side = SideLong
+		} else if short == n {
+			side = SideShort
+		}
+	case CombineAny:
+		if long > 0 && short == 0 {
+			side = SideLong
+		} else if short > 0 && long == 0 {
+			side = SideShort
+		}
+	case CombineMajority:
+		if 2*long > n {
+			side = SideLong
+		} else if 2*short > n {
+			side = SideShort
+		}
+	}
+	return side, side
+}
+
+// open builds the entry signal: the reason lists the entry reasons of the
+// children holding side; stop/target/risk come from the first of them
+// (the primary for filter).
+func (s *CompositeStrategy) open(bar Bar, side Side) *Signal {
+	sig := &Signal{Time: bar.Time, Action: SignalBuy}
+	if side == SideShort {
+		sig.Action = SignalShort
+	}
+	var reasons []string
+	var lead *Signal
+	for _, c := range s.children {
+		if c.pos.Side != side || c.entry == nil {
+			continue
+		}
+		reasons = append(reasons, c.name+":"+c.entry.Reason)
+		if lead == nil {
+			lead = c.entry
+		}
+	}
+	sig.Reason = s.p.Combine + "(" + strings.Join(reasons, ",") + ")"
+	if lead != nil {
+		sig = withLevels(sig, lead.Stop, lead.Target)
+		sig.RiskPct = lead.RiskPct
+	}
+	s.lastPlan = &tradePlan{time: bar.Time, side: side, stop: sig.Stop, target: sig.Target, reason: sig.Reason}
+	return sig
+}
@@ -0,0 +1,124 @@
+package backtest
+
+import (
+	"strings"
+	"testing"
+)
+
+// testScriptParams scripts a child strategy for composite tests.
+type testScriptParams struct {
+	Buy    []int   `yaml:"buy" json:"buy"`
+	Sell   []int   `yaml:"sell" json:"sell"`
+	Short  []int   `yaml:"short" json:"short"`
+	Cover  []int   `yaml:"cover" json:"cover"`
+	Reason string  `yaml:"reason" json:"reason"`
+	Stop   float64 `yaml:"stop" json:"stop"`
+}
+
+type testScriptStrategy struct{ p testScriptParams }
+
+func (s *testScriptStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
+	for action, at := range map[SignalAction][]int{SignalBuy: s.p.Buy, SignalSell: s.p.Sell, SignalShort: s.p.Short, SignalCover: s.p.Cover} {
+		for _, k := range at {
+			if k == i {
+				return withLevels(&Signal{Time: bars[i].Time, Action: action, Reason: s.p.Reason}, s.p.Stop, 0)
+			}
+		}
+	}
+	return nil
+}
+func (s *testScriptStrategy) Clone() Strategy { return &testScriptStrategy{p: s.p} }
+
+func init() {
+	RegisterStrategy(StrategyDef[testScriptParams]{
+		Name: "test_script",
+		New:  func(p testScriptParams) Strategy { return &testScriptStrategy{p: p} },
+	})
+}
+
+func script(name, reason string, buy, sell []int) map[string]any {
+	return map[string]any{"name": name, "type": "test_script", "params": map[string]any{"buy": buy, "sell": sell, "reason": reason}}
+}
+
+func compositeSignals(t *testing.T, combine string, children ...map[string]any) map[int]*Signal {
+	t.Helper()
+	list := make([]any, len(children))
+	for k, c := range children {
+		list[k] = c
+	}
+	s, err := NewStrategy("composite", map[string]any{"combine": combine, "strategies": list})
+	if err != nil {
+		t.Fatalf("%s: %v", combine, err)
+	}
+	return runSignals(s, flatBars(20))
+}
+
+func TestCompositeCombiners(t *testing.T) {
+	a := script("a", "ra", []int{2, 10}, []int{6, 14})
+	b := script("b", "rb", []int{4}, []int{8})
+	c := script("c", "rc", []int{5}, []int{12})
+
+	wantSignals(t, "all", compositeSignals(t, "all", a, b), map[int]string{
+		4: "buy all(a:ra,b:rb)", 6: "sell all_exit(a:ra)",
+	})
+	wantSignals(t, "any", compositeSignals(t, "any", a, b), map[int]string{
+		2: "buy any(a:ra)", 8: "sell any_exit(b:rb)", 10: "buy any(a:ra)", 14: "sell any_exit(a:ra)",
+	})
+	// a+b from 4, a+b+c from 5, b+c from 6, c alone from 8
+	wantSignals(t, "majority", compositeSignals(t, "majority", a, b, c), map[int]string{
+		4: "buy majority(a:ra,b:rb)", 8: "sell majority_exit(b:rb)", 10: "buy majority(a:ra,c:rc)", 12: "sell majority_exit(c:rc)",
+	})
+	// the primary (a) trades; b must be long to enter but does not force exits
+	wantSignals(t, "filter", compositeSignals(t, "filter", a, b), map[int]string{
+		4: "buy filter(a:ra,b:rb)", 6: "sell filter_exit(a:ra)",
+	})
+	wantSignals(t, "filter late", compositeSignals(t, "filter", script("a", "ra", []int{2}, []int{12}), b), map[int]string{
+		4: "buy filter(a:ra,b:rb)", 12: "sell filter_exit(a:ra)",
+	})
+}
+
+func TestCompositeChildStops(t *testing.T) {
+	// a child's stop closes its virtual position; the stop is carried to the entry
+	stopped := map[string]any{"type": "test_script", "params": map[string]any{"buy": []int{3}, "stop": 9.8, "reason": "x"}}
+	bars := flatBars(10)
+	bars[6].Low = 9.5
+	s, err := NewStrategy("composite", map[string]any{"combine": "any", "strategies": []any{stopped, script("b", "rb", nil, nil)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigs := runSignals(s, bars)
+	wantSignals(t, "stop", sigs, map[int]string{3: "buy any(test_script:x)", 6: "sell any_exit(test_script:stop_hit)"})
+	if sigs[3].Stop != 9.8 {
+		t.Fatalf("entry stop %v", sigs[3].Stop)
+	}
+	if stop, _, ok := s.(PlanProvider).Plan(bars[3].Time); !ok || stop != 9.8 {
+		t.Fatalf("plan %v %v", stop, ok)
+	}
+}
+
+func TestCompositeParams(t *testing.T) {
+	for _, params := range []map[string]any{
+		{"strategies": []any{script("a", "", nil, nil)}},
+		{"combine": "vote", "strategies": []any{script("a", "", nil, nil), script("b", "", nil, nil)}},
+		{"strategies": []any{script("a", "", nil, nil), script("a", "", nil, nil)}},
+		{"strategies": []any{script("a", "", nil, nil), map[string]any{"type": "ma_cross", "params": map[string]any{"fast": 50, "slow": 5}}}},
+	} {
+		if _, err := NewStrategy("composite", params); err == nil {
+			t.Errorf("%v: error expected", params)
+		}
+	}
+	s, err := NewStrategy("composite", map[string]any{"strategies": []any{
+		map[string]any{"type": "tsai_sen"},
+		map[string]any{"type": "patterns"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.(VolumeFilter).VolumeMAN() != 20 {
+		t.Fatalf("volume MA from tsai_sen")
+	}
+	c := s.Clone().(*CompositeStrategy)
+	if c.children[0].strategy == s.(*CompositeStrategy).children[0].strategy || !strings.HasPrefix(c.p.Combine, "all") {
+		t.Fatalf("clone shares children")
+	}
+}
@@ -0,0 +1,111 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"stock/indicators"
+)
+
+// MACrossParams: dual moving average crossover. Buy when the fast MA crosses
+// above the slow MA, sell when it crosses back below.
+type MACrossParams struct {
+	Fast int `yaml:"fast" json:"fast"`
+	Slow int `yaml:"slow" json:"slow"`
+	// MAType: sma (default) | ema
+	MAType string `yaml:"ma_type" json:"ma_type"`
+	// Short also trades the opposite crosses short (futures with allow_short only).
+	Short bool `yaml:"short" json:"short"`
+}
+
+func (p MACrossParams) withDefaults() MACrossParams {
+	if p.Fast <= 0 {
+		p.Fast = 10
+	}
+	if p.Slow <= 0 {
+		p.Slow = 30
+	}
+	p.MAType = strings.ToLower(strings.TrimSpace(p.MAType))
+	if p.MAType == "" {
+		p.MAType = "sma"
+	}
+	return p
+}
+
+func (p MACrossParams) validate() error {
+	if p.Fast > 500 || p.Slow > 500 {
+		return fmt.Errorf("strategy.params.fast/slow out of range")
+	}
+	if p.Fast >= p.Slow {
+		return fmt.Errorf("strategy.params.fast must be < slow")
+	}
+	if p.MAType != "sma" && p.MAType != "ema" {
+		return fmt.Errorf("strategy.params.ma_type must be sma or ema")
+	}
+	return nil
+}
+
+func init() {
+	RegisterStrategy(StrategyDef[MACrossParams]{
+		Name:     "ma_cross",
+		Defaults: MACrossParams.withDefaults,
+		Validate: MACrossParams.validate,
+		New:      func(p MACrossParams) Strategy { return NewMACrossStrategy(p) },
+	})
+}
+
+type MACrossStrategy struct {
+	p MACrossParams
+
+	computed   barsKey
+	fast, slow []float64
+}
+
+func NewMACrossStrategy(p MACrossParams) *MACrossStrategy {
+	return &MACrossStrategy{p: p.withDefaults()}
+}
+
+func (s *MACrossStrategy) Clone() Strategy { return NewMACrossStrategy(s.p) }
+
+func (s *MACrossStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
+	if s.computed.update(bars) {
+		ma := indicators.SMASeries
+		if s.p.MAType == "ema" {
+			ma = indicators.EMASeries
+		}
+		closes := indicators.Closes(bars)
+		s.fast, s.slow = ma(closes, s.p.Fast), ma(closes, s.p.Slow)
+	}
+	up, down := crossed(s.fast, s.slow, i)
+	return crossSignal(bars[i], pos, up, down, s.p.Short, "ma_cross")
+}
+
+// crossed reports whether a crossed above (up) or below (down) b at bar i.
+func crossed(a, b []float64, i int) (up, down bool) {
+	if i < 1 {
+		return false, false
+	}
+	for _, v := range []float64{a[i-1], b[i-1], a[i], b[i]} {
+		if math.IsNaN(v) {
+			return false, false
+		}
+	}
+	return a[i-1] <= b[i-1] && a[i] > b[i], a[i-1] >= b[i-1] && a[i] < b[i]
+}
+
+// crossSignal maps up/down crosses to orders: up buys (or covers a short),
+// down sells (or opens a short when short is set). Reasons are name_up/name_down.
+func crossSignal(bar Bar, pos Position, up, down, short bool, name string) *Signal {
+	switch {
+	case up && pos.Side == SideFlat:
+		return &Signal{Time: bar.Time, Action: SignalBuy, Reason: name + "_up"}
+	case up && pos.Side == SideShort:
+		return &Signal{Time: bar.Time, Action: SignalCover, Reason: name + "_up"}
+	case down && pos.Side == SideLong:
+		return &Signal{Time: bar.Time, Action: SignalSell, Reason: name + "_down"}
+	case down && pos.Side == SideFlat && short:
+		return &Signal{Time: bar.Time, Action: SignalShort, Reason: name + "_down"}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package backtest
+
+import (
+	"fmt"
+
+	"stock/indicators"
+)
+
+// MACDCrossParams: buy when DIF crosses above DEA, sell when it crosses below.
+type MACDCrossParams struct {
+	Fast   int `yaml:"fast" json:"fast"`
+	Slow   int `yaml:"slow" json:"slow"`
+	Signal int `yaml:"signal" json:"signal"`
+	// ZeroFilter only takes long entries above the zero line (short entries below it).
+	ZeroFilter bool `yaml:"zero_filter" json:"zero_filter"`
+	// Short also trades the opposite crosses short (futures with allow_short only).
+	Short bool `yaml:"short" json:"short"`
+}
+
+func (p MACDCrossParams) withDefaults() MACDCrossParams {
+	if p.Fast <= 0 {
+		p.Fast = 12
+	}
+	if p.Slow <= 0 {
+		p.Slow = 26
+	}
+	if p.Signal <= 0 {
+		p.Signal = 9
+	}
+	return p
+}
+
+func (p MACDCrossParams) validate() error {
+	if p.Fast >= p.Slow || p.Slow > 500 || p.Signal > 200 {
+		return fmt.Errorf("strategy.params.fast/slow/signal out of range (fast < slow <= 500, signal <= 200)")
+	}
+	return nil
+}
+
+func init() {
+	RegisterStrategy(StrategyDef[MACDCrossParams]{
+		Name:     "macd_cross",
+		Defaults: MACDCrossParams.withDefaults,
+		Validate: MACDCrossParams.validate,
+		New:      func(p MACDCrossParams) Strategy { return NewMACDCrossStrategy(p) },
+	})
+}
+
+type MACDCrossStrategy struct {
+	p MACDCrossParams
+
+	computed barsKey
+	dif, dea []float64
+}
+
+func NewMACDCrossStrategy(p MACDCrossParams) *MACDCrossStrategy {
+	return &MACDCrossStrategy{p: p.withDefaults()}
+}
+
+func (s *MACDCrossStrategy) Clone() Strategy { return NewMACDCrossStrategy(s.p) }
+
+func (s *MACDCrossStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
+	if s.computed.update(bars) {
+		s.dif, s.dea, _ = indicators.MACDSeries(indicators.Closes(bars), s.p.Fast, s.p.Slow, s.p.Signal)
+	}
+	up, down := crossed(s.dif, s.dea, i)
+	if s.p.ZeroFilter && pos.Side == SideFlat {
+		// exits are never filtered
+		up = up && s.dif[i] > 0
+		down = down && s.dif[i] < 0
+	}
+	return crossSignal(bars[i], pos, up, down, s.p.Short, "macd_cross")
+}
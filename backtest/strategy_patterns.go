@@ -3,6 +3,7 @@ package backtest
 import (
 	"fmt"
 	"math"
+	"time"
 )
 
 type PatternsParams struct {
@@ -82,6 +83,7 @@ func (p PatternsParams) withDefaults() PatternsParams {
 }
 
 type tradePlan struct {
+	time   time.Time // signal bar
 	side   Side
 	target float64
 	stop   float64
@@ -97,6 +99,43 @@ type PatternsStrategy struct {
 	activePlan  *tradePlan
 }
 
+func init() {
+	RegisterStrategy(StrategyDef[PatternsParams]{
+		Name:     "patterns",
+		Defaults: PatternsParams.withDefaults,
+		Validate: PatternsParams.validate,
+		New:      func(p PatternsParams) Strategy { return NewPatternsStrategy(p) },
+	})
+}
+
+func (p PatternsParams) validate() error {
+	if p.Lookback < 20 || p.Lookback > 2000 {
+		return fmt.Errorf("strategy.params.lookback out of range")
+	}
+	if p.PivotN < 1 || p.PivotN > 20 {
+		return fmt.Errorf("strategy.params.pivot_n out of range")
+	}
+	if p.EqualTolPct > 0.2 {
+		return fmt.Errorf("strategy.params.equal_tol_pct out of range")
+	}
+	if p.BreakPct > 0.2 {
+		return fmt.Errorf("strategy.params.break_pct out of range")
+	}
+	if p.StopBufferPct > 0.2 {
+		return fmt.Errorf("strategy.params.stop_buffer_pct out of range")
+	}
+	if p.TargetMultiple > 10 {
+		return fmt.Errorf("strategy.params.target_multiple out of range")
+	}
+	if p.TriangleLookback < 10 || p.TriangleLookback > 2000 {
+		return fmt.Errorf("strategy.params.triangle_lookback out of range")
+	}
+	if p.TriangleMinPivots < 2 || p.TriangleMinPivots > 50 {
+		return fmt.Errorf("strategy.params.triangle_min_pivots out of range")
+	}
+	return nil
+}
+
 func NewPatternsStrategy(p PatternsParams) *PatternsStrategy {
 	pp := p.withDefaults()
 	return &PatternsStrategy{p: pp, lastPos: SideFlat}
@@ -106,6 +145,14 @@ func (s *PatternsStrategy) Clone() Strategy {
 	return NewPatternsStrategy(s.p)
 }
 
+// Plan returns the stop/target planned with the entry signal at t.
+func (s *PatternsStrategy) Plan(t time.Time) (stop, target float64, ok bool) {
+	if s.pendingPlan == nil || !s.pendingPlan.time.Equal(t) {
+		return 0, 0, false
+	}
+	return s.pendingPlan.stop, s.pendingPlan.target, true
+}
+
 func (s *PatternsStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
 	if i <= 0 {
 		return nil
@@ -163,13 +210,14 @@ func (s *PatternsStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
 		if plan == nil {
 			return nil
 		}
+		plan.time = bars[i].Time
 		s.pendingPlan = plan
 		s.pendingAge = 0
 		switch plan.side {
 		case SideLong:
-			return &Signal{Time: bars[i].Time, Action: SignalBuy, Reason: plan.reason}
+			return withLevels(&Signal{Time: bars[i].Time, Action: SignalBuy, Reason: plan.reason}, plan.stop, plan.target)
 		case SideShort:
-			return &Signal{Time: bars[i].Time, Action: SignalShort, Reason: plan.reason}
+			return withLevels(&Signal{Time: bars[i].Time, Action: SignalShort, Reason: plan.reason}, plan.stop, plan.target)
 		default:
 			return nil
 		}
@@ -0,0 +1,174 @@
+package backtest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RulesParams defines a strategy by conditions over bar fields and indicators
+// (see rules_expr.go for the expression language), evaluated at each close.
+type RulesParams struct {
+	// Entry opens a long position, e.g. "close > sma(close, 20) and rsi(14) < 70".
+	Entry string `yaml:"entry" json:"entry"`
+	// Exit closes the long position (empty: only stop/target or the end of the run).
+	Exit string `yaml:"exit" json:"exit"`
+	// ShortEntry / ShortExit do the same for shorts (futures with allow_short only).
+	ShortEntry string `yaml:"short_entry" json:"short_entry"`
+	ShortExit  string `yaml:"short_exit" json:"short_exit"`
+
+	// StopPct / TargetPct attach protective stop/target orders at the signal
+	// close -/+ pct (reversed for shorts); 0 = none.
+	StopPct   float64 `yaml:"stop_pct" json:"stop_pct"`
+	TargetPct float64 `yaml:"target_pct" json:"target_pct"`
+}
+
+type compiledRules struct {
+	entry, exit, shortEntry, shortExit *ruleExpr
+}
+
+func (p RulesParams) compile() (compiledRules, error) {
+	var c compiledRules
+	for _, r := range []struct {
+		key string
+		src string
+		dst **ruleExpr
+	}{
+		{"entry", p.Entry, &c.entry},
+		{"exit", p.Exit, &c.exit},
+		{"short_entry", p.ShortEntry, &c.shortEntry},
+		{"short_exit", p.ShortExit, &c.shortExit},
+	} {
+		if strings.TrimSpace(r.src) == "" {
+			continue
+		}
+		e, err := compileRule(r.src)
+		if err != nil {
+			return c, fmt.Errorf("strategy.params.%s: %w", r.key, err)
+		}
+		*r.dst = e
+	}
+	return c, nil
+}
+
+func (p RulesParams) validate() error {
+	if strings.TrimSpace(p.Entry) == "" && strings.TrimSpace(p.ShortEntry) == "" {
+		return fmt.Errorf("strategy.params.entry or short_entry is required")
+	}
+	if p.StopPct < 0 || p.StopPct >= 1 {
+		return fmt.Errorf("strategy.params.stop_pct out of range")
+	}
+	if p.TargetPct < 0 || p.TargetPct > 10 {
+		return fmt.Errorf("strategy.params.target_pct out of range")
+	}
+	_, err := p.compile()
+	return err
+}
+
+func init() {
+	RegisterStrategy(StrategyDef[RulesParams]{
+		Name:     "rules",
+		Validate: RulesParams.validate,
+		New: func(p RulesParams) Strategy {
+			s, err := NewRulesStrategy(p)
+			if err != nil {
+				panic(err) // unreachable: Validate compiled the rules
+			}
+			return s
+		},
+	})
+}
+
+type RulesStrategy struct {
+	p     RulesParams
+	rules compiledRules
+
+	// rule values over the whole series seen last (see barsKey)
+	evaluated barsKey
+	entry     []float64
+	exit      []float64
+	sEntry    []float64
+	sExit     []float64
+	lastPlan  *tradePlan
+}
+
+// NewRulesStrategy compiles the rules in p.
+func NewRulesStrategy(p RulesParams) (*RulesStrategy, error) {
+	rules, err := p.compile()
+	if err != nil {
+		return nil, err
+	}
+	return &RulesStrategy{p: p, rules: rules}, nil
+}
+
+func (s *RulesStrategy) Clone() Strategy {
+	return &RulesStrategy{p: s.p, rules: s.rules}
+}
+
+// Plan returns the stop/target attached to the entry signal at t.
+func (s *RulesStrategy) Plan(t time.Time) (stop, target float64, ok bool) {
+	if s.lastPlan == nil || !s.lastPlan.time.Equal(t) || (s.lastPlan.stop <= 0 && s.lastPlan.target <= 0) {
+		return 0, 0, false
+	}
+	return s.lastPlan.stop, s.lastPlan.target, true
+}
+
+func (s *RulesStrategy) prepare(bars []Bar) {
+	if !s.evaluated.update(bars) {
+		return
+	}
+	eval := func(e *ruleExpr) []float64 {
+		if e == nil {
+			return nil
+		}
+		return e.eval(bars)
+	}
+	s.entry = eval(s.rules.entry)
+	s.exit = eval(s.rules.exit)
+	s.sEntry = eval(s.rules.shortEntry)
+	s.sExit = eval(s.rules.shortExit)
+}
+
+func (s *RulesStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
+	s.prepare(bars)
+	on := func(vals []float64) bool { return vals != nil && fires(vals[i]) }
+	bar := bars[i]
+	switch pos.Side {
+	case SideFlat:
+		if on(s.entry) {
+			return s.open(bar, SideLong)
+		}
+		if on(s.sEntry) {
+			return s.open(bar, SideShort)
+		}
+	case SideLong:
+		if on(s.exit) {
+			return &Signal{Time: bar.Time, Action: SignalSell, Reason: "rules_exit"}
+		}
+	case SideShort:
+		if on(s.sExit) {
+			return &Signal{Time: bar.Time, Action: SignalCover, Reason: "rules_short_exit"}
+		}
+	}
+	return nil
+}
+
+func (s *RulesStrategy) open(bar Bar, side Side) *Signal {
+	plan := &tradePlan{time: bar.Time, side: side, reason: "rules_entry"}
+	sig := &Signal{Time: bar.Time, Action: SignalBuy}
+	sign := 1.0
+	if side == SideShort {
+		plan.reason = "rules_short_entry"
+		sig.Action = SignalShort
+		sign = -1
+	}
+	if s.p.StopPct > 0 {
+		plan.stop = bar.Close * (1 - sign*s.p.StopPct)
+	}
+	if s.p.TargetPct > 0 {
+		plan.target = bar.Close * (1 + sign*s.p.TargetPct)
+	}
+	s.lastPlan = plan
+	sig.Reason = plan.reason
+	return withLevels(sig, plan.stop, plan.target)
+}
@@ -1,9 +1,11 @@
 package backtest
 
 import (
-	"math"
+	"fmt"
 	"sort"
 	"time"
+
+	"stock/indicators"
 )
 
 type TsaiSenParams struct {
@@ -29,6 +31,12 @@ type TsaiSenParams struct {
 	VolRatioMin  float64 `yaml:"vol_ratio_min" json:"vol_ratio_min"`
 	EnableFakeBO bool    `yaml:"enable_fake_breakout" json:"enable_fake_breakout"`
 	FakeMaxBars  int     `yaml:"fake_max_bars" json:"fake_max_bars"`
+
+	// HTFFilter: "" (off) | weekly | monthly. Entries need the last completed
+	// higher-timeframe close above its box support (shorts: below its box
+	// resistance); levels use the same settings with HTFLookback bars.
+	HTFFilter   string `yaml:"htf_filter" json:"htf_filter"`
+	HTFLookback int    `yaml:"htf_lookback" json:"htf_lookback"`
 }
 
 func (p TsaiSenParams) withDefaults() TsaiSenParams {
@@ -80,9 +88,72 @@ func (p TsaiSenParams) withDefaults() TsaiSenParams {
 	if p.FakeMaxBars <= 0 {
 		p.FakeMaxBars = 10
 	}
+	if p.HTFLookback <= 0 {
+		p.HTFLookback = 26
+	}
 	return p
 }
 
+// validate checks params after defaults.
+func (p TsaiSenParams) validate() error {
+	if p.LevelMode != "pivots" && p.LevelMode != "extremes" {
+		return fmt.Errorf("strategy.params.level_mode invalid")
+	}
+	if p.BoxLookback < 10 || p.BoxLookback > 500 {
+		return fmt.Errorf("strategy.params.box_lookback out of range")
+	}
+	if p.PivotN < 1 || p.PivotN > 20 {
+		return fmt.Errorf("strategy.params.pivot_n out of range")
+	}
+	if p.TouchTolPct <= 0 || p.TouchTolPct > 0.05 {
+		return fmt.Errorf("strategy.params.touch_tol_pct out of range")
+	}
+	if p.MinTouches < 2 || p.MinTouches > 50 {
+		return fmt.Errorf("strategy.params.min_touches out of range")
+	}
+	if p.MinRangePct <= 0 || p.MinRangePct > 0.5 {
+		return fmt.Errorf("strategy.params.min_range_pct out of range")
+	}
+	if p.BreakPct <= 0 || p.BreakPct > 0.2 {
+		return fmt.Errorf("strategy.params.break_pct out of range")
+	}
+	if p.ReclaimPct < 0 || p.ReclaimPct > 0.2 {
+		return fmt.Errorf("strategy.params.reclaim_pct out of range")
+	}
+	if p.FlipMaxBars <= 0 || p.FlipMaxBars > 300 {
+		return fmt.Errorf("strategy.params.flip_max_bars out of range")
+	}
+	if p.EntryMode != "reclaim_support" && p.EntryMode != "stabilize_support" && p.EntryMode != "break_resistance" {
+		return fmt.Errorf("strategy.params.entry_mode invalid")
+	}
+	if p.StabilizeBars < 1 || p.StabilizeBars > 60 {
+		return fmt.Errorf("strategy.params.stabilize_bars out of range")
+	}
+	if p.StopBufferPct <= 0 || p.StopBufferPct > 0.2 {
+		return fmt.Errorf("strategy.params.stop_buffer_pct out of range")
+	}
+	if p.TargetMultiple <= 0 || p.TargetMultiple > 10 {
+		return fmt.Errorf("strategy.params.target_multiple out of range")
+	}
+	if p.VolMAN < 1 || p.VolMAN > 300 {
+		return fmt.Errorf("strategy.params.vol_ma_n out of range")
+	}
+	if p.VolRatioMin < 0 || p.VolRatioMin > 50 {
+		return fmt.Errorf("strategy.params.vol_ratio_min out of range")
+	}
+	if p.FakeMaxBars < 1 || p.FakeMaxBars > 300 {
+		return fmt.Errorf("strategy.params.fake_max_bars out of range")
+	}
+	if _, err := ParseTimeframe(p.HTFFilter); err != nil {
+		return fmt.Errorf("strategy.params.htf_filter: %w", err)
+	}
+	if p.HTFLookback < 4 || p.HTFLookback > 500 {
+		return fmt.Errorf("strategy.params.htf_lookback out of range")
+	}
+
+	return nil
+}
+
 type TsaiSenStrategy struct {
 	p TsaiSenParams
 
@@ -110,6 +181,15 @@ type tsaiSenPlan struct {
 	target float64
 }
 
+func init() {
+	RegisterStrategy(StrategyDef[TsaiSenParams]{
+		Name:     "tsai_sen",
+		Defaults: TsaiSenParams.withDefaults,
+		Validate: TsaiSenParams.validate,
+		New:      func(p TsaiSenParams) Strategy { return NewTsaiSenStrategy(p) },
+	})
+}
+
 func NewTsaiSenStrategy(p TsaiSenParams) *TsaiSenStrategy {
 	pp := p.withDefaults()
 	return &TsaiSenStrategy{p: pp}
@@ -119,10 +199,66 @@ func (s *TsaiSenStrategy) Params() TsaiSenParams {
 	return s.p
 }
 
+// Levels returns the support/resistance at bars[i].
+func (s *TsaiSenStrategy) Levels(bars []Bar, i int) (support, resistance float64) {
+	return levels(bars, i, s.p)
+}
+
+// Plan returns the stop/target planned with the entry signal at t.
+func (s *TsaiSenStrategy) Plan(t time.Time) (stop, target float64, ok bool) {
+	if s.lastPlan == nil || !s.lastPlan.time.Equal(t) {
+		return 0, 0, false
+	}
+	return s.lastPlan.stop, s.lastPlan.target, true
+}
+
+// VolumeMAN is the volume MA window of the volume filter.
+func (s *TsaiSenStrategy) VolumeMAN() int {
+	return s.p.VolMAN
+}
+
 func (s *TsaiSenStrategy) Clone() Strategy {
 	return NewTsaiSenStrategy(s.p)
 }
 
+// OnBarContext applies the higher-timeframe filter (htf_filter) to entries.
+func (s *TsaiSenStrategy) OnBarContext(ctx BarContext, pos Position) *Signal {
+	sig := s.OnBar(ctx.I, ctx.Bars, pos)
+	if sig == nil || s.p.HTFFilter == "" || (sig.Action != SignalBuy && sig.Action != SignalShort) {
+		return sig
+	}
+	tf, _ := ParseTimeframe(s.p.HTFFilter)
+	if !s.htfAllows(sig.Action, ctx.Higher(tf)) {
+		return nil
+	}
+	return sig
+}
+
+// HigherTimeframes returns the htf_filter timeframe, if set.
+func (s *TsaiSenStrategy) HigherTimeframes() []Timeframe {
+	if s.p.HTFFilter == "" {
+		return nil
+	}
+	tf, _ := ParseTimeframe(s.p.HTFFilter)
+	return []Timeframe{tf}
+}
+
+// htfAllows reports whether the last completed higher-timeframe bar of htf
+// closes above its box support (buy) or below its box resistance (short).
+func (s *TsaiSenStrategy) htfAllows(action SignalAction, htf []Bar) bool {
+	if len(htf) < 2 {
+		return false
+	}
+	p := s.p
+	p.BoxLookback = p.HTFLookback
+	last := len(htf) - 1
+	support, resist := levels(htf, last, p)
+	if action == SignalShort {
+		return resist > 0 && htf[last].Close < resist
+	}
+	return support > 0 && htf[last].Close > support
+}
+
 func (s *TsaiSenStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
 	if i <= 0 || i < s.p.BoxLookback {
 		return nil
@@ -181,7 +317,7 @@ func (s *TsaiSenStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
 				if s.p.EntryMode == "reclaim_support" {
 					s.lastPlan = s.planLong(bar.Time)
 					s.resetBreak()
-					return &Signal{Time: bar.Time, Action: SignalBuy, Reason: "break_bottom_flip_reclaim_support"}
+					return withLevels(&Signal{Time: bar.Time, Action: SignalBuy, Reason: "break_bottom_flip_reclaim_support"}, s.lastPlan.stop, s.lastPlan.target)
 				}
 			}
 		}
@@ -193,7 +329,7 @@ func (s *TsaiSenStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
 				s.flipReady = false
 				s.lastPlan = s.planLong(bar.Time)
 				s.resetBreak()
-				return &Signal{Time: bar.Time, Action: SignalBuy, Reason: "break_bottom_flip_stabilize_support"}
+				return withLevels(&Signal{Time: bar.Time, Action: SignalBuy, Reason: "break_bottom_flip_stabilize_support"}, s.lastPlan.stop, s.lastPlan.target)
 			}
 		}
 
@@ -204,7 +340,7 @@ func (s *TsaiSenStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
 				s.flipReady = false
 				s.lastPlan = s.planLong(bar.Time)
 				s.resetBreak()
-				return &Signal{Time: bar.Time, Action: SignalBuy, Reason: "break_bottom_flip_break_resistance"}
+				return withLevels(&Signal{Time: bar.Time, Action: SignalBuy, Reason: "break_bottom_flip_break_resistance"}, s.lastPlan.stop, s.lastPlan.target)
 			}
 		}
 
@@ -216,7 +352,7 @@ func (s *TsaiSenStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
 				if bar.Close < s.fakeResist*(1.0-s.p.ReclaimPct) {
 					s.fakeActive = false
 					s.lastPlan = s.planShort(bar.Time)
-					return &Signal{Time: bar.Time, Action: SignalShort, Reason: "fake_breakout_confirm"}
+					return withLevels(&Signal{Time: bar.Time, Action: SignalShort, Reason: "fake_breakout_confirm"}, s.lastPlan.stop, s.lastPlan.target)
 				}
 			}
 		}
@@ -299,24 +435,11 @@ func (s *TsaiSenStrategy) planShort(t time.Time) *tsaiSenPlan {
 }
 
 func boxLevels(bars []Bar, i int, lookback int) (support, resist float64) {
-	start := i - lookback
-	if start < 0 {
-		start = 0
-	}
-	lo := math.Inf(1)
-	hi := math.Inf(-1)
-	for j := start; j < i; j++ {
-		if bars[j].Low < lo {
-			lo = bars[j].Low
-		}
-		if bars[j].High > hi {
-			hi = bars[j].High
-		}
-	}
-	if !math.IsInf(lo, 0) && !math.IsInf(hi, 0) {
-		return lo, hi
+	hi, lo, ok := indicators.HighLow(bars, i-lookback, i)
+	if !ok {
+		return 0, 0
 	}
-	return 0, 0
+	return lo, hi
 }
 
 func levels(bars []Bar, i int, p TsaiSenParams) (support, resist float64) {
@@ -372,29 +495,11 @@ func collectPivots(bars []Bar, start, end, n int) (pivotLows []float64, pivotHig
 }
 
 func isPivotLow(bars []Bar, idx, n int) bool {
-	x := bars[idx].Low
-	for k := idx - n; k <= idx+n; k++ {
-		if k == idx {
-			continue
-		}
-		if bars[k].Low < x {
-			return false
-		}
-	}
-	return true
+	return indicators.PivotLow(bars, idx, n)
 }
 
 func isPivotHigh(bars []Bar, idx, n int) bool {
-	x := bars[idx].High
-	for k := idx - n; k <= idx+n; k++ {
-		if k == idx {
-			continue
-		}
-		if bars[k].High > x {
-			return false
-		}
-	}
-	return true
+	return indicators.PivotHigh(bars, idx, n)
 }
 
 func clusterLevel(values []float64, tolPct float64) (level float64, count int) {
@@ -442,24 +547,5 @@ func allClosesAbove(bars []Bar, i int, n int, level float64) bool {
 }
 
 func volMA(bars []Bar, i int, n int) float64 {
-	if n <= 0 {
-		return 0
-	}
-	start := i - n + 1
-	if start < 0 {
-		start = 0
-	}
-	sum := 0.0
-	cnt := 0
-	for j := start; j <= i; j++ {
-		if bars[j].Volume <= 0 {
-			continue
-		}
-		sum += float64(bars[j].Volume)
-		cnt++
-	}
-	if cnt == 0 {
-		return 0
-	}
-	return sum / float64(cnt)
+	return indicators.VolumeMA(bars, i, n)
 }
@@ -0,0 +1,145 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"stock/indicators"
+)
+
+// TurtleParams: Donchian channel breakout with Turtle-style ATR (N) stops and
+// sizing. Enter when the close breaks the high (low) of the previous EntryN
+// bars, exit when it breaks the opposite ExitN-bar channel or the stop at
+// StopATR * N from the entry. Units are not pyramided.
+type TurtleParams struct {
+	EntryN  int     `yaml:"entry_n" json:"entry_n"`
+	ExitN   int     `yaml:"exit_n" json:"exit_n"`
+	ATRN    int     `yaml:"atr_n" json:"atr_n"`
+	StopATR float64 `yaml:"stop_atr" json:"stop_atr"`
+	// RiskPct is the budget fraction lost on a 1 N move (Turtle unit: 1%),
+	// i.e. RiskPct * StopATR is lost at the stop; 0 invests the whole budget.
+	RiskPct float64 `yaml:"risk_pct" json:"risk_pct"`
+	// Short also trades downside breakouts (futures with allow_short only).
+	Short bool `yaml:"short" json:"short"`
+}
+
+func (p TurtleParams) withDefaults() TurtleParams {
+	if p.EntryN <= 0 {
+		p.EntryN = 20
+	}
+	if p.ExitN <= 0 {
+		p.ExitN = 10
+	}
+	if p.ATRN <= 0 {
+		p.ATRN = 20
+	}
+	if p.StopATR <= 0 {
+		p.StopATR = 2
+	}
+	return p
+}
+
+func (p TurtleParams) validate() error {
+	if p.EntryN > 500 || p.ExitN > 500 || p.ATRN > 500 {
+		return fmt.Errorf("strategy.params.entry_n/exit_n/atr_n out of range")
+	}
+	if p.StopATR > 20 {
+		return fmt.Errorf("strategy.params.stop_atr out of range")
+	}
+	if p.RiskPct < 0 || p.RiskPct > 0.2 {
+		return fmt.Errorf("strategy.params.risk_pct out of range (0~0.2)")
+	}
+	return nil
+}
+
+func init() {
+	RegisterStrategy(StrategyDef[TurtleParams]{
+		Name:     "turtle",
+		Defaults: TurtleParams.withDefaults,
+		Validate: TurtleParams.validate,
+		New:      func(p TurtleParams) Strategy { return NewTurtleStrategy(p) },
+	})
+}
+
+type TurtleStrategy struct {
+	p TurtleParams
+
+	computed barsKey
+	atr      []float64
+	lastPlan *tradePlan
+}
+
+func NewTurtleStrategy(p TurtleParams) *TurtleStrategy {
+	return &TurtleStrategy{p: p.withDefaults()}
+}
+
+func (s *TurtleStrategy) Clone() Strategy { return NewTurtleStrategy(s.p) }
+
+// Levels returns the entry channel (low, high of the EntryN bars before i).
+func (s *TurtleStrategy) Levels(bars []Bar, i int) (support, resistance float64) {
+	hi, lo, ok := indicators.HighLow(bars, i-s.p.EntryN, i)
+	if !ok {
+		return 0, 0
+	}
+	return lo, hi
+}
+
+// Plan returns the stop planned with the entry signal at t (no fixed target).
+func (s *TurtleStrategy) Plan(t time.Time) (stop, target float64, ok bool) {
+	if s.lastPlan == nil || !s.lastPlan.time.Equal(t) {
+		return 0, 0, false
+	}
+	return s.lastPlan.stop, 0, true
+}
+
+func (s *TurtleStrategy) OnBar(i int, bars []Bar, pos Position) *Signal {
+	if s.computed.update(bars) {
+		s.atr = indicators.ATRSeries(bars, s.p.ATRN)
+	}
+	bar := bars[i]
+	switch pos.Side {
+	case SideLong:
+		_, lo, ok := indicators.HighLow(bars, i-s.p.ExitN, i)
+		if ok && i >= s.p.ExitN && bar.Close < lo {
+			return &Signal{Time: bar.Time, Action: SignalSell, Reason: "turtle_exit"}
+		}
+		// close-based stop when intrabar exits are off
+		if pos.Stop > 0 && bar.Close <= pos.Stop {
+			return &Signal{Time: bar.Time, Action: SignalSell, Reason: "turtle_stop"}
+		}
+	case SideShort:
+		hi, _, ok := indicators.HighLow(bars, i-s.p.ExitN, i)
+		if ok && i >= s.p.ExitN && bar.Close > hi {
+			return &Signal{Time: bar.Time, Action: SignalCover, Reason: "turtle_exit"}
+		}
+		if pos.Stop > 0 && bar.Close >= pos.Stop {
+			return &Signal{Time: bar.Time, Action: SignalCover, Reason: "turtle_stop"}
+		}
+	case SideFlat:
+		n := s.atr[i]
+		if i < s.p.EntryN || !indicators.Valid(n) || n <= 0 {
+			return nil
+		}
+		hi, lo, _ := indicators.HighLow(bars, i-s.p.EntryN, i)
+		switch {
+		case bar.Close > hi:
+			return s.entry(bar, SideLong, bar.Close-s.p.StopATR*n)
+		case bar.Close < lo && s.p.Short:
+			return s.entry(bar, SideShort, bar.Close+s.p.StopATR*n)
+		}
+	}
+	return nil
+}
+
+func (s *TurtleStrategy) entry(bar Bar, side Side, stop float64) *Signal {
+	sig := &Signal{Time: bar.Time, Action: SignalBuy, Reason: "turtle_breakout_up"}
+	if side == SideShort {
+		sig.Action, sig.Reason = SignalShort, "turtle_breakout_down"
+	}
+	stop = math.Max(stop, 0)
+	s.lastPlan = &tradePlan{time: bar.Time, side: side, stop: stop, reason: sig.Reason}
+	sig = withLevels(sig, stop, 0)
+	sig.RiskPct = s.p.RiskPct * s.p.StopATR
+	return sig
+}
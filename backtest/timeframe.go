@@ -0,0 +1,196 @@
+package backtest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"stock/fetcher"
+)
+
+// Timeframe is a higher timeframe that daily bars are resampled to.
+type Timeframe string
+
+const (
+	TimeframeWeekly  Timeframe = "weekly"
+	TimeframeMonthly Timeframe = "monthly"
+)
+
+// ParseTimeframe parses weekly/monthly; empty stays empty (no timeframe).
+func ParseTimeframe(s string) (Timeframe, error) {
+	switch tf := Timeframe(strings.ToLower(strings.TrimSpace(s))); tf {
+	case "", TimeframeWeekly, TimeframeMonthly:
+		return tf, nil
+	default:
+		return "", fmt.Errorf("unknown timeframe: %s (weekly|monthly)", s)
+	}
+}
+
+// key identifies the period (ISO week or calendar month) containing t.
+func (tf Timeframe) key(t time.Time) int {
+	if tf == TimeframeMonthly {
+		return t.Year()*100 + int(t.Month())
+	}
+	y, w := t.ISOWeek()
+	return y*100 + w
+}
+
+// lastDay reports whether t falls on the last weekday of its period, so the
+// close of t completes the period. Holidays are unknown here: a period whose
+// last weekday is a holiday completes with the next period's first bar.
+func (tf Timeframe) lastDay(t time.Time) bool {
+	next := t.AddDate(0, 0, 1)
+	for next.Weekday() == time.Saturday || next.Weekday() == time.Sunday {
+		next = next.AddDate(0, 0, 1)
+	}
+	return tf.key(next) != tf.key(t)
+}
+
+// ResampleBars aggregates daily bars into tf bars: first open, highest high,
+// lowest low, last close, summed volume and last open interest. Each bar's
+// Time is that of its last daily bar; the final bar may be incomplete.
+func ResampleBars(bars []Bar, tf Timeframe) []Bar {
+	var out []Bar
+	last := 0
+	for k, b := range bars {
+		key := tf.key(b.Time)
+		if k == 0 || key != last {
+			out = append(out, b)
+			last = key
+			continue
+		}
+		cur := &out[len(out)-1]
+		cur.Time = b.Time
+		cur.High = max(cur.High, b.High)
+		cur.Low = min(cur.Low, b.Low)
+		cur.Close = b.Close
+		cur.Volume += b.Volume
+		cur.OpenInterest = b.OpenInterest
+	}
+	return out
+}
+
+// Timeframes holds weekly and monthly bars resampled from a daily series and,
+// per daily bar, how many of them are complete at its close.
+type Timeframes struct {
+	Weekly  []Bar
+	Monthly []Bar
+
+	weeklyDone  []int
+	monthlyDone []int
+}
+
+// NewTimeframes resamples daily bars to weekly and monthly bars.
+func NewTimeframes(bars []Bar) *Timeframes {
+	tf := &Timeframes{
+		Weekly:  ResampleBars(bars, TimeframeWeekly),
+		Monthly: ResampleBars(bars, TimeframeMonthly),
+	}
+	tf.weeklyDone = completedAt(bars, TimeframeWeekly)
+	tf.monthlyDone = completedAt(bars, TimeframeMonthly)
+	return tf
+}
+
+// completedAt counts, for each bar, the tf periods complete at its close: all
+// earlier periods, plus its own when it is the period's last weekday (and no
+// later bar falls in it).
+func completedAt(bars []Bar, tf Timeframe) []int {
+	out := make([]int, len(bars))
+	period := -1
+	last := 0
+	for k, b := range bars {
+		if key := tf.key(b.Time); k == 0 || key != last {
+			period++
+			last = key
+		}
+		out[k] = period
+		if tf.lastDay(b.Time) && (k+1 == len(bars) || tf.key(bars[k+1].Time) != last) {
+			out[k]++
+		}
+	}
+	return out
+}
+
+// At returns the weekly and monthly bars complete at the close of bars[i].
+func (tf *Timeframes) At(i int) (weekly, monthly []Bar) {
+	return tf.Weekly[:tf.weeklyDone[i]:tf.weeklyDone[i]], tf.Monthly[:tf.monthlyDone[i]:tf.monthlyDone[i]]
+}
+
+// BarContext is what a ContextStrategy sees at the close of Bars[I].
+type BarContext struct {
+	I    int
+	Bars []Bar
+	// Weekly / Monthly hold only the higher-timeframe bars completed by the
+	// close of Bars[I] (daily runs only; nil for intraday frequencies).
+	Weekly  []Bar
+	Monthly []Bar
+}
+
+// Higher returns the complete bars of timeframe t in ctx (nil for an unknown t).
+func (ctx BarContext) Higher(t Timeframe) []Bar {
+	switch t {
+	case TimeframeWeekly:
+		return ctx.Weekly
+	case TimeframeMonthly:
+		return ctx.Monthly
+	}
+	return nil
+}
+
+// ContextStrategy is implemented by strategies that use higher-timeframe bars;
+// the engine calls OnBarContext instead of OnBar.
+type ContextStrategy interface {
+	Strategy
+	OnBarContext(ctx BarContext, pos Position) *Signal
+}
+
+// HigherTimeframeUser is implemented by strategies configured to read
+// higher-timeframe bars, which only daily runs provide.
+type HigherTimeframeUser interface {
+	HigherTimeframes() []Timeframe
+}
+
+// checkTimeframes rejects a strategy that needs higher-timeframe bars on an
+// intraday run, where it would never see any.
+func (cfg RunConfig) checkTimeframes(st Strategy) error {
+	u, ok := st.(HigherTimeframeUser)
+	if !ok || cfg.period() == fetcher.PeriodDaily {
+		return nil
+	}
+	if tfs := u.HigherTimeframes(); len(tfs) > 0 {
+		return fmt.Errorf("strategy needs %s bars, which are resampled from daily bars only (frequency %s)", tfs[0], cfg.period())
+	}
+	return nil
+}
+
+// timeframeFeed runs strategies on a bar series, resampling it once for
+// ContextStrategy implementations.
+type timeframeFeed struct {
+	intraday bool
+	key      barsKey
+	tf       *Timeframes
+}
+
+func newTimeframeFeed(cfg RunConfig) timeframeFeed {
+	return timeframeFeed{intraday: cfg.period() != fetcher.PeriodDaily}
+}
+
+func (f *timeframeFeed) context(i int, bars []Bar) BarContext {
+	ctx := BarContext{I: i, Bars: bars}
+	if f.intraday {
+		return ctx
+	}
+	if f.key.update(bars) {
+		f.tf = NewTimeframes(bars)
+	}
+	ctx.Weekly, ctx.Monthly = f.tf.At(i)
+	return ctx
+}
+
+// onBar calls st for bar i (OnBarContext when st wants the context).
+func (f *timeframeFeed) onBar(st Strategy, i int, bars []Bar, pos Position) *Signal {
+	if cs, ok := st.(ContextStrategy); ok {
+		return cs.OnBarContext(f.context(i, bars), pos)
+	}
+	return st.OnBar(i, bars, pos)
+}
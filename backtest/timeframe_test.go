@@ -0,0 +1,133 @@
+package backtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// weekdayBars returns one bar per listed date (2024-01-dd), close = day.
+func weekdayBars(days ...int) []Bar {
+	var bars []Bar
+	for _, d := range days {
+		c := float64(d)
+		bars = append(bars, Bar{Time: time.Date(2024, 1, d, 0, 0, 0, 0, time.Local), Open: c, High: c + 1, Low: c - 1, Close: c, Volume: 10})
+	}
+	return bars
+}
+
+func TestResampleBars(t *testing.T) {
+	bars := weekdayBars(2, 3, 4, 5, 8, 9)
+	w := ResampleBars(bars, TimeframeWeekly)
+	if len(w) != 2 {
+		t.Fatalf("weekly bars %d", len(w))
+	}
+	if w[0].Open != 2 || w[0].High != 6 || w[0].Low != 1 || w[0].Close != 5 || w[0].Volume != 40 || !w[0].Time.Equal(bars[3].Time) {
+		t.Fatalf("week 1 %+v", w[0])
+	}
+	if w[1].Open != 8 || w[1].Close != 9 || w[1].Volume != 20 {
+		t.Fatalf("week 2 %+v", w[1])
+	}
+	if m := ResampleBars(bars, TimeframeMonthly); len(m) != 1 || m[0].Close != 9 || m[0].Volume != 60 {
+		t.Fatalf("monthly %+v", m)
+	}
+	if _, err := ParseTimeframe("daily"); err == nil {
+		t.Fatal("daily is not a higher timeframe")
+	}
+}
+
+func TestTimeframesLookAhead(t *testing.T) {
+	// Fri 2024-01-12 is missing (holiday): week 2 completes with Mon 01-15.
+	bars := weekdayBars(1, 2, 3, 4, 5, 8, 9, 10, 11, 15, 31)
+	tf := NewTimeframes(bars)
+	want := []int{0, 0, 0, 0, 1, 1, 1, 1, 1, 2, 3}
+	for i, n := range want {
+		w, m := tf.At(i)
+		if len(w) != n {
+			t.Fatalf("bar %d (%s): %d weekly bars, want %d", i, bars[i].Time.Format("01-02"), len(w), n)
+		}
+		if n > 0 && w[n-1].Time.After(bars[i].Time) {
+			t.Fatalf("bar %d sees a future weekly bar", i)
+		}
+		// January completes only at its last weekday (Wed 31).
+		if wantM := map[bool]int{true: 1, false: 0}[i == len(bars)-1]; len(m) != wantM {
+			t.Fatalf("bar %d: %d monthly bars", i, len(m))
+		}
+	}
+	// week 1's bar stays complete when its last daily bar is cut off
+	if w, _ := NewTimeframes(bars[:4]).At(3); len(w) != 0 {
+		t.Fatalf("a partial week must not be visible: %+v", w)
+	}
+}
+
+// contextProbe records how many weekly bars it sees.
+type contextProbe struct{ seen []int }
+
+func (s *contextProbe) OnBar(i int, bars []Bar, pos Position) *Signal { return nil }
+func (s *contextProbe) Clone() Strategy                               { return &contextProbe{} }
+func (s *contextProbe) OnBarContext(ctx BarContext, pos Position) *Signal {
+	s.seen = append(s.seen, len(ctx.Weekly))
+	return nil
+}
+
+func TestSimulatorPassesContext(t *testing.T) {
+	bars := weekdayBars(1, 2, 3, 4, 5, 8, 9, 10, 11, 12, 15)
+	probe := &contextProbe{}
+	cfg := DefaultRunConfig()
+	cfg.Strategy = probe
+	sim := NewSimulator(Instrument{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}, bars, cfg)
+	sim.Run(func(Snapshot) {})
+	st := sim.Strategy().(*contextProbe)
+	want := []int{0, 0, 0, 0, 1, 1, 1, 1, 1, 2, 2}
+	if len(st.seen) != len(want) {
+		t.Fatalf("seen %v", st.seen)
+	}
+	for i := range want {
+		if st.seen[i] != want[i] {
+			t.Fatalf("seen %v, want %v", st.seen, want)
+		}
+	}
+}
+
+func TestTsaiSenHTFFilter(t *testing.T) {
+	if _, err := NewStrategy("tsai_sen", map[string]any{"htf_filter": "daily"}); err == nil {
+		t.Fatal("want htf_filter error")
+	}
+	st, err := NewStrategy("tsai_sen", map[string]any{"htf_filter": "weekly", "htf_lookback": 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := st.(*TsaiSenStrategy)
+	weekly := weekdayBars(5, 12, 19, 26)
+	if !s.htfAllows(SignalBuy, weekly) || s.htfAllows(SignalShort, weekly) {
+		t.Fatal("weekly close above support: buys only")
+	}
+	weekly[3].Close = 3 // below the lows of the previous weeks
+	if s.htfAllows(SignalBuy, weekly) || !s.htfAllows(SignalShort, weekly) {
+		t.Fatal("weekly close below support: shorts only")
+	}
+	if s.htfAllows(SignalBuy, weekly[:1]) {
+		t.Fatal("needs completed weekly bars")
+	}
+}
+
+func TestHTFFilterNeedsDailyBars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backtest.yaml")
+	load := func(freq string) error {
+		yml := "backtest:\n  frequency: " + freq + "\nstrategy:\n  type: composite\n  params:\n    strategies:\n" +
+			"      - type: ma_cross\n      - type: tsai_sen\n        params: { htf_filter: weekly }\n"
+		if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadRunConfig(path)
+		return err
+	}
+	if err := load("1d"); err != nil {
+		t.Fatalf("daily: %v", err)
+	}
+	if err := load("5m"); err == nil || !strings.Contains(err.Error(), "weekly") {
+		t.Fatalf("intraday htf_filter must be rejected, got %v", err)
+	}
+}
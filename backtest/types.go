@@ -1,6 +1,10 @@
 package backtest
 
-import "time"
+import (
+	"time"
+
+	"stock/model"
+)
 
 type InstrumentType string
 
@@ -17,14 +21,8 @@ const (
 	SideShort Side = "short"
 )
 
-type Bar struct {
-	Time   time.Time
-	Open   float64
-	High   float64
-	Low    float64
-	Close  float64
-	Volume int64
-}
+// Bar is one OHLCV bar; shared with the indicators package.
+type Bar = model.Bar
 
 type Instrument struct {
 	Symbol     string
@@ -32,6 +30,21 @@ type Instrument struct {
 	LotSize    int64   // stock only (default 100)
 	Multiplier float64 // futures only (default 1)
 	AllowShort bool    // futures only
+
+	// Futures contract spec (see ContractSpecs); zero values fall back to the run config.
+	Exchange         string
+	TickSize         float64
+	MarginRate       float64
+	CommissionPerLot float64
+	CommissionBps    float64
+	CloseTodayRatio  *float64 // nil = same fee as closing an older position
+}
+
+func (inst Instrument) closeTodayRatio() float64 {
+	if inst.CloseTodayRatio == nil {
+		return 1
+	}
+	return *inst.CloseTodayRatio
 }
 
 type SignalAction string
@@ -47,6 +60,15 @@ type Signal struct {
 	Time   time.Time
 	Action SignalAction
 	Reason string
+
+	// Protective levels for entry signals (0 = none); the engine fills them
+	// intrabar once the position is open (see protectiveExit).
+	Stop   float64
+	Target float64
+
+	// RiskPct sizes an entry with a Stop so that a fill-to-stop loss is
+	// RiskPct of the position budget, capped by the budget (0 = whole budget).
+	RiskPct float64
 }
 
 type Position struct {
@@ -55,7 +77,12 @@ type Position struct {
 	EntryTime  time.Time
 	EntryPrice float64
 	EntryFee   float64
+	EntryFees  Fees
 	Margin     float64
+
+	// Protective stop/target carried over from the entry signal.
+	Stop   float64
+	Target float64
 }
 
 type Trade struct {
@@ -71,4 +98,24 @@ type Trade struct {
 	ReturnPct   float64 `json:"return_pct"`
 	ReasonEntry string  `json:"reason_entry"`
 	ReasonExit  string  `json:"reason_exit"`
+	// Fees is the entry + exit cost breakdown (NetPnL = GrossPnL - Fees.Total).
+	Fees Fees `json:"fees"`
+}
+
+// barsKey identifies the bar series a strategy precomputed indicator series
+// for. Strategies are cloned per run and see the same slice on every OnBar,
+// so indicators are computed once; they only look back, so value i is what
+// was known at the close of bar i.
+type barsKey struct {
+	first *Bar
+	n     int
+}
+
+// update reports whether bars differ from the series seen last and records them.
+func (k *barsKey) update(bars []Bar) bool {
+	if len(bars) == 0 || (k.first == &bars[0] && k.n == len(bars)) {
+		return false
+	}
+	k.first, k.n = &bars[0], len(bars)
+	return true
 }
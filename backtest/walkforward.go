@@ -0,0 +1,315 @@
+package backtest
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"stock/fetcher"
+)
+
+// WalkForwardSpec splits the history into rolling in-sample / out-of-sample
+// windows (the `walk_forward` section). Lengths are calendar days.
+type WalkForwardSpec struct {
+	InSampleDays    int
+	OutOfSampleDays int
+	// StepDays shifts consecutive windows (default OutOfSampleDays, so the
+	// out-of-sample windows tile the history without overlap).
+	StepDays int
+	// Anchored keeps every in-sample window starting at the first bar (expanding).
+	Anchored bool
+	// WarmupDays of bars before each window only feed the strategy (see RunConfig.TradeFrom).
+	WarmupDays int
+}
+
+// DefaultWalkForwardSpec: two years in-sample, half a year out-of-sample.
+func DefaultWalkForwardSpec() WalkForwardSpec {
+	return WalkForwardSpec{InSampleDays: 730, OutOfSampleDays: 182, WarmupDays: 365}
+}
+
+type walkForwardYAML struct {
+	WalkForward struct {
+		InSampleDays    int  `yaml:"in_sample_days"`
+		OutOfSampleDays int  `yaml:"out_of_sample_days"`
+		StepDays        int  `yaml:"step_days"`
+		Anchored        bool `yaml:"anchored"`
+		WarmupDays      *int `yaml:"warmup_days"`
+	} `yaml:"walk_forward"`
+}
+
+// LoadWalkForwardSpec reads the `walk_forward` section of a YAML file; missing
+// keys keep DefaultWalkForwardSpec.
+func LoadWalkForwardSpec(path string) (WalkForwardSpec, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return WalkForwardSpec{}, fmt.Errorf("read walk-forward spec: %w", err)
+	}
+	var y walkForwardYAML
+	if err := yaml.Unmarshal(raw, &y); err != nil {
+		return WalkForwardSpec{}, fmt.Errorf("parse yaml: %w", err)
+	}
+	spec := DefaultWalkForwardSpec()
+	w := y.WalkForward
+	if w.InSampleDays > 0 {
+		spec.InSampleDays = w.InSampleDays
+	}
+	if w.OutOfSampleDays > 0 {
+		spec.OutOfSampleDays = w.OutOfSampleDays
+	}
+	spec.StepDays = w.StepDays
+	spec.Anchored = w.Anchored
+	if w.WarmupDays != nil {
+		spec.WarmupDays = *w.WarmupDays
+	}
+	return spec, spec.validate()
+}
+
+func (s WalkForwardSpec) validate() error {
+	if s.InSampleDays <= 0 || s.OutOfSampleDays <= 0 || s.StepDays < 0 || s.WarmupDays < 0 {
+		return fmt.Errorf("walk_forward: window lengths must be > 0 (step/warmup >= 0)")
+	}
+	return nil
+}
+
+func (s WalkForwardSpec) step() int {
+	if s.StepDays > 0 {
+		return s.StepDays
+	}
+	return s.OutOfSampleDays
+}
+
+// WalkForwardWindow is one in-sample optimization and its out-of-sample run.
+// Dates are inclusive.
+type WalkForwardWindow struct {
+	InSampleStart    string         `json:"in_sample_start"`
+	InSampleEnd      string         `json:"in_sample_end"`
+	OutOfSampleStart string         `json:"out_of_sample_start"`
+	OutOfSampleEnd   string         `json:"out_of_sample_end"`
+	Params           map[string]any `json:"params,omitempty"`
+	// InSample is the best in-sample trial; OutOfSample the same params traded
+	// on the following window, scored with the same objective.
+	InSample    OptimizeTrial `json:"in_sample"`
+	OutOfSample OptimizeTrial `json:"out_of_sample"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// WalkForwardReport holds the windows and the stitched out-of-sample results.
+type WalkForwardReport struct {
+	Objective Objective           `json:"objective"`
+	Windows   []WalkForwardWindow `json:"windows"`
+	// Results are the out-of-sample runs stitched per instrument (one portfolio
+	// result in portfolio mode): each window restarts from initial_cash and its
+	// equity curve is compounded onto the previous window's final equity.
+	Results []Result `json:"results"`
+	// Efficiency is the mean out-of-sample score / mean in-sample score.
+	Efficiency float64 `json:"efficiency"`
+}
+
+type walkWindow struct {
+	isStart, oosStart, oosEnd time.Time // oosEnd exclusive
+}
+
+// WalkForward optimizes spec on each in-sample window and runs the winning
+// params unchanged on the following out-of-sample window.
+func (r *Runner) WalkForward(cfg RunConfig, spec OptimizeSpec, wf WalkForwardSpec) (WalkForwardReport, error) {
+	rep := WalkForwardReport{Objective: spec.Objective}
+	if err := wf.validate(); err != nil {
+		return rep, err
+	}
+	if len(cfg.Instruments) == 0 {
+		return rep, fmt.Errorf("no instruments configured")
+	}
+	runner := r.memoized()
+	cfg.Benchmark = nil
+
+	first, last, err := runner.historySpan(cfg)
+	if err != nil {
+		return rep, err
+	}
+	windows := wf.windows(first, last)
+	if len(windows) == 0 {
+		return rep, fmt.Errorf("history %s ~ %s is shorter than one in-sample + out-of-sample window",
+			first.Format(fetcher.DateLayout), last.Format(fetcher.DateLayout))
+	}
+
+	stitched := map[string]*Result{}
+	var order []string
+	var isSum, oosSum float64
+	scored := 0
+	for _, w := range windows {
+		win := WalkForwardWindow{
+			InSampleStart:    w.isStart.Format(fetcher.DateLayout),
+			InSampleEnd:      w.oosStart.AddDate(0, 0, -1).Format(fetcher.DateLayout),
+			OutOfSampleStart: w.oosStart.Format(fetcher.DateLayout),
+			OutOfSampleEnd:   w.oosEnd.AddDate(0, 0, -1).Format(fetcher.DateLayout),
+		}
+
+		trials, err := runner.Optimize(wf.slice(cfg, w.isStart, w.oosStart), spec)
+		if err != nil {
+			return rep, err
+		}
+		if len(trials) == 0 || trials[0].Error != "" {
+			win.Error = "in-sample: " + FirstTrialError(trials)
+			rep.Windows = append(rep.Windows, win)
+			continue
+		}
+		win.InSample = trials[0]
+		win.Params = trials[0].Params
+
+		oos := wf.slice(cfg, w.oosStart, w.oosEnd)
+		oos.StrategyParams = mergeParams(cfg.StrategyParams, win.Params)
+		if oos.Strategy, err = NewStrategy(cfg.StrategyType, oos.StrategyParams); err != nil {
+			return rep, err
+		}
+		results, err := runner.runAll(oos)
+		if err != nil {
+			win.Error = "out-of-sample: " + err.Error()
+			rep.Windows = append(rep.Windows, win)
+			continue
+		}
+		win.OutOfSample = scoreResults(results, cfg.InitialCash, spec.Objective)
+		win.OutOfSample.Params = win.Params
+		if win.OutOfSample.Error == "" {
+			isSum += win.InSample.Score
+			oosSum += win.OutOfSample.Score
+			scored++
+		}
+		for _, res := range results {
+			if len(res.Errors) > 0 || len(res.EquityCurve) == 0 {
+				continue
+			}
+			st, ok := stitched[res.Symbol]
+			if !ok {
+				st = &Result{Symbol: res.Symbol, Instrument: res.Instrument, Adjust: res.Adjust, FinalEquity: cfg.InitialCash}
+				stitched[res.Symbol] = st
+				order = append(order, res.Symbol)
+			}
+			stitch(st, res, cfg.InitialCash)
+		}
+		rep.Windows = append(rep.Windows, win)
+	}
+
+	for _, sym := range order {
+		st := stitched[sym]
+		st.finish(cfg.InitialCash)
+		rep.Results = append(rep.Results, *st)
+	}
+	if scored > 0 && isSum != 0 {
+		rep.Efficiency = round2(oosSum / isSum)
+	}
+	return rep, nil
+}
+
+// historySpan returns the first and last bar time over all instruments.
+func (r *Runner) historySpan(cfg RunConfig) (time.Time, time.Time, error) {
+	var first, last time.Time
+	for _, inst := range cfg.Instruments {
+		bars, _, err := r.loadSeries(inst, cfg)
+		if err != nil {
+			continue
+		}
+		if first.IsZero() || bars[0].Time.Before(first) {
+			first = bars[0].Time
+		}
+		if t := bars[len(bars)-1].Time; t.After(last) {
+			last = t
+		}
+	}
+	if first.IsZero() {
+		return first, last, fmt.Errorf("no instrument has enough bars")
+	}
+	return first, last, nil
+}
+
+func (wf WalkForwardSpec) windows(first, last time.Time) []walkWindow {
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()) }
+	start, end := day(first), day(last).AddDate(0, 0, 1)
+	var out []walkWindow
+	for k := 0; ; k++ {
+		isStart := start.AddDate(0, 0, k*wf.step())
+		oosStart := isStart.AddDate(0, 0, wf.InSampleDays)
+		if wf.Anchored {
+			isStart = start
+		}
+		if !oosStart.Before(end) {
+			return out
+		}
+		oosEnd := oosStart.AddDate(0, 0, wf.OutOfSampleDays)
+		if oosEnd.After(end) {
+			oosEnd = end
+		}
+		out = append(out, walkWindow{isStart: isStart, oosStart: oosStart, oosEnd: oosEnd})
+	}
+}
+
+// slice restricts cfg to trading in [from, to), with WarmupDays of bars before from.
+func (wf WalkForwardSpec) slice(cfg RunConfig, from, to time.Time) RunConfig {
+	cfg.Start = from.AddDate(0, 0, -wf.WarmupDays)
+	cfg.TradeFrom = from
+	cfg.End = to.AddDate(0, 0, -1)
+	return cfg
+}
+
+// stitch compounds res (started from initialCash) onto st. The P&L and fees
+// of its trades are scaled by the same factor, so the stitched trade P&L adds
+// up to the stitched equity; Qty stays as traded.
+func stitch(st *Result, res Result, initialCash float64) {
+	scale := 1.0
+	if initialCash > 0 {
+		scale = st.FinalEquity / initialCash
+	}
+	for _, p := range res.EquityCurve {
+		st.EquityCurve = append(st.EquityCurve, Point{Time: p.Time, Equity: round2(p.Equity * scale)})
+	}
+	st.FinalEquity = res.FinalEquity * scale
+	for _, t := range res.Trades {
+		t.GrossPnL = round2(t.GrossPnL * scale)
+		t.NetPnL = round2(t.NetPnL * scale)
+		t.Fees = t.Fees.scaled(scale)
+		st.Trades = append(st.Trades, t)
+	}
+	st.Rolls = append(st.Rolls, res.Rolls...)
+	st.Unfilled = append(st.Unfilled, res.Unfilled...)
+	st.SkippedEntries += res.SkippedEntries
+}
+
+// finish derives the summary fields of a stitched result from its curve and trades.
+func (st *Result) finish(initialCash float64) {
+	st.FinalEquity = round2(st.FinalEquity)
+	st.TotalTrades = len(st.Trades)
+	wins := 0
+	for _, t := range st.Trades {
+		if t.NetPnL > 0 {
+			wins++
+		}
+	}
+	if st.TotalTrades > 0 {
+		st.WinRatePct = round2(float64(wins) / float64(st.TotalTrades) * 100)
+	}
+	peak, maxDD := initialCash, 0.0
+	for _, p := range st.EquityCurve {
+		if p.Equity > peak {
+			peak = p.Equity
+		}
+		if peak > 0 {
+			if dd := (peak - p.Equity) / peak; dd > maxDD {
+				maxDD = dd
+			}
+		}
+	}
+	st.MaxDDPct = round2(maxDD * 100)
+	m := ComputeMetrics(st.EquityCurve, st.Trades, initialCash)
+	st.Metrics = &m
+}
+
+// FirstTrialError returns the error of the first failed trial.
+func FirstTrialError(trials []OptimizeTrial) string {
+	for _, t := range trials {
+		if t.Error != "" {
+			return t.Error
+		}
+	}
+	return "no trials"
+}
@@ -0,0 +1,122 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"stock/fetcher"
+)
+
+func TestWalkForwardWindows(t *testing.T) {
+	first := time.Date(2024, 1, 1, 9, 30, 0, 0, time.Local)
+	last := first.AddDate(0, 0, 499)
+	wf := WalkForwardSpec{InSampleDays: 200, OutOfSampleDays: 100}
+	ws := wf.windows(first, last)
+	if len(ws) != 3 {
+		t.Fatalf("windows %d", len(ws))
+	}
+	day0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	if !ws[1].isStart.Equal(day0.AddDate(0, 0, 100)) || !ws[1].oosStart.Equal(day0.AddDate(0, 0, 300)) {
+		t.Fatalf("window 1 %+v", ws[1])
+	}
+	if !ws[2].oosEnd.Equal(day0.AddDate(0, 0, 500)) {
+		t.Fatalf("last window must end after the last bar: %v", ws[2].oosEnd)
+	}
+
+	wf.Anchored = true
+	wf.StepDays = 150
+	ws = wf.windows(first, last)
+	if len(ws) != 2 || !ws[1].isStart.Equal(day0) || !ws[1].oosStart.Equal(day0.AddDate(0, 0, 350)) {
+		t.Fatalf("anchored windows %+v", ws)
+	}
+}
+
+func TestRunnerWalkForward(t *testing.T) {
+	src := fetcher.NewMemoryBarSource()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	var kl []fetcher.KLine
+	for i := 0; i < 500; i++ {
+		c := 10 + float64(i%20)*0.1 + float64(i)*0.002
+		kl = append(kl, fetcher.KLine{Date: start.AddDate(0, 0, i).Format("2006-01-02"), Open: c, High: c + 0.2, Low: c - 0.2, Close: c, Volume: 100})
+	}
+	src.Set("sh600000", kl)
+
+	cfg := DefaultRunConfig()
+	cfg.Days = 600
+	cfg.Instruments = []Instrument{{Symbol: "sh600000", Type: InstrumentTypeStock, LotSize: 100}}
+	cfg.StrategyParams = map[string]any{"vol_ma_n": 10}
+	spec := OptimizeSpec{
+		Mode:      SearchGrid,
+		Objective: ObjectiveNetPnL,
+		Params:    map[string]ParamRange{"box_lookback": {Values: []any{20, 40}}, "target_multiple": {Values: []any{1.0, 2.0}}},
+	}
+	wf := WalkForwardSpec{InSampleDays: 200, OutOfSampleDays: 100, WarmupDays: 60}
+	rep, err := NewRunnerWithSource(src).WalkForward(cfg, spec, wf)
+	if err != nil {
+		t.Fatalf("walk-forward: %v", err)
+	}
+	if len(rep.Windows) != 3 {
+		t.Fatalf("windows %d", len(rep.Windows))
+	}
+	for i, w := range rep.Windows {
+		if w.Error != "" || w.Params == nil {
+			t.Fatalf("window %d: %+v", i, w)
+		}
+	}
+	if rep.Windows[0].OutOfSampleStart != "2024-07-19" || rep.Windows[0].InSampleEnd != "2024-07-18" {
+		t.Fatalf("window 0 dates %+v", rep.Windows[0])
+	}
+	if len(rep.Results) != 1 {
+		t.Fatalf("results %d", len(rep.Results))
+	}
+	res := rep.Results[0]
+	oosStart := start.AddDate(0, 0, 200)
+	prev := time.Time{}
+	for _, p := range res.EquityCurve {
+		pt, err := fetcher.ParseKLineTime(p.Time, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// warm-up bars produce no equity points
+		if pt.Before(oosStart) || !pt.After(prev) {
+			t.Fatalf("curve point %s out of order / before out-of-sample", p.Time)
+		}
+		prev = pt
+	}
+	if len(res.EquityCurve) != 300 {
+		t.Fatalf("stitched curve has %d points, want 300", len(res.EquityCurve))
+	}
+	if last := res.EquityCurve[len(res.EquityCurve)-1].Equity; math.Abs(last-res.FinalEquity) > 0.05 {
+		t.Fatalf("final equity %v != last curve point %v", res.FinalEquity, last)
+	}
+	if res.Metrics == nil || res.TotalTrades != len(res.Trades) {
+		t.Fatalf("stitched summary %+v", res)
+	}
+}
+
+func TestStitchScalesTrades(t *testing.T) {
+	st := &Result{FinalEquity: 1000}
+	window := func(pnl float64) Result {
+		return Result{
+			EquityCurve: []Point{{Time: "t", Equity: 1000 + pnl}},
+			FinalEquity: 1000 + pnl,
+			Trades:      []Trade{{Qty: 10, GrossPnL: pnl + 1, NetPnL: pnl, Fees: Fees{Commission: 1, Total: 1}}},
+		}
+	}
+	stitch(st, window(100), 1000)
+	stitch(st, window(100), 1000) // compounds on 1100
+	if st.FinalEquity != 1210 {
+		t.Fatalf("final equity %v", st.FinalEquity)
+	}
+	net := 0.0
+	for _, tr := range st.Trades {
+		net += tr.NetPnL
+	}
+	if tr := st.Trades[1]; tr.Qty != 10 || tr.NetPnL != 110 || tr.GrossPnL != 111.1 || tr.Fees.Total != 1.1 {
+		t.Fatalf("second window trade %+v", tr)
+	}
+	if math.Abs(1000+net-st.FinalEquity) > 1e-9 {
+		t.Fatalf("trade P&L %v does not add up to the stitched equity %v", net, st.FinalEquity)
+	}
+}
@@ -0,0 +1,504 @@
+package barstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+
+	"stock/config"
+	"stock/fetcher"
+)
+
+// 导入文件格式
+const (
+	// FormatAuto 自动识别：JSONL / 带表头CSV（通用、Wind）/ 无表头（通达信）
+	FormatAuto = "auto"
+	// FormatTDX 通达信“数据导出”：日期,开盘,最高,最低,收盘,成交量[,成交额]，首尾的说明行会被跳过
+	FormatTDX = "tdx"
+	// FormatWind Wind 风格：中文表头（代码/日期/开盘价(元)/...），可包含多只证券
+	FormatWind = "wind"
+	// FormatGeneric 通用 OHLCV：表头 date,open,high,low,close,volume（可选 symbol）
+	FormatGeneric = "generic"
+	// FormatJSONL 每行一个 JSON 对象（stockctl data export -format jsonl 的输出）
+	FormatJSONL = "jsonl"
+	// FormatCSV 导出用：与 FormatGeneric 相同的列
+	FormatCSV = "csv"
+)
+
+// Series 一只标的的K线序列
+type Series struct {
+	Symbol string
+	Bars   []fetcher.KLine
+}
+
+// column 表头别名（小写，去掉括号内的单位）
+var columnAliases = map[string][]string{
+	"symbol": {"symbol", "code", "ts_code", "windcode", "代码", "证券代码", "合约代码"},
+	"date":   {"date", "datetime", "trade_date", "trade_time", "日期", "交易日期"},
+	"time":   {"time", "时间"},
+	"open":   {"open", "o", "开盘", "开盘价"},
+	"high":   {"high", "h", "最高", "最高价"},
+	"low":    {"low", "l", "最低", "最低价"},
+	"close":  {"close", "c", "收盘", "收盘价"},
+	"volume": {"volume", "vol", "v", "成交量"},
+}
+
+var unitSuffixRe = regexp.MustCompile(`[(（].*[)）]$`)
+
+// ReadBars 从 r 读取K线，format 为空或 auto 时自动识别。
+// 返回的序列按标的分组；文件中没有代码列时 Symbol 为空，由调用方补齐。
+func ReadBars(r io.Reader, format string) ([]Series, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	raw = bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(raw) {
+		// 通达信/Wind 在 Windows 上默认导出 GBK
+		if dec, _, err := transform.Bytes(simplifiedchinese.GBK.NewDecoder(), raw); err == nil {
+			raw = dec
+		}
+	}
+
+	f := strings.ToLower(strings.TrimSpace(format))
+	if f == "" || f == FormatAuto {
+		f = detectFormat(raw)
+	}
+
+	var series []Series
+	switch f {
+	case FormatJSONL:
+		series, err = readJSONL(raw)
+	case FormatTDX:
+		series, err = readTDX(raw)
+	case FormatWind, FormatGeneric, FormatCSV:
+		series, err = readHeaderCSV(raw)
+	default:
+		return nil, fmt.Errorf("未知的导入格式: %s (可选: auto, tdx, wind, generic, jsonl)", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range series {
+		series[i].Bars = dedupSorted(series[i].Bars)
+	}
+	return series, nil
+}
+
+func detectFormat(raw []byte) string {
+	for _, line := range splitLines(raw) {
+		if strings.HasPrefix(line, "{") {
+			return FormatJSONL
+		}
+		if _, ok := headerIndex(splitFields(line)); ok {
+			return FormatGeneric
+		}
+		if _, ok := parseDate(firstField(line)); ok {
+			return FormatTDX
+		}
+	}
+	return FormatTDX
+}
+
+type jsonlBar struct {
+	Symbol string `json:"symbol"`
+	fetcher.KLine
+}
+
+func readJSONL(raw []byte) ([]Series, error) {
+	g := newGrouper()
+	for n, line := range splitLines(raw) {
+		var jb jsonlBar
+		if err := json.Unmarshal([]byte(line), &jb); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		d, ok := parseDate(jb.Date)
+		if !ok {
+			return nil, fmt.Errorf("line %d: bad date %q", n+1, jb.Date)
+		}
+		jb.Date = d
+		g.add(jb.Symbol, jb.KLine)
+	}
+	return g.series(), nil
+}
+
+// readTDX 通达信导出：首行为“600000 浦发银行 日线 前复权”，次行为中文表头，末行为“数据来源:通达信”。
+// 分钟线导出多一列“时间”（如 0935），由表头识别。
+func readTDX(raw []byte) ([]Series, error) {
+	var symbol string
+	var bars []fetcher.KLine
+	hasTime := false
+	for _, line := range splitLines(raw) {
+		fields := splitFields(line)
+		d, ok := parseDate(fields[0])
+		if !ok {
+			if symbol == "" && len(bars) == 0 {
+				if f := strings.Fields(line); len(f) > 0 && isDigits(f[0]) {
+					symbol = f[0]
+				}
+			}
+			for _, f := range fields {
+				if f == "时间" {
+					hasTime = true
+				}
+			}
+			continue
+		}
+		if hasTime {
+			if d, ok = withTime(d, at(fields, 1)); !ok {
+				return nil, fmt.Errorf("%s: bad time %q", fields[0], at(fields, 1))
+			}
+			fields = fields[1:]
+		}
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("%s: expected date,open,high,low,close[,volume], got %d fields", d, len(fields))
+		}
+		k, err := parseOHLCV(d, fields[1], fields[2], fields[3], fields[4], at(fields, 5))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d, err)
+		}
+		bars = append(bars, k)
+	}
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no bars found")
+	}
+	if symbol != "" {
+		symbol = NormalizeSymbol(symbol)
+	}
+	return []Series{{Symbol: symbol, Bars: bars}}, nil
+}
+
+// readHeaderCSV 带表头的 CSV/TSV：通用 OHLCV 与 Wind 风格共用，按表头别名定位列。
+func readHeaderCSV(raw []byte) ([]Series, error) {
+	var idx map[string]int
+	var title string
+	g := newGrouper()
+	for _, line := range splitLines(raw) {
+		fields := splitFields(line)
+		if idx == nil {
+			if m, ok := headerIndex(fields); ok {
+				idx = m
+			} else if f := strings.Fields(line); title == "" && len(f) > 0 && isDigits(f[0]) {
+				// 通达信带表头导出的标题行
+				title = f[0]
+			}
+			continue
+		}
+		d, ok := parseDate(at(fields, idx["date"]))
+		if !ok {
+			// Wind 导出末尾的“数据来源：Wind”等说明行
+			continue
+		}
+		if i, ok := idx["time"]; ok && i != idx["date"] {
+			if d, ok = withTime(d, at(fields, i)); !ok {
+				return nil, fmt.Errorf("%s: bad time %q", d, at(fields, i))
+			}
+		}
+		vol := ""
+		if i, ok := idx["volume"]; ok {
+			vol = at(fields, i)
+		}
+		k, err := parseOHLCV(d, at(fields, idx["open"]), at(fields, idx["high"]), at(fields, idx["low"]), at(fields, idx["close"]), vol)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d, err)
+		}
+		sym := title
+		if i, ok := idx["symbol"]; ok {
+			sym = at(fields, i)
+		}
+		g.add(sym, k)
+	}
+	if idx == nil {
+		return nil, fmt.Errorf("header not found (need date/open/high/low/close columns)")
+	}
+	return g.series(), nil
+}
+
+func headerIndex(fields []string) (map[string]int, bool) {
+	idx := map[string]int{}
+	for i, f := range fields {
+		name := strings.ToLower(strings.TrimSpace(unitSuffixRe.ReplaceAllString(strings.TrimSpace(f), "")))
+		for col, aliases := range columnAliases {
+			if _, dup := idx[col]; dup {
+				continue
+			}
+			for _, a := range aliases {
+				if name == a {
+					idx[col] = i
+					break
+				}
+			}
+		}
+	}
+	if _, ok := idx["date"]; !ok {
+		// 只有“时间”一列时按完整时间戳处理
+		if i, ok := idx["time"]; ok {
+			idx["date"] = i
+		}
+	}
+	for _, col := range []string{"date", "open", "high", "low", "close"} {
+		if _, ok := idx[col]; !ok {
+			return nil, false
+		}
+	}
+	return idx, true
+}
+
+func parseOHLCV(date, open, high, low, cls, vol string) (fetcher.KLine, error) {
+	k := fetcher.KLine{Date: date}
+	var err error
+	if k.Open, err = parseNum(open); err != nil {
+		return k, fmt.Errorf("open: %w", err)
+	}
+	if k.High, err = parseNum(high); err != nil {
+		return k, fmt.Errorf("high: %w", err)
+	}
+	if k.Low, err = parseNum(low); err != nil {
+		return k, fmt.Errorf("low: %w", err)
+	}
+	if k.Close, err = parseNum(cls); err != nil {
+		return k, fmt.Errorf("close: %w", err)
+	}
+	if strings.TrimSpace(vol) != "" {
+		v, err := parseNum(vol)
+		if err != nil {
+			return k, fmt.Errorf("volume: %w", err)
+		}
+		k.Volume = int64(math.Round(v))
+	}
+	return k, nil
+}
+
+func parseNum(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	if s == "" || s == "--" {
+		return 0, fmt.Errorf("empty value")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+var dateLayouts = []string{"2006-01-02", "2006/01/02", "2006/1/2", "20060102", "2006-1-2", "2006.01.02"}
+
+// parseDate 识别常见日期写法，统一为 2006-01-02；带时间部分（非 00:00）时为 2006-01-02 15:04
+func parseDate(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	clock := ""
+	if i := strings.IndexAny(s, " T"); i > 0 {
+		s, clock = s[:i], strings.TrimSpace(s[i+1:])
+	}
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return "", false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			d := t.Format(fetcher.DateLayout)
+			if clock == "" {
+				return d, true
+			}
+			return withTime(d, clock)
+		}
+	}
+	return "", false
+}
+
+// withTime 在日期后拼上时间（15:04 / 15:04:05 / 0935 / 935），00:00 视为日线
+func withTime(date, clock string) (string, bool) {
+	clock = strings.TrimSpace(clock)
+	var hh, mm int
+	switch {
+	case strings.Contains(clock, ":"):
+		parts := strings.Split(clock, ":")
+		if len(parts) < 2 {
+			return "", false
+		}
+		var err1, err2 error
+		hh, err1 = strconv.Atoi(parts[0])
+		mm, err2 = strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil {
+			return "", false
+		}
+	case isDigits(clock) && len(clock) >= 3 && len(clock) <= 4:
+		n, _ := strconv.Atoi(clock)
+		hh, mm = n/100, n%100
+	default:
+		return "", false
+	}
+	if hh < 0 || hh > 23 || mm < 0 || mm > 59 {
+		return "", false
+	}
+	if hh == 0 && mm == 0 {
+		return date, true
+	}
+	return fmt.Sprintf("%s %02d:%02d", date, hh, mm), true
+}
+
+func splitLines(raw []byte) []string {
+	var out []string
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// splitFields 按 Tab / 逗号 / 分号 / 空白 切分一行（逗号分隔时支持引号）
+func splitFields(line string) []string {
+	var fields []string
+	switch {
+	case strings.Contains(line, "\t"):
+		fields = strings.Split(line, "\t")
+	case strings.Contains(line, ","):
+		r := csv.NewReader(strings.NewReader(line))
+		r.LazyQuotes = true
+		r.FieldsPerRecord = -1
+		if rec, err := r.Read(); err == nil {
+			fields = rec
+		} else {
+			fields = strings.Split(line, ",")
+		}
+	case strings.Contains(line, ";"):
+		fields = strings.Split(line, ";")
+	default:
+		fields = strings.Fields(line)
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+func firstField(line string) string {
+	return at(splitFields(line), 0)
+}
+
+func at(fields []string, i int) string {
+	if i < 0 || i >= len(fields) {
+		return ""
+	}
+	return fields[i]
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+type grouper struct {
+	order []string
+	bars  map[string][]fetcher.KLine
+}
+
+func newGrouper() *grouper {
+	return &grouper{bars: map[string][]fetcher.KLine{}}
+}
+
+func (g *grouper) add(symbol string, k fetcher.KLine) {
+	if symbol != "" {
+		symbol = NormalizeSymbol(symbol)
+	}
+	if _, ok := g.bars[symbol]; !ok {
+		g.order = append(g.order, symbol)
+	}
+	g.bars[symbol] = append(g.bars[symbol], k)
+}
+
+func (g *grouper) series() []Series {
+	out := make([]Series, 0, len(g.order))
+	for _, s := range g.order {
+		out = append(out, Series{Symbol: s, Bars: g.bars[s]})
+	}
+	return out
+}
+
+var (
+	windStockRe   = regexp.MustCompile(`^(?i)(\d{6})\.(SH|SZ|BJ)$`)
+	windFuturesRe = regexp.MustCompile(`^(?i)([A-Z]+\d*)\.(SHF|DCE|CZC|INE|GFE|CFE)$`)
+)
+
+// NormalizeSymbol 统一标的代码写法：
+// 600000.SH / SH#600000 / sh600000 / 600000 -> sh600000（见 config.NormalizeStockCode）；
+// RB2405.SHF / rb2405 -> nf_RB2405。
+func NormalizeSymbol(s string) string {
+	s = strings.TrimSpace(s)
+	if c := config.NormalizeStockCode(s); config.IsStockCode(c) {
+		return c
+	}
+	if m := windFuturesRe.FindStringSubmatch(s); m != nil {
+		s = m[1]
+	}
+	return config.NormalizeFuturesCode(s)
+}
+
+// SymbolFromPath 从文件名推断标的代码（如 SH#600000.txt、600000.SH.csv、nf_RB0.jsonl）
+func SymbolFromPath(path string) string {
+	base := filepath.Base(path)
+	if ext := filepath.Ext(base); ext != "" && !windStockRe.MatchString(base) && !windFuturesRe.MatchString(base) {
+		base = strings.TrimSuffix(base, ext)
+	}
+	return NormalizeSymbol(base)
+}
+
+// IsStockSymbol 是否为 A 股代码（sh/sz/bj 前缀）
+func IsStockSymbol(symbol string) bool {
+	return config.IsStockCode(strings.ToLower(strings.TrimSpace(symbol)))
+}
+
+// WriteBars 按 format（csv/jsonl）写出K线；csv 的列与 FormatGeneric 一致，可直接再导入。
+func WriteBars(w io.Writer, format string, series []Series) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatCSV, FormatGeneric:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"symbol", "date", "open", "high", "low", "close", "volume"}); err != nil {
+			return err
+		}
+		for _, s := range series {
+			for _, b := range s.Bars {
+				rec := []string{s.Symbol, b.Date, fmtNum(b.Open), fmtNum(b.High), fmtNum(b.Low), fmtNum(b.Close), strconv.FormatInt(b.Volume, 10)}
+				if err := cw.Write(rec); err != nil {
+					return err
+				}
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		for _, s := range series {
+			for _, b := range s.Bars {
+				if err := enc.Encode(jsonlBar{Symbol: s.Symbol, KLine: b}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("未知的导出格式: %s (可选: csv, jsonl)", format)
+	}
+}
+
+func fmtNum(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
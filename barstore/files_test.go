@@ -0,0 +1,165 @@
+package barstore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	"stock/fetcher"
+)
+
+func TestReadBarsTDX(t *testing.T) {
+	src := "600000 浦发银行 日线 前复权\r\n" +
+		"      日期\t    开盘\t    最高\t    最低\t    收盘\t    成交量\t    成交额\r\n" +
+		"2024/01/03\t6.60\t6.66\t6.56\t6.62\t31456700\t208456789.00\r\n" +
+		"2024/01/02\t6.55\t6.63\t6.51\t6.60\t29876500\t196543210.00\r\n" +
+		"数据来源:通达信\r\n"
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []string{FormatAuto, FormatTDX} {
+		series, err := ReadBars(strings.NewReader(gbk), format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(series) != 1 || series[0].Symbol != "sh600000" {
+			t.Fatalf("%s: unexpected series %+v", format, series)
+		}
+		want := []fetcher.KLine{
+			{Date: "2024-01-02", Open: 6.55, High: 6.63, Low: 6.51, Close: 6.60, Volume: 29876500},
+			{Date: "2024-01-03", Open: 6.60, High: 6.66, Low: 6.56, Close: 6.62, Volume: 31456700},
+		}
+		assertBars(t, series[0].Bars, want)
+	}
+}
+
+func TestReadBarsWind(t *testing.T) {
+	src := "代码,名称,日期,开盘价(元),最高价(元),最低价(元),收盘价(元),成交量(股),成交额(元)\n" +
+		"600000.SH,浦发银行,2024-01-02,6.55,6.63,6.51,6.60,\"29,876,500\",196543210\n" +
+		"000001.SZ,平安银行,2024-01-02,9.39,9.42,9.21,9.21,1.1581E+08,1071000000\n" +
+		"600000.SH,浦发银行,2024-01-03,6.60,6.66,6.56,6.62,31456700,208456789\n" +
+		"数据来源：Wind\n"
+	series, err := ReadBars(strings.NewReader(src), FormatWind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 2 || series[0].Symbol != "sh600000" || series[1].Symbol != "sz000001" {
+		t.Fatalf("unexpected series %+v", series)
+	}
+	if len(series[0].Bars) != 2 || series[0].Bars[0].Volume != 29876500 {
+		t.Fatalf("sh600000 bars = %+v", series[0].Bars)
+	}
+	if series[1].Bars[0].Volume != 115810000 {
+		t.Fatalf("sz000001 volume = %d", series[1].Bars[0].Volume)
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	in := []Series{{Symbol: "nf_RB0", Bars: []fetcher.KLine{
+		{Date: "2024-01-02", Open: 3900, High: 3950, Low: 3880, Close: 3921.5, Volume: 1200},
+		{Date: "2024-01-03", Open: 3921, High: 3930, Low: 3860, Close: 3870, Volume: 980},
+	}}}
+	for _, format := range []string{FormatCSV, FormatJSONL} {
+		var buf bytes.Buffer
+		if err := WriteBars(&buf, format, in); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		out, err := ReadBars(&buf, FormatAuto)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(out) != 1 || out[0].Symbol != "nf_RB0" {
+			t.Fatalf("%s: unexpected series %+v", format, out)
+		}
+		assertBars(t, out[0].Bars, in[0].Bars)
+	}
+}
+
+func TestNormalizeSymbol(t *testing.T) {
+	cases := map[string]string{
+		"600000.SH":  "sh600000",
+		"SZ#000001":  "sz000001",
+		"sh600000":   "sh600000",
+		"510300":     "sh510300",
+		"300750":     "sz300750",
+		"430047.BJ":  "bj430047",
+		"920001":     "bj920001",
+		"900901":     "sh900901",
+		"RB2405.SHF": "nf_RB2405",
+		"rb2405":     "nf_RB2405",
+	}
+	for in, want := range cases {
+		if got := NormalizeSymbol(in); got != want {
+			t.Errorf("NormalizeSymbol(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := SymbolFromPath("/data/SH#600000.txt"); got != "sh600000" {
+		t.Errorf("SymbolFromPath = %q", got)
+	}
+	if got := SymbolFromPath("600000.SH.csv"); got != "sh600000" {
+		t.Errorf("SymbolFromPath = %q", got)
+	}
+}
+
+func TestImportedSeriesIsNotOverwritten(t *testing.T) {
+	store := New(t.TempDir())
+	bars := dailyKLines(mustDate(t, "2024-01-02"), 60, func(i int) float64 { return 100 + float64(i) })
+	if _, err := store.Import(StockKey("600000.SH", fetcher.KLineOptions{}), bars); err != nil {
+		t.Fatal(err)
+	}
+
+	up := &countingSource{MemoryBarSource: fetcher.NewMemoryBarSource()}
+	kl, err := NewCachedSource(up, store).FetchStockKLine("sh600000", 500, fetcher.KLineOptions{})
+	if err != nil || len(kl) != 60 {
+		t.Fatalf("cached: len=%d err=%v", len(kl), err)
+	}
+	if len(up.calls) != 0 {
+		t.Fatalf("imported series should not hit upstream, got %v", up.calls)
+	}
+
+	kl, err = NewLocalSource(store).FetchStockKLine("sh600000", 20, fetcher.KLineOptions{})
+	if err != nil || len(kl) != 20 || kl[19].Close != 159 {
+		t.Fatalf("local: len=%d err=%v", len(kl), err)
+	}
+}
+
+func assertBars(t *testing.T, got, want []fetcher.KLine) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d bars, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("bar %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReadBarsTDXMinute(t *testing.T) {
+	src := "RB2405 螺纹2405 5分钟线 不复权\n" +
+		"日期\t时间\t开盘\t最高\t最低\t收盘\t成交量\t持仓量\t结算价\n" +
+		"2024/01/02\t0905\t3900\t3910\t3895\t3905\t1200\t100000\t0\n" +
+		"2024/01/02\t0910\t3905\t3912\t3901\t3911\t980\t100050\t0\n"
+	series, err := ReadBars(strings.NewReader(src), FormatTDX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || len(series[0].Bars) != 2 {
+		t.Fatalf("unexpected series %+v", series)
+	}
+	if b := series[0].Bars[1]; b.Date != "2024-01-02 09:10" || b.Close != 3911 || b.Volume != 980 {
+		t.Fatalf("bar = %+v", b)
+	}
+
+	generic := "datetime,open,high,low,close,volume\n2024-01-02 09:35:00,10,11,9,10.5,100\n"
+	series, err = ReadBars(strings.NewReader(generic), FormatAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := series[0].Bars[0].Date; d != "2024-01-02 09:35" {
+		t.Fatalf("date = %q", d)
+	}
+}
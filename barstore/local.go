@@ -0,0 +1,53 @@
+package barstore
+
+import (
+	"fmt"
+	"strings"
+
+	"stock/config"
+	"stock/fetcher"
+)
+
+// LocalBarSource 只读本地K线库的数据源名称（data.bar_source: local），回测/扫描完全离线
+const LocalBarSource = "local"
+
+func init() {
+	fetcher.RegisterBarSource(LocalBarSource, func(cfg config.DataConfig) (fetcher.BarSource, error) {
+		return NewLocalSource(New(cfg.StoreDir)), nil
+	})
+}
+
+// LocalSource 只从本地K线库读取，不访问网络
+type LocalSource struct {
+	store *Store
+}
+
+// NewLocalSource 创建本地K线数据源
+func NewLocalSource(store *Store) *LocalSource {
+	return &LocalSource{store: store}
+}
+
+// FetchStockKLine 读取股票K线（按 opt 选择对应周期/复权方式的序列）
+func (l *LocalSource) FetchStockKLine(code string, days int, opt fetcher.KLineOptions) ([]fetcher.KLine, error) {
+	return l.fetch(StockKey(code, opt), days)
+}
+
+// FetchFuturesKLine 读取期货K线
+func (l *LocalSource) FetchFuturesKLine(code string, days int, opt fetcher.KLineOptions) ([]fetcher.KLine, error) {
+	return l.fetch(FuturesKey(code, opt), days)
+}
+
+func (l *LocalSource) fetch(k Key, days int) ([]fetcher.KLine, error) {
+	kl, _, err := l.store.Load(k)
+	if err != nil {
+		return nil, err
+	}
+	if len(kl) == 0 {
+		return nil, fmt.Errorf("本地K线库无数据: %s（先用 stockctl data import 导入）", k)
+	}
+	return tail(kl, days), nil
+}
+
+func isLocal(name string) bool {
+	return strings.EqualFold(strings.TrimSpace(name), LocalBarSource)
+}
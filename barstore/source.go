@@ -0,0 +1,202 @@
+package barstore
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"stock/config"
+	"stock/fetcher"
+)
+
+// overlapBars 增量同步时与本地序列重叠的K线根数，用于校验复权因子是否变化
+const overlapBars = 3
+
+// CachedSource 带本地K线库的数据源：首次全量拉取，之后只拉取最后一根已存K线之后缺失的部分。
+type CachedSource struct {
+	upstream fetcher.BarSource
+	store    *Store
+	now      func() time.Time
+}
+
+// NewCachedSource 在 upstream 之上包一层本地K线库
+func NewCachedSource(upstream fetcher.BarSource, store *Store) *CachedSource {
+	return &CachedSource{upstream: upstream, store: store, now: time.Now}
+}
+
+// NewSourceFromConfig 按 data 配置创建K线数据源，并默认接入本地K线库（data.disable_cache 关闭）
+func NewSourceFromConfig(cfg config.DataConfig) (fetcher.BarSource, error) {
+	src, err := fetcher.NewBarSourceFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DisableCache || (isLocal(cfg.BarSource) && len(cfg.Sources) == 0) {
+		return src, nil
+	}
+	return NewCachedSource(src, New(cfg.StoreDir)), nil
+}
+
+// Store 返回底层K线库
+func (c *CachedSource) Store() *Store {
+	return c.store
+}
+
+// FetchStockKLine 获取股票K线（各周期/复权方式分开缓存）
+func (c *CachedSource) FetchStockKLine(code string, days int, opt fetcher.KLineOptions) ([]fetcher.KLine, error) {
+	return c.fetch(StockKey(code, opt), days, func(code string, days int) ([]fetcher.KLine, error) {
+		return c.upstream.FetchStockKLine(code, days, opt)
+	})
+}
+
+// FetchFuturesKLine 获取期货K线
+func (c *CachedSource) FetchFuturesKLine(code string, days int, opt fetcher.KLineOptions) ([]fetcher.KLine, error) {
+	return c.fetch(FuturesKey(code, opt), days, func(code string, days int) ([]fetcher.KLine, error) {
+		return c.upstream.FetchFuturesKLine(code, days, opt)
+	})
+}
+
+func (c *CachedSource) fetch(k Key, days int, pull func(code string, days int) ([]fetcher.KLine, error)) ([]fetcher.KLine, error) {
+	l := c.store.lock(k)
+	l.Lock()
+	defer l.Unlock()
+
+	stored, meta, err := c.store.Load(k)
+	if err != nil {
+		// 本地文件损坏：当作没有缓存，重新全量拉取
+		stored, meta = nil, Meta{}
+	}
+
+	now := c.now()
+	out := stored
+	switch {
+	case meta.Source == SourceImport && len(stored) > 0:
+		// 导入的数据以本地为准，不与上游同步
+	case len(stored) == 0 || meta.Depth < days:
+		out, err = c.full(k, days, pull, now)
+	case !c.fresh(k, meta, now):
+		out, err = c.incremental(k, days, stored, meta, pull, now)
+	}
+	if err != nil {
+		// 上游失败（限流/断网）时退回本地已有数据
+		if len(out) == 0 {
+			out = stored
+		}
+		if len(out) == 0 {
+			return nil, err
+		}
+	}
+	return tail(out, days), nil
+}
+
+func (c *CachedSource) full(k Key, days int, pull func(string, int) ([]fetcher.KLine, error), now time.Time) ([]fetcher.KLine, error) {
+	kl, err := pull(k.Symbol, days)
+	if err != nil {
+		return nil, err
+	}
+	kl = dedupSorted(kl)
+	if err := c.store.Save(k, kl, Meta{UpdatedAt: now, Depth: days}); err != nil {
+		return kl, err
+	}
+	return kl, nil
+}
+
+func (c *CachedSource) incremental(k Key, days int, stored []fetcher.KLine, meta Meta, pull func(string, int) ([]fetcher.KLine, error), now time.Time) ([]fetcher.KLine, error) {
+	last := stored[len(stored)-1]
+	lastDate, err := time.ParseInLocation("2006-01-02", dateOnly(last.Date), now.Location())
+	if err != nil {
+		return c.full(k, meta.Depth, pull, now)
+	}
+
+	need := weekdaysBetween(lastDate, now) + overlapBars
+	if k.Freq.IsIntraday() {
+		// 最后一根所在交易日可能只存了一部分
+		need = k.Freq.BarsForDays(weekdaysBetween(lastDate, now)+1) + overlapBars
+	}
+	kl, err := pull(k.Symbol, need)
+	if err != nil {
+		return stored, err
+	}
+	kl = dedupSorted(kl)
+
+	// 复权序列在除权除息后会整体变化：重叠部分对不上就全量重拉
+	// 上次同步当天（及之后）的K线可能是盘中未走完的，不参与校验
+	synced := meta.UpdatedAt.In(now.Location()).Format(fetcher.DateLayout)
+	byDate := make(map[string]fetcher.KLine, need)
+	for _, b := range stored[max(0, len(stored)-need):] {
+		if dateOnly(b.Date) < synced {
+			byDate[b.Date] = b
+		}
+	}
+	for _, b := range kl {
+		if old, ok := byDate[b.Date]; ok && !sameClose(old.Close, b.Close) {
+			depth := meta.Depth
+			if days > depth {
+				depth = days
+			}
+			return c.full(k, depth, pull, now)
+		}
+	}
+
+	merged := dedupSorted(append(stored, kl...))
+	meta.UpdatedAt = now
+	if err := c.store.Save(k, merged, meta); err != nil {
+		return merged, err
+	}
+	return merged, nil
+}
+
+// fresh 本地序列是否已是最新：最近一个已收盘的交易日收盘后已同步过。
+// 日线在盘中沿用上一交易日的数据（当日K线未走完）；分钟线盘中总是增量同步。
+func (c *CachedSource) fresh(k Key, meta Meta, now time.Time) bool {
+	return !meta.UpdatedAt.Before(lastClose(now, k.Freq.IsIntraday()))
+}
+
+// lastClose 最近一个已收盘交易日的同步时间点（跳过周末）；
+// 工作日收盘前，日线取上一个工作日，分钟线取当日。
+func lastClose(now time.Time, intraday bool) time.Time {
+	d := now
+	if !intraday && now.Before(closeTime(now)) {
+		d = d.AddDate(0, 0, -1)
+	}
+	for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		d = d.AddDate(0, 0, -1)
+	}
+	return closeTime(d)
+}
+
+// closeTime 当日收盘后的安全同步时间点
+func closeTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 15, 30, 0, 0, t.Location())
+}
+
+// weekdaysBetween 统计 (from, to] 之间的工作日数
+func weekdaysBetween(from, to time.Time) int {
+	n := 0
+	d := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, to.Location()).AddDate(0, 0, 1)
+	for !d.After(to) {
+		if wd := d.Weekday(); wd != time.Saturday && wd != time.Sunday {
+			n++
+		}
+		d = d.AddDate(0, 0, 1)
+	}
+	return n
+}
+
+func sameClose(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-6*math.Max(1, math.Abs(a))
+}
+
+func dateOnly(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 10 {
+		return s[:10]
+	}
+	return s
+}
+
+func tail(kl []fetcher.KLine, n int) []fetcher.KLine {
+	if n > 0 && len(kl) > n {
+		kl = kl[len(kl)-n:]
+	}
+	return append([]fetcher.KLine(nil), kl...)
+}
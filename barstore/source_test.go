@@ -0,0 +1,156 @@
+package barstore
+
+import (
+	"testing"
+	"time"
+
+	"stock/fetcher"
+)
+
+type countingSource struct {
+	*fetcher.MemoryBarSource
+	calls []int
+}
+
+func (c *countingSource) FetchStockKLine(code string, days int, opt fetcher.KLineOptions) ([]fetcher.KLine, error) {
+	c.calls = append(c.calls, days)
+	return c.MemoryBarSource.FetchStockKLine(code, days, opt)
+}
+
+func dailyKLines(start time.Time, n int, closeAt func(i int) float64) []fetcher.KLine {
+	var out []fetcher.KLine
+	for d := start; len(out) < n; d = d.AddDate(0, 0, 1) {
+		if wd := d.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			continue
+		}
+		c := closeAt(len(out))
+		out = append(out, fetcher.KLine{Date: d.Format("2006-01-02"), Open: c, High: c, Low: c, Close: c, Volume: 100})
+	}
+	return out
+}
+
+func TestCachedSourceIncrementalSync(t *testing.T) {
+	up := &countingSource{MemoryBarSource: fetcher.NewMemoryBarSource()}
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local) // Monday
+	all := dailyKLines(start, 30, func(i int) float64 { return 10 + float64(i) })
+
+	up.Set("sh600000", all[:20])
+	src := NewCachedSource(up, New(t.TempDir()))
+	src.now = func() time.Time { return time.Date(2025, 1, 31, 16, 0, 0, 0, time.Local) } // after close of bar #20
+
+	kl, err := src.FetchStockKLine("sh600000", 100, fetcher.KLineOptions{})
+	if err != nil || len(kl) != 20 {
+		t.Fatalf("initial fetch: len=%d err=%v", len(kl), err)
+	}
+	// Same day, after close: served from the store.
+	if _, err := src.FetchStockKLine("sh600000", 50, fetcher.KLineOptions{}); err != nil {
+		t.Fatalf("cached fetch: %v", err)
+	}
+	if len(up.calls) != 1 {
+		t.Fatalf("expected 1 upstream call, got %v", up.calls)
+	}
+
+	// A week later: only the missing bars (+ overlap) are pulled.
+	up.Set("sh600000", all[:25])
+	src.now = func() time.Time { return time.Date(2025, 2, 7, 16, 0, 0, 0, time.Local) }
+	kl, err = src.FetchStockKLine("sh600000", 100, fetcher.KLineOptions{})
+	if err != nil || len(kl) != 25 {
+		t.Fatalf("incremental fetch: len=%d err=%v", len(kl), err)
+	}
+	if got := up.calls[len(up.calls)-1]; got >= 20 {
+		t.Fatalf("expected a small incremental pull, got days=%d", got)
+	}
+	if kl[24].Date != all[24].Date {
+		t.Fatalf("last bar = %s, want %s", kl[24].Date, all[24].Date)
+	}
+}
+
+func TestCachedSourceNoPullDuringSession(t *testing.T) {
+	up := &countingSource{MemoryBarSource: fetcher.NewMemoryBarSource()}
+	up.Set("sh600000", dailyKLines(time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local), 20, func(i int) float64 { return 10 }))
+	src := NewCachedSource(up, New(t.TempDir()))
+	src.now = func() time.Time { return time.Date(2025, 2, 3, 9, 40, 0, 0, time.Local) } // Monday, before the close
+	if _, err := src.FetchStockKLine("sh600000", 100, fetcher.KLineOptions{}); err != nil {
+		t.Fatalf("initial fetch: %v", err)
+	}
+	// Later in the same session: Friday's close is the last completed session.
+	src.now = func() time.Time { return time.Date(2025, 2, 3, 14, 0, 0, 0, time.Local) }
+	if _, err := src.FetchStockKLine("sh600000", 100, fetcher.KLineOptions{}); err != nil {
+		t.Fatalf("cached fetch: %v", err)
+	}
+	if len(up.calls) != 1 {
+		t.Fatalf("expected no pull during the session, got %v", up.calls)
+	}
+	// After the close, today's bar is pulled.
+	src.now = func() time.Time { return time.Date(2025, 2, 3, 16, 0, 0, 0, time.Local) }
+	if _, err := src.FetchStockKLine("sh600000", 100, fetcher.KLineOptions{}); err != nil {
+		t.Fatalf("fetch after close: %v", err)
+	}
+	if len(up.calls) != 2 {
+		t.Fatalf("expected a pull after the close, got %v", up.calls)
+	}
+}
+
+func TestCachedSourceRefreshesPartialDailyBar(t *testing.T) {
+	up := &countingSource{MemoryBarSource: fetcher.NewMemoryBarSource()}
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local)
+	all := dailyKLines(start, 20, func(i int) float64 { return 10 + float64(i) })
+	partial := append([]fetcher.KLine(nil), all...)
+	partial[19].Close = 99 // 2025-01-31 mid-session
+
+	up.Set("sh600000", partial)
+	src := NewCachedSource(up, New(t.TempDir()))
+	src.now = func() time.Time { return time.Date(2025, 1, 31, 11, 0, 0, 0, time.Local) }
+	if _, err := src.FetchStockKLine("sh600000", 100, fetcher.KLineOptions{}); err != nil {
+		t.Fatalf("intraday fetch: %v", err)
+	}
+
+	// After the close the stored bar of today must not be served as final,
+	// and its changed close is not an adjustment change (no full refetch).
+	up.Set("sh600000", all)
+	src.now = func() time.Time { return time.Date(2025, 1, 31, 16, 0, 0, 0, time.Local) }
+	kl, err := src.FetchStockKLine("sh600000", 100, fetcher.KLineOptions{})
+	if err != nil || len(kl) != 20 {
+		t.Fatalf("fetch after close: len=%d err=%v", len(kl), err)
+	}
+	if kl[19].Close != all[19].Close {
+		t.Fatalf("last close = %v, want %v", kl[19].Close, all[19].Close)
+	}
+	if len(up.calls) != 2 || up.calls[1] >= 20 {
+		t.Fatalf("expected one small incremental pull, got %v", up.calls)
+	}
+}
+
+func TestCachedSourceRefetchOnAdjustmentChange(t *testing.T) {
+	up := &countingSource{MemoryBarSource: fetcher.NewMemoryBarSource()}
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local)
+	up.Set("sh600000", dailyKLines(start, 20, func(i int) float64 { return 10 + float64(i) }))
+	src := NewCachedSource(up, New(t.TempDir()))
+	src.now = func() time.Time { return time.Date(2025, 1, 31, 16, 0, 0, 0, time.Local) }
+	if _, err := src.FetchStockKLine("sh600000", 100, fetcher.KLineOptions{}); err != nil {
+		t.Fatalf("initial fetch: %v", err)
+	}
+
+	// Ex-dividend: the whole forward-adjusted history shifts.
+	up.Set("sh600000", dailyKLines(start, 25, func(i int) float64 { return 9 + float64(i) }))
+	src.now = func() time.Time { return time.Date(2025, 2, 7, 16, 0, 0, 0, time.Local) }
+	kl, err := src.FetchStockKLine("sh600000", 100, fetcher.KLineOptions{})
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(kl) != 25 || kl[0].Close != 9 {
+		t.Fatalf("expected full refetch, got len=%d first close=%v", len(kl), kl[0].Close)
+	}
+	if got := up.calls[len(up.calls)-1]; got != 100 {
+		t.Fatalf("expected full pull of 100 bars, got %d", got)
+	}
+}
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
@@ -0,0 +1,190 @@
+// Package barstore 本地K线库：按 标的/周期/复权方式 持久化到 runtime/ 目录，并支持增量同步。
+package barstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"stock/fetcher"
+)
+
+// DefaultDir 默认K线库目录
+var DefaultDir = filepath.Join("runtime", "bars")
+
+// Key 标识一条K线序列；不同周期、不同复权方式分开存储
+type Key struct {
+	Symbol string
+	Freq   fetcher.Period
+	Adjust fetcher.Adjust
+}
+
+// StockKey 股票K线序列（按 opt 的周期与复权方式）
+func StockKey(code string, opt fetcher.KLineOptions) Key {
+	return Key{Symbol: code, Freq: opt.KLinePeriod(), Adjust: opt.StockAdjust()}
+}
+
+// FuturesKey 期货K线序列（按 opt 的周期，不复权）
+func FuturesKey(code string, opt fetcher.KLineOptions) Key {
+	return Key{Symbol: code, Freq: opt.KLinePeriod(), Adjust: fetcher.AdjustNone}
+}
+
+func (k Key) String() string {
+	return k.Symbol + "/" + string(k.Freq) + "/" + string(k.Adjust)
+}
+
+// Meta 序列元数据
+type Meta struct {
+	// UpdatedAt 最近一次与上游同步的时间
+	UpdatedAt time.Time `json:"updated_at"`
+	// Depth 最近一次全量拉取请求的K线根数，请求更长历史时需要重新全量拉取
+	Depth int `json:"depth"`
+	// Source 数据来源；SourceImport 表示由 stockctl data import 导入，不再与上游同步
+	Source string `json:"source,omitempty"`
+}
+
+// SourceImport 离线导入的序列（自有复权数据等），以本地为准
+const SourceImport = "import"
+
+type fileData struct {
+	Meta
+	Bars []fetcher.KLine `json:"bars"`
+}
+
+// Store 本地K线库
+type Store struct {
+	dir string
+
+	mu    sync.Mutex
+	locks map[Key]*sync.Mutex
+}
+
+// New 创建K线库，dir 为空时使用 DefaultDir
+func New(dir string) *Store {
+	if strings.TrimSpace(dir) == "" {
+		dir = DefaultDir
+	}
+	return &Store{dir: dir, locks: map[Key]*sync.Mutex{}}
+}
+
+// Dir 返回K线库目录
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// lock 返回某条序列的互斥锁（同一序列的同步串行执行）
+func (s *Store) lock(k Key) *sync.Mutex {
+	k.Symbol = NormalizeSymbol(k.Symbol)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.locks[k]
+	if !ok {
+		m = &sync.Mutex{}
+		s.locks[k] = m
+	}
+	return m
+}
+
+func (s *Store) path(k Key) string {
+	return filepath.Join(s.dir, safeName(string(k.Freq)), safeName(string(k.Adjust)), safeName(NormalizeSymbol(k.Symbol))+".json")
+}
+
+// Import 合并导入的K线（同日期以导入数据为准），并把序列标记为 SourceImport
+func (s *Store) Import(k Key, bars []fetcher.KLine) (int, error) {
+	l := s.lock(k)
+	l.Lock()
+	defer l.Unlock()
+
+	stored, meta, err := s.Load(k)
+	if err != nil {
+		return 0, err
+	}
+	merged := dedupSorted(append(stored, bars...))
+	meta.Source = SourceImport
+	meta.UpdatedAt = time.Now()
+	if len(merged) > meta.Depth {
+		meta.Depth = len(merged)
+	}
+	if err := s.Save(k, merged, meta); err != nil {
+		return 0, err
+	}
+	return len(merged), nil
+}
+
+// Load 读取序列；不存在时返回空序列且不报错
+func (s *Store) Load(k Key) ([]fetcher.KLine, Meta, error) {
+	raw, err := os.ReadFile(s.path(k))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, Meta{}, nil
+		}
+		return nil, Meta{}, err
+	}
+	var fd fileData
+	if err := json.Unmarshal(raw, &fd); err != nil {
+		return nil, Meta{}, fmt.Errorf("parse %s: %w", s.path(k), err)
+	}
+	return fd.Bars, fd.Meta, nil
+}
+
+// Save 写入序列（按日期排序去重，原子替换文件）
+func (s *Store) Save(k Key, bars []fetcher.KLine, meta Meta) error {
+	p := s.path(k)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(fileData{Meta: meta, Bars: dedupSorted(bars)})
+	if err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// dedupSorted 按日期升序排序，同日期保留后出现的一条
+func dedupSorted(bars []fetcher.KLine) []fetcher.KLine {
+	if len(bars) == 0 {
+		return nil
+	}
+	idx := make(map[string]int, len(bars))
+	out := make([]fetcher.KLine, 0, len(bars))
+	for _, b := range bars {
+		if b.Date == "" {
+			continue
+		}
+		if i, ok := idx[b.Date]; ok {
+			out[i] = b
+			continue
+		}
+		idx[b.Date] = len(out)
+		out = append(out, b)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out
+}
+
+func safeName(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "_"
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' || r == '.' {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteByte('_')
+	}
+	return b.String()
+}
@@ -20,6 +20,9 @@ func main() {
 }
 
 func shouldRouteToCtl(args []string) bool {
+	if len(args) > 0 && (args[0] == "data" || args[0] == "optimize" || args[0] == "walkforward" || args[0] == "montecarlo") {
+		return true
+	}
 	for _, a := range args {
 		if a == "" {
 			continue
@@ -28,6 +28,28 @@ type YAMLConfig struct {
 		EnableAI     bool `yaml:"enable_ai"`
 		SyncInterval int  `yaml:"sync_interval"`
 	} `yaml:"server"`
+
+	Data DataConfig `yaml:"data"`
+}
+
+// DataConfig 数据源配置（实时行情 / 历史K线）
+type DataConfig struct {
+	// 实时行情数据源（默认 sina）
+	QuoteSource string `yaml:"quote_source"`
+	// 历史K线数据源（默认 default：股票东方财富 + 期货新浪）
+	BarSource string `yaml:"bar_source"`
+	// 按标的指定K线数据源，如 sh600000: local（只作用于K线，实时行情统一用 QuoteSource）
+	Sources map[string]string `yaml:"sources"`
+
+	// 本地K线库目录（默认 runtime/bars）
+	StoreDir string `yaml:"store_dir"`
+	// 关闭本地K线库（每次都从数据源全量拉取）
+	DisableCache bool `yaml:"disable_cache"`
+}
+
+// IsZero 是否未配置任何数据源
+func (d DataConfig) IsZero() bool {
+	return d.QuoteSource == "" && d.BarSource == "" && len(d.Sources) == 0 && d.StoreDir == "" && !d.DisableCache
 }
 
 // Config 配置
@@ -61,6 +83,9 @@ type Config struct {
 
 	// 是否启用AI分析
 	EnableAI bool
+
+	// 数据源配置
+	Data DataConfig
 }
 
 // DefaultConfig 默认配置
@@ -120,6 +145,41 @@ func normalizeFuturesCode(code string) string {
 	return c
 }
 
+var (
+	stockPrefixedRe = regexp.MustCompile(`^(?i)(sh|sz|bj)#?(\d{6})$`)
+	stockSuffixedRe = regexp.MustCompile(`^(?i)(\d{6})\.(sh|sz|bj)$`)
+	stockCodeRe     = regexp.MustCompile(`^(sh|sz|bj)\d{6}$`)
+)
+
+// NormalizeStockCode 统一A股代码格式为小写交易所前缀：
+// SH600000 / SH#600000 / 600000.SH / 600000 -> sh600000；其他写法原样返回（去空白）。
+// 纯 6 位数字按号段推断交易所：4/8/92 开头为北交所，5/6/9 开头为上交所，其余为深交所。
+func NormalizeStockCode(code string) string {
+	c := strings.TrimSpace(code)
+	if m := stockPrefixedRe.FindStringSubmatch(c); m != nil {
+		return strings.ToLower(m[1]) + m[2]
+	}
+	if m := stockSuffixedRe.FindStringSubmatch(c); m != nil {
+		return strings.ToLower(m[2]) + m[1]
+	}
+	if len(c) == 6 && strings.Trim(c, "0123456789") == "" {
+		switch {
+		case c[0] == '4' || c[0] == '8' || strings.HasPrefix(c, "92"):
+			return "bj" + c
+		case c[0] == '5' || c[0] == '6' || c[0] == '9':
+			return "sh" + c
+		default:
+			return "sz" + c
+		}
+	}
+	return c
+}
+
+// IsStockCode 是否为规范化后的A股代码（sh/sz/bj + 6 位数字）
+func IsStockCode(code string) bool {
+	return stockCodeRe.MatchString(code)
+}
+
 func normalizeFuturesCodes(codes []string) []string {
 	out := make([]string, 0, len(codes))
 	for _, code := range codes {
@@ -171,6 +231,9 @@ func LoadFromFile(path string) (*Config, error) {
 		config.RefreshInterval = time.Duration(yamlConfig.Server.SyncInterval) * time.Second
 	}
 
+	// 数据源配置
+	config.Data = yamlConfig.Data
+
 	return &config, nil
 }
 
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"stock/model"
 )
 
 // KLine K线数据
@@ -18,6 +21,202 @@ type KLine struct {
 	High   float64 `json:"high"`   // 最高价
 	Low    float64 `json:"low"`    // 最低价
 	Volume int64   `json:"volume"` // 成交量
+
+	OpenInterest int64 `json:"open_interest,omitempty"` // 持仓量（期货）
+}
+
+// Adjust 股票K线复权方式
+type Adjust string
+
+const (
+	// AdjustNone 不复权
+	AdjustNone Adjust = "none"
+	// AdjustForward 前复权（默认）：最新价格不变，历史价格按除权因子向下调整
+	AdjustForward Adjust = "forward"
+	// AdjustBackward 后复权：上市首日价格不变，之后价格向上累积，长周期回测不会出现负价格
+	AdjustBackward Adjust = "backward"
+)
+
+// ParseAdjust 解析复权方式，空值为前复权；同时接受 qfq/hfq/bfq 简写
+func ParseAdjust(s string) (Adjust, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "forward", "qfq":
+		return AdjustForward, nil
+	case "backward", "hfq":
+		return AdjustBackward, nil
+	case "none", "bfq":
+		return AdjustNone, nil
+	default:
+		return "", fmt.Errorf("未知的复权方式: %s (可选: none, forward, backward)", s)
+	}
+}
+
+// fqt 东方财富接口的复权参数：0 不复权 / 1 前复权 / 2 后复权
+func (a Adjust) fqt() int {
+	switch a {
+	case AdjustNone:
+		return 0
+	case AdjustBackward:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Period K线周期
+type Period string
+
+const (
+	// PeriodDaily 日线（默认）
+	PeriodDaily Period = "1d"
+	Period1Min  Period = "1m"
+	Period5Min  Period = "5m"
+	Period15Min Period = "15m"
+	Period30Min Period = "30m"
+	Period60Min Period = "60m"
+)
+
+const (
+	// DateLayout 日线 KLine.Date 格式
+	DateLayout = "2006-01-02"
+	// MinuteLayout 分钟线 KLine.Date 格式（K线结束时间）
+	MinuteLayout = "2006-01-02 15:04"
+)
+
+// ParsePeriod 解析K线周期，空值为日线；接受 1d/daily、1m/5m/15m/30m/60m（或 1/5/15/30/60、5min 等写法）
+func ParsePeriod(s string) (Period, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "min"), "m")
+	switch v {
+	case "", "1d", "d", "day", "daily":
+		return PeriodDaily, nil
+	case "1", "5", "15", "30", "60":
+		return Period(v + "m"), nil
+	case "1h", "h":
+		return Period60Min, nil
+	default:
+		return "", fmt.Errorf("未知的K线周期: %s (可选: 1d, 1m, 5m, 15m, 30m, 60m)", s)
+	}
+}
+
+// Minutes 分钟线的周期长度；日线返回 0
+func (p Period) Minutes() int {
+	switch p {
+	case Period1Min:
+		return 1
+	case Period5Min:
+		return 5
+	case Period15Min:
+		return 15
+	case Period30Min:
+		return 30
+	case Period60Min:
+		return 60
+	default:
+		return 0
+	}
+}
+
+// IsIntraday 是否为分钟线
+func (p Period) IsIntraday() bool {
+	return p.Minutes() > 0
+}
+
+// BarsForDays 估算 days 个交易日对应的K线根数（分钟线按每日约 6 小时交易时段估算，含期货夜盘余量）
+func (p Period) BarsForDays(days int) int {
+	if m := p.Minutes(); m > 0 {
+		return days * (360 / m)
+	}
+	return days
+}
+
+// Layout 该周期 KLine.Date 的时间格式
+func (p Period) Layout() string {
+	if p.IsIntraday() {
+		return MinuteLayout
+	}
+	return DateLayout
+}
+
+// FormatTime 按周期格式化K线时间（日线只保留日期）
+func (p Period) FormatTime(t time.Time) string {
+	return t.Format(p.Layout())
+}
+
+// ParseKLineTime 解析 KLine.Date：2006-01-02 / 2006-01-02 15:04 / 2006-01-02 15:04:05
+func ParseKLineTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{DateLayout, MinuteLayout, "2006-01-02 15:04:05"} {
+		if len(s) == len(layout) {
+			if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析K线时间: %q", s)
+}
+
+// KLinesToBars 把 KLine 转成按时间排序的 model.Bar；时间无法解析的行跳过
+func KLinesToBars(kl []KLine, loc *time.Location) []model.Bar {
+	bars := make([]model.Bar, 0, len(kl))
+	for _, k := range kl {
+		t, err := ParseKLineTime(k.Date, loc)
+		if err != nil {
+			continue
+		}
+		bars = append(bars, model.Bar{
+			Time:         t,
+			Open:         k.Open,
+			High:         k.High,
+			Low:          k.Low,
+			Close:        k.Close,
+			Volume:       k.Volume,
+			OpenInterest: k.OpenInterest,
+		})
+	}
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Time.Before(bars[j].Time) })
+	return bars
+}
+
+// normalizeKLineDate 分钟线时间统一到分钟（新浪返回 2006-01-02 15:04:05）
+func normalizeKLineDate(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) == len("2006-01-02 15:04:05") {
+		return s[:len(MinuteLayout)]
+	}
+	return s
+}
+
+// KLineOptions K线请求选项
+type KLineOptions struct {
+	// Adjust 复权方式，仅对股票生效；空值为前复权
+	Adjust Adjust
+	// Period K线周期；空值为日线
+	Period Period
+}
+
+// KLinePeriod 返回实际使用的K线周期（空值归一为日线）
+func (o KLineOptions) KLinePeriod() Period {
+	if o.Period == "" {
+		return PeriodDaily
+	}
+	return o.Period
+}
+
+// klt 东方财富接口的K线周期参数：1/5/15/30/60 分钟，101 日线
+func (p Period) klt() int {
+	if m := p.Minutes(); m > 0 {
+		return m
+	}
+	return 101
+}
+
+// StockAdjust 返回股票实际使用的复权方式（空值归一为前复权）
+func (o KLineOptions) StockAdjust() Adjust {
+	if o.Adjust == "" {
+		return AdjustForward
+	}
+	return o.Adjust
 }
 
 // KLineFetcher K线数据拉取器
@@ -34,19 +233,20 @@ func NewKLineFetcher() *KLineFetcher {
 	}
 }
 
-// FetchStockKLine 获取股票日K线数据
-// code: 股票代码（如 sh600000, sz000001）
-// days: 获取天数
-func (f *KLineFetcher) FetchStockKLine(code string, days int) ([]KLine, error) {
+// FetchStockKLine 获取股票K线数据
+// code: 股票代码（如 sh600000, sz000001, bj430047）
+// days: 获取K线根数
+// opt.Adjust: 复权方式（默认前复权）；opt.Period: K线周期（默认日线）
+func (f *KLineFetcher) FetchStockKLine(code string, days int, opt KLineOptions) ([]KLine, error) {
 	// 使用东方财富接口获取日K数据
-	// 转换代码格式: sh600000 -> 1.600000, sz000001 -> 0.000001
+	// 转换代码格式: sh600000 -> 1.600000, sz000001 -> 0.000001, bj430047 -> 0.430047
 	var secid string
 	if len(code) > 2 {
 		market := code[:2]
 		num := code[2:]
 		if market == "sh" {
 			secid = "1." + num
-		} else if market == "sz" {
+		} else if market == "sz" || market == "bj" {
 			secid = "0." + num
 		} else {
 			return nil, fmt.Errorf("未知的股票代码格式: %s", code)
@@ -56,8 +256,8 @@ func (f *KLineFetcher) FetchStockKLine(code string, days int) ([]KLine, error) {
 	}
 
 	url := fmt.Sprintf(
-		"https://push2his.eastmoney.com/api/qt/stock/kline/get?secid=%s&fields1=f1,f2,f3,f4,f5,f6&fields2=f51,f52,f53,f54,f55,f56,f57&klt=101&fqt=1&end=20500101&lmt=%d",
-		secid, days,
+		"https://push2his.eastmoney.com/api/qt/stock/kline/get?secid=%s&fields1=f1,f2,f3,f4,f5,f6&fields2=f51,f52,f53,f54,f55,f56,f57&klt=%d&fqt=%d&end=20500101&lmt=%d",
+		secid, opt.KLinePeriod().klt(), opt.StockAdjust().fqt(), days,
 	)
 
 	req, err := http.NewRequest("GET", url, nil)
@@ -108,7 +308,7 @@ func (f *KLineFetcher) parseStockKLine(data []byte) ([]KLine, error) {
 		volume, _ := strconv.ParseInt(parts[5], 10, 64)
 
 		k := KLine{
-			Date:   parts[0],
+			Date:   normalizeKLineDate(parts[0]),
 			Open:   open,
 			Close:  close,
 			High:   high,
@@ -121,10 +321,18 @@ func (f *KLineFetcher) parseStockKLine(data []byte) ([]KLine, error) {
 	return klines, nil
 }
 
-// FetchFuturesKLine 获取期货日K线数据
-// code: 期货代码（如 nf_AU0）
-// days: 获取天数
-func (f *KLineFetcher) FetchFuturesKLine(code string, days int) ([]KLine, error) {
+// FetchFuturesKLine 获取期货K线数据
+// code: 期货代码（如 nf_AU0；外盘 hf_CL 仅支持日线）
+// days: 获取K线根数
+// opt.Period: K线周期（默认日线）；期货不复权，opt.Adjust 被忽略
+func (f *KLineFetcher) FetchFuturesKLine(code string, days int, opt KLineOptions) ([]KLine, error) {
+	if IsGlobalFutures(code) {
+		if opt.KLinePeriod().IsIntraday() {
+			return nil, fmt.Errorf("外盘期货暂不支持分钟K线: %s", code)
+		}
+		return f.fetchGlobalFuturesKLine(code, days)
+	}
+
 	// 期货使用新浪接口
 	// nf_AU0 -> AU0
 	symbol := code
@@ -136,6 +344,12 @@ func (f *KLineFetcher) FetchFuturesKLine(code string, days int) ([]KLine, error)
 		"https://stock2.finance.sina.com.cn/futures/api/jsonp.php/var=/InnerFuturesNewService.getDailyKLine?symbol=%s&_=%d",
 		symbol, time.Now().UnixMilli(),
 	)
+	if p := opt.KLinePeriod(); p.IsIntraday() {
+		url = fmt.Sprintf(
+			"https://stock2.finance.sina.com.cn/futures/api/jsonp.php/var=/InnerFuturesNewService.getFewMinLine?symbol=%s&type=%d&_=%d",
+			symbol, p.Minutes(), time.Now().UnixMilli(),
+		)
+	}
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -166,6 +380,7 @@ type FuturesKLineData struct {
 	L string `json:"l"` // 最低
 	C string `json:"c"` // 收盘
 	V string `json:"v"` // 成交量
+	P string `json:"p"` // 持仓量
 }
 
 // parseFuturesKLine 解析期货K线数据
@@ -212,18 +427,107 @@ func (f *KLineFetcher) parseFuturesKLine(data []byte, days int) ([]KLine, error)
 		low, _ := strconv.ParseFloat(row.L, 64)
 		close, _ := strconv.ParseFloat(row.C, 64)
 		volume, _ := strconv.ParseInt(row.V, 10, 64)
+		oi, _ := strconv.ParseFloat(row.P, 64)
 
 		k := KLine{
-			Date:   row.D,
+			Date:         normalizeKLineDate(row.D),
+			Open:         open,
+			High:         high,
+			Low:          low,
+			Close:        close,
+			Volume:       volume,
+			OpenInterest: int64(oi),
+		}
+		klines = append(klines, k)
+	}
+
+	return klines, nil
+}
+
+// IsGlobalFutures 是否为外盘期货代码（hf_ 前缀，如 hf_CL 原油、hf_SI 白银）
+func IsGlobalFutures(code string) bool {
+	c := strings.TrimSpace(code)
+	return len(c) > 3 && strings.EqualFold(c[:3], "hf_")
+}
+
+// fetchGlobalFuturesKLine 获取外盘期货日K线（新浪 GlobalFuturesService）
+func (f *KLineFetcher) fetchGlobalFuturesKLine(code string, days int) ([]KLine, error) {
+	// hf_CL -> CL
+	symbol := strings.ToUpper(strings.TrimSpace(code)[3:])
+	now := time.Now()
+
+	url := fmt.Sprintf(
+		"https://stock2.finance.sina.com.cn/futures/api/jsonp.php/var=/GlobalFuturesService.getGlobalFuturesDailyKLine?symbol=%s&_=%d_%d_%d&source=web",
+		symbol, now.Year(), int(now.Month()), now.Day(),
+	)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	req.Header.Set("Referer", "https://finance.sina.com.cn/")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.parseGlobalFuturesKLine(body, days)
+}
+
+// GlobalFuturesKLineData 外盘期货K线数据结构
+type GlobalFuturesKLineData struct {
+	Date   string `json:"date"`
+	Open   string `json:"open"`
+	High   string `json:"high"`
+	Low    string `json:"low"`
+	Close  string `json:"close"`
+	Volume string `json:"volume"`
+}
+
+// parseGlobalFuturesKLine 解析外盘期货K线数据，响应格式: var=([{"date":"2024-01-02","open":"71.65",...},...]);
+func (f *KLineFetcher) parseGlobalFuturesKLine(data []byte, days int) ([]KLine, error) {
+	str := string(data)
+	start := strings.Index(str, "[")
+	end := strings.LastIndex(str, "]")
+	if start == -1 || end == -1 || start >= end {
+		return nil, fmt.Errorf("无法解析外盘期货K线数据")
+	}
+
+	var rawData []GlobalFuturesKLineData
+	if err := json.Unmarshal([]byte(str[start:end+1]), &rawData); err != nil {
+		return nil, err
+	}
+
+	// 只取最后 days 条
+	if days > 0 && len(rawData) > days {
+		rawData = rawData[len(rawData)-days:]
+	}
+
+	klines := make([]KLine, 0, len(rawData))
+	for _, row := range rawData {
+		open, _ := strconv.ParseFloat(row.Open, 64)
+		high, _ := strconv.ParseFloat(row.High, 64)
+		low, _ := strconv.ParseFloat(row.Low, 64)
+		close, _ := strconv.ParseFloat(row.Close, 64)
+		volume, _ := strconv.ParseFloat(row.Volume, 64)
+
+		klines = append(klines, KLine{
+			Date:   normalizeKLineDate(row.Date),
 			Open:   open,
 			High:   high,
 			Low:    low,
 			Close:  close,
-			Volume: volume,
-		}
-		klines = append(klines, k)
+			Volume: int64(volume),
+		})
 	}
 
 	return klines, nil
 }
-
@@ -0,0 +1,28 @@
+package fetcher
+
+import "testing"
+
+func TestParseGlobalFuturesKLine(t *testing.T) {
+	body := []byte(`var _S2024_1_4=([{"date":"2024-01-02","open":"71.65","high":"73.13","low":"69.90","close":"70.38","volume":"315233"},` +
+		`{"date":"2024-01-03","open":"70.40","high":"73.20","low":"70.08","close":"72.70","volume":"351006"}]);`)
+	kl, err := NewKLineFetcher().parseGlobalFuturesKLine(body, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kl) != 1 {
+		t.Fatalf("expected last 1 bar, got %d", len(kl))
+	}
+	want := KLine{Date: "2024-01-03", Open: 70.40, High: 73.20, Low: 70.08, Close: 72.70, Volume: 351006}
+	if kl[0] != want {
+		t.Fatalf("got %+v, want %+v", kl[0], want)
+	}
+}
+
+func TestGlobalFuturesRejectsIntraday(t *testing.T) {
+	if !IsGlobalFutures("HF_cl") || IsGlobalFutures("nf_RB0") {
+		t.Fatalf("IsGlobalFutures mismatch")
+	}
+	if _, err := NewKLineFetcher().FetchFuturesKLine("hf_CL", 10, KLineOptions{Period: Period5Min}); err == nil {
+		t.Fatalf("expected error for intraday hf_ bars")
+	}
+}
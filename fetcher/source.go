@@ -0,0 +1,263 @@
+package fetcher
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"stock/config"
+	"stock/model"
+)
+
+// QuoteSource 实时行情数据源
+type QuoteSource interface {
+	// FetchStocks 拉取多只股票的实时行情
+	FetchStocks(codes []string) ([]*model.StockQuote, error)
+	// FetchFutures 拉取多个期货合约的实时行情
+	FetchFutures(codes []string) ([]*model.FuturesQuote, error)
+}
+
+// BarSource 历史K线数据源
+type BarSource interface {
+	// FetchStockKLine 获取股票最近 days 根日K（按 opt.Adjust 复权）
+	FetchStockKLine(code string, days int, opt KLineOptions) ([]KLine, error)
+	// FetchFuturesKLine 获取期货最近 days 根日K
+	FetchFuturesKLine(code string, days int, opt KLineOptions) ([]KLine, error)
+}
+
+// QuoteSourceFactory 实时行情数据源构造函数
+type QuoteSourceFactory func(cfg config.DataConfig) (QuoteSource, error)
+
+// BarSourceFactory K线数据源构造函数
+type BarSourceFactory func(cfg config.DataConfig) (BarSource, error)
+
+const (
+	// DefaultQuoteSource 默认实时行情数据源（新浪）
+	DefaultQuoteSource = "sina"
+	// DefaultBarSource 默认K线数据源（股票东方财富 + 期货新浪）
+	DefaultBarSource = "default"
+)
+
+var (
+	registryMu     sync.RWMutex
+	quoteFactories = map[string]QuoteSourceFactory{}
+	barFactories   = map[string]BarSourceFactory{}
+)
+
+func init() {
+	RegisterQuoteSource(DefaultQuoteSource, func(config.DataConfig) (QuoteSource, error) {
+		return NewSinaQuoteSource(), nil
+	})
+	RegisterBarSource(DefaultBarSource, func(config.DataConfig) (BarSource, error) {
+		return NewKLineFetcher(), nil
+	})
+}
+
+// RegisterQuoteSource 注册实时行情数据源（同名覆盖）
+func RegisterQuoteSource(name string, f QuoteSourceFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	quoteFactories[normalizeSourceName(name)] = f
+}
+
+// RegisterBarSource 注册K线数据源（同名覆盖）
+func RegisterBarSource(name string, f BarSourceFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	barFactories[normalizeSourceName(name)] = f
+}
+
+// QuoteSourceNames 返回已注册的实时行情数据源名称
+func QuoteSourceNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(quoteFactories))
+	for n := range quoteFactories {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BarSourceNames 返回已注册的K线数据源名称
+func BarSourceNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(barFactories))
+	for n := range barFactories {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewQuoteSource 按名称创建实时行情数据源，name 为空时使用默认数据源。
+// 实时行情不按标的路由，data.sources 对它不起作用。
+func NewQuoteSource(name string, cfg config.DataConfig) (QuoteSource, error) {
+	n := normalizeSourceName(name)
+	if n == "" {
+		n = DefaultQuoteSource
+	}
+	registryMu.RLock()
+	f, ok := quoteFactories[n]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的行情数据源: %s (可选: %s)", name, strings.Join(QuoteSourceNames(), ", "))
+	}
+	return f(cfg)
+}
+
+// NewBarSource 按名称创建K线数据源，name 为空时使用默认数据源
+func NewBarSource(name string, cfg config.DataConfig) (BarSource, error) {
+	n := normalizeSourceName(name)
+	if n == "" {
+		n = DefaultBarSource
+	}
+	registryMu.RLock()
+	f, ok := barFactories[n]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的K线数据源: %s (可选: %s)", name, strings.Join(BarSourceNames(), ", "))
+	}
+	return f(cfg)
+}
+
+// NewBarSourceFromConfig 按 data 配置创建K线数据源：
+// data.bar_source 为默认数据源，data.sources 可按标的单独指定K线数据源。
+// data.sources 只作用于K线，写成实时行情数据源名（如 sina）会报错。
+func NewBarSourceFromConfig(cfg config.DataConfig) (BarSource, error) {
+	def, err := NewBarSource(cfg.BarSource, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Sources) == 0 {
+		return def, nil
+	}
+
+	byName := map[string]BarSource{normalizeSourceName(cfg.BarSource): def}
+	bySymbol := make(map[string]BarSource, len(cfg.Sources))
+	for sym, name := range cfg.Sources {
+		n := normalizeSourceName(name)
+		src, ok := byName[n]
+		if !ok {
+			if isQuoteOnly(n) {
+				return nil, fmt.Errorf("data.sources[%s]: %s 是实时行情数据源，data.sources 只按标的指定K线数据源，实时行情用 data.quote_source", sym, name)
+			}
+			src, err = NewBarSource(name, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("data.sources[%s]: %w", sym, err)
+			}
+			byName[n] = src
+		}
+		bySymbol[normalizeSymbol(sym)] = src
+	}
+	return &routedBarSource{def: def, bySymbol: bySymbol}, nil
+}
+
+// isQuoteOnly name 是否只注册为实时行情数据源
+func isQuoteOnly(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, quote := quoteFactories[name]
+	_, bar := barFactories[name]
+	return quote && !bar
+}
+
+// routedBarSource 按标的路由到不同的K线数据源
+type routedBarSource struct {
+	def      BarSource
+	bySymbol map[string]BarSource
+}
+
+func (r *routedBarSource) pick(code string) BarSource {
+	if src, ok := r.bySymbol[normalizeSymbol(code)]; ok {
+		return src
+	}
+	return r.def
+}
+
+func (r *routedBarSource) FetchStockKLine(code string, days int, opt KLineOptions) ([]KLine, error) {
+	return r.pick(code).FetchStockKLine(code, days, opt)
+}
+
+func (r *routedBarSource) FetchFuturesKLine(code string, days int, opt KLineOptions) ([]KLine, error) {
+	return r.pick(code).FetchFuturesKLine(code, days, opt)
+}
+
+// SinaQuoteSource 新浪实时行情数据源
+type SinaQuoteSource struct {
+	stock   *StockFetcher
+	futures *FuturesFetcher
+}
+
+// NewSinaQuoteSource 创建新浪实时行情数据源
+func NewSinaQuoteSource() *SinaQuoteSource {
+	return &SinaQuoteSource{
+		stock:   NewStockFetcher(),
+		futures: NewFuturesFetcher(),
+	}
+}
+
+// FetchStocks 拉取多只股票的实时行情
+func (s *SinaQuoteSource) FetchStocks(codes []string) ([]*model.StockQuote, error) {
+	return s.stock.Fetch(codes)
+}
+
+// FetchFutures 拉取多个期货合约的实时行情
+func (s *SinaQuoteSource) FetchFutures(codes []string) ([]*model.FuturesQuote, error) {
+	return s.futures.Fetch(codes)
+}
+
+// MemoryBarSource 内存K线数据源，用于测试/离线研究注入数据（不走 HTTP）
+type MemoryBarSource struct {
+	mu    sync.RWMutex
+	lines map[string][]KLine
+}
+
+// NewMemoryBarSource 创建内存K线数据源
+func NewMemoryBarSource() *MemoryBarSource {
+	return &MemoryBarSource{lines: map[string][]KLine{}}
+}
+
+// Set 设置某个标的的K线（按日期升序）
+func (m *MemoryBarSource) Set(code string, klines []KLine) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lines[normalizeSymbol(code)] = append([]KLine(nil), klines...)
+}
+
+// FetchStockKLine 返回最近 days 根K线（内存数据按原样返回，不区分复权方式）
+func (m *MemoryBarSource) FetchStockKLine(code string, days int, opt KLineOptions) ([]KLine, error) {
+	return m.fetch(code, days)
+}
+
+// FetchFuturesKLine 返回最近 days 根K线
+func (m *MemoryBarSource) FetchFuturesKLine(code string, days int, opt KLineOptions) ([]KLine, error) {
+	return m.fetch(code, days)
+}
+
+func (m *MemoryBarSource) fetch(code string, days int) ([]KLine, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	kl, ok := m.lines[normalizeSymbol(code)]
+	if !ok {
+		return nil, fmt.Errorf("无K线数据: %s", code)
+	}
+	if days > 0 && len(kl) > days {
+		kl = kl[len(kl)-days:]
+	}
+	return append([]KLine(nil), kl...), nil
+}
+
+func normalizeSourceName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// normalizeSymbol 统一标的代码写法：股票走 config.NormalizeStockCode（sh600000），期货走 config.NormalizeFuturesCode。
+func normalizeSymbol(code string) string {
+	if c := config.NormalizeStockCode(code); config.IsStockCode(c) {
+		return c
+	}
+	return config.NormalizeFuturesCode(strings.TrimSpace(code))
+}
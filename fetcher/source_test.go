@@ -0,0 +1,18 @@
+package fetcher
+
+import (
+	"strings"
+	"testing"
+
+	"stock/config"
+)
+
+func TestSourcesRejectsQuoteSource(t *testing.T) {
+	_, err := NewBarSourceFromConfig(config.DataConfig{Sources: map[string]string{"sh600000": "sina"}})
+	if err == nil || !strings.Contains(err.Error(), "quote_source") {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := NewBarSourceFromConfig(config.DataConfig{Sources: map[string]string{"sh600000": "default"}}); err != nil {
+		t.Fatal(err)
+	}
+}
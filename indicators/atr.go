@@ -0,0 +1,144 @@
+package indicators
+
+import "math"
+
+// TrueRange 真实波幅；prevClose <= 0（第一根）时为最高价 - 最低价
+func TrueRange(b Bar, prevClose float64) float64 {
+	tr := b.High - b.Low
+	if prevClose > 0 {
+		tr = math.Max(tr, math.Max(math.Abs(b.High-prevClose), math.Abs(b.Low-prevClose)))
+	}
+	return tr
+}
+
+// ATR 平均真实波幅（流式），威尔德平滑；满 n 根后有值。
+type ATR struct {
+	w         wilder
+	prevClose float64
+}
+
+// NewATR 常用 14
+func NewATR(n int) *ATR {
+	if n < 1 {
+		n = 1
+	}
+	return &ATR{w: wilder{n: n}}
+}
+
+// Update 加入一根K线并返回当前 ATR
+func (a *ATR) Update(b Bar) float64 {
+	v := a.w.update(TrueRange(b, a.prevClose))
+	a.prevClose = b.Close
+	return v
+}
+
+// Value 当前 ATR
+func (a *ATR) Value() float64 {
+	if !a.w.ready() {
+		return math.NaN()
+	}
+	return a.w.value
+}
+
+// Ready 是否已有值
+func (a *ATR) Ready() bool { return a.w.ready() }
+
+// ATRSeries ATR 序列
+func ATRSeries(bars []Bar, n int) []float64 {
+	a := NewATR(n)
+	out := make([]float64, len(bars))
+	for i, b := range bars {
+		out[i] = a.Update(b)
+	}
+	return out
+}
+
+// ADX 平均趋向指标（流式，威尔德原始算法）：
+// TR、+DM、-DM 先取前 n 个之和，之后 S = S - S/n + x；
+// +DI = 100·S(+DM)/S(TR)，DX = 100·|+DI - -DI|/(+DI + -DI)，ADX 为 DX 的威尔德平均。
+// 第 n+1 根起有 DI，第 2n 根起有 ADX。
+type ADX struct {
+	n                   int
+	started             bool
+	count               int // 有前一根K线的样本数（DM/TR）
+	prev                Bar
+	tr, plusDM, minusDM float64
+	dx                  wilder
+	plusDI, minusDI     float64
+	adx                 float64
+}
+
+// NewADX 常用 14
+func NewADX(n int) *ADX {
+	if n < 1 {
+		n = 1
+	}
+	return &ADX{n: n, dx: wilder{n: n}, plusDI: math.NaN(), minusDI: math.NaN(), adx: math.NaN()}
+}
+
+// Update 加入一根K线，返回 ADX、+DI、-DI
+func (a *ADX) Update(b Bar) (adx, plusDI, minusDI float64) {
+	if !a.started {
+		a.started = true
+		a.prev = b
+		return a.Value()
+	}
+	up := b.High - a.prev.High
+	down := a.prev.Low - b.Low
+	pdm, mdm := 0.0, 0.0
+	if up > down && up > 0 {
+		pdm = up
+	}
+	if down > up && down > 0 {
+		mdm = down
+	}
+	tr := TrueRange(b, a.prev.Close)
+	a.prev = b
+	a.count++
+
+	n := float64(a.n)
+	if a.count <= a.n {
+		a.tr += tr
+		a.plusDM += pdm
+		a.minusDM += mdm
+		if a.count < a.n {
+			return a.Value()
+		}
+	} else {
+		a.tr = a.tr - a.tr/n + tr
+		a.plusDM = a.plusDM - a.plusDM/n + pdm
+		a.minusDM = a.minusDM - a.minusDM/n + mdm
+	}
+	if a.tr > 0 {
+		a.plusDI = 100 * a.plusDM / a.tr
+		a.minusDI = 100 * a.minusDM / a.tr
+	} else {
+		a.plusDI, a.minusDI = 0, 0
+	}
+	dx := 0.0
+	if s := a.plusDI + a.minusDI; s > 0 {
+		dx = 100 * math.Abs(a.plusDI-a.minusDI) / s
+	}
+	a.adx = a.dx.update(dx)
+	return a.Value()
+}
+
+// Value 当前 ADX、+DI、-DI
+func (a *ADX) Value() (adx, plusDI, minusDI float64) {
+	return a.adx, a.plusDI, a.minusDI
+}
+
+// Ready ADX 是否已有值
+func (a *ADX) Ready() bool { return a.dx.ready() }
+
+// ADXSeries ADX、+DI、-DI 序列
+func ADXSeries(bars []Bar, n int) (adx, plusDI, minusDI []float64) {
+	a := NewADX(n)
+	adx = make([]float64, len(bars))
+	plusDI = make([]float64, len(bars))
+	minusDI = make([]float64, len(bars))
+	for i, b := range bars {
+		adx[i], plusDI[i], minusDI[i] = a.Update(b)
+	}
+	return adx, plusDI, minusDI
+}
@@ -0,0 +1,60 @@
+package indicators
+
+import "math"
+
+// Bollinger 布林带（流式）：中轨 = n 期均线，上下轨 = 中轨 ± k × 总体标准差。
+type Bollinger struct {
+	n   int
+	k   float64
+	buf []float64
+}
+
+// NewBollinger 常用参数 20, 2
+func NewBollinger(n int, k float64) *Bollinger {
+	if n < 1 {
+		n = 1
+	}
+	return &Bollinger{n: n, k: k}
+}
+
+// Update 加入一根收盘价，返回上轨、中轨、下轨；不足 n 根时为 NaN
+func (b *Bollinger) Update(close float64) (upper, mid, lower float64) {
+	b.buf = append(b.buf, close)
+	if len(b.buf) > b.n {
+		b.buf = b.buf[1:]
+	}
+	return b.Value()
+}
+
+// Value 当前上轨、中轨、下轨
+func (b *Bollinger) Value() (upper, mid, lower float64) {
+	if !b.Ready() {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+	sum := 0.0
+	for _, x := range b.buf {
+		sum += x
+	}
+	mid = sum / float64(b.n)
+	v := 0.0
+	for _, x := range b.buf {
+		v += (x - mid) * (x - mid)
+	}
+	sd := math.Sqrt(v / float64(b.n))
+	return mid + b.k*sd, mid, mid - b.k*sd
+}
+
+// Ready 是否已满 n 根
+func (b *Bollinger) Ready() bool { return len(b.buf) >= b.n }
+
+// BollingerSeries 布林带序列
+func BollingerSeries(closes []float64, n int, k float64) (upper, mid, lower []float64) {
+	b := NewBollinger(n, k)
+	upper = make([]float64, len(closes))
+	mid = make([]float64, len(closes))
+	lower = make([]float64, len(closes))
+	for i, c := range closes {
+		upper[i], mid[i], lower[i] = b.Update(c)
+	}
+	return upper, mid, lower
+}
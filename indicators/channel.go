@@ -0,0 +1,104 @@
+package indicators
+
+import "math"
+
+// Donchian 唐奇安通道（流式）：上轨 = 最近 n 根（含当根）最高价，下轨 = 最低价，中轨取平均。
+// 突破类信号通常与上一根的通道比较。
+type Donchian struct {
+	n           int
+	highs, lows []float64
+}
+
+// NewDonchian 常用 20
+func NewDonchian(n int) *Donchian {
+	if n < 1 {
+		n = 1
+	}
+	return &Donchian{n: n}
+}
+
+// Update 加入一根K线，返回上轨、中轨、下轨；不足 n 根时为 NaN
+func (d *Donchian) Update(b Bar) (upper, mid, lower float64) {
+	d.highs = append(d.highs, b.High)
+	d.lows = append(d.lows, b.Low)
+	if len(d.highs) > d.n {
+		d.highs = d.highs[1:]
+		d.lows = d.lows[1:]
+	}
+	return d.Value()
+}
+
+// Value 当前上轨、中轨、下轨
+func (d *Donchian) Value() (upper, mid, lower float64) {
+	if !d.Ready() {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+	upper, lower = math.Inf(-1), math.Inf(1)
+	for i := range d.highs {
+		upper = math.Max(upper, d.highs[i])
+		lower = math.Min(lower, d.lows[i])
+	}
+	return upper, (upper + lower) / 2, lower
+}
+
+// Ready 是否已满 n 根
+func (d *Donchian) Ready() bool { return len(d.highs) >= d.n }
+
+// DonchianSeries 唐奇安通道序列
+func DonchianSeries(bars []Bar, n int) (upper, mid, lower []float64) {
+	d := NewDonchian(n)
+	upper = make([]float64, len(bars))
+	mid = make([]float64, len(bars))
+	lower = make([]float64, len(bars))
+	for i, b := range bars {
+		upper[i], mid[i], lower[i] = d.Update(b)
+	}
+	return upper, mid, lower
+}
+
+// HighLow bars[from:to] 的最高价与最低价（区间越界部分截掉）；ok 为 false 表示区间为空
+func HighLow(bars []Bar, from, to int) (high, low float64, ok bool) {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(bars) {
+		to = len(bars)
+	}
+	if from >= to {
+		return 0, 0, false
+	}
+	high, low = math.Inf(-1), math.Inf(1)
+	for j := from; j < to; j++ {
+		high = math.Max(high, bars[j].High)
+		low = math.Min(low, bars[j].Low)
+	}
+	return high, low, true
+}
+
+// PivotLow bars[idx] 的最低价是否为左右各 n 根内的最低（分型低点）；两侧K线不足时为 false
+func PivotLow(bars []Bar, idx, n int) bool {
+	if idx-n < 0 || idx+n >= len(bars) {
+		return false
+	}
+	x := bars[idx].Low
+	for k := idx - n; k <= idx+n; k++ {
+		if k != idx && bars[k].Low < x {
+			return false
+		}
+	}
+	return true
+}
+
+// PivotHigh bars[idx] 的最高价是否为左右各 n 根内的最高（分型高点）
+func PivotHigh(bars []Bar, idx, n int) bool {
+	if idx-n < 0 || idx+n >= len(bars) {
+		return false
+	}
+	x := bars[idx].High
+	for k := idx - n; k <= idx+n; k++ {
+		if k != idx && bars[k].High > x {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+// Package indicators 技术指标：均线、MACD、RSI、KDJ、布林带、ATR/ADX、OBV、VWAP、唐奇安通道等。
+//
+// 每个指标都有两种用法：
+//   - 流式：NewXxx(...) 得到状态对象，逐根 Update，适合策略在 OnBar 里增量计算；
+//   - 批量：XxxSeries(...) 返回与输入等长的序列，适合出图与报告。
+//
+// 窗口类指标（SMA、RSI、布林带、ATR/ADX、唐奇安、滚动 VWAP）样本不足的位置为 NaN，可用 Valid 判断；
+// 递推类指标（EMA、MACD、KDJ、OBV、分时 VWAP）与通达信一样从第一根起就有值，开头一段受初值影响，
+// 需要区分预热期时用 Ready。算法口径与通达信/同花顺常用公式一致，各指标的注释里写明了差异点。
+package indicators
+
+import (
+	"math"
+
+	"stock/model"
+)
+
+// Bar 即 model.Bar（backtest.Bar 是同一类型）
+type Bar = model.Bar
+
+// Valid 指标值是否可用（非 NaN/Inf）
+func Valid(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+// Closes 收盘价序列
+func Closes(bars []Bar) []float64 {
+	out := make([]float64, len(bars))
+	for i, b := range bars {
+		out[i] = b.Close
+	}
+	return out
+}
+
+// Volumes 成交量序列
+func Volumes(bars []Bar) []float64 {
+	out := make([]float64, len(bars))
+	for i, b := range bars {
+		out[i] = float64(b.Volume)
+	}
+	return out
+}
+
+// TypicalPrice (最高+最低+收盘)/3
+func TypicalPrice(b Bar) float64 {
+	return (b.High + b.Low + b.Close) / 3
+}
+
+func nanSeries(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
+
+// wilder 威尔德平滑：前 n 个样本取简单平均，之后 v = (v*(n-1)+x)/n
+type wilder struct {
+	n     int
+	count int
+	sum   float64
+	value float64
+}
+
+func (w *wilder) update(x float64) float64 {
+	w.count++
+	if w.count <= w.n {
+		w.sum += x
+		if w.count < w.n {
+			return math.NaN()
+		}
+		w.value = w.sum / float64(w.n)
+		return w.value
+	}
+	w.value = (w.value*float64(w.n-1) + x) / float64(w.n)
+	return w.value
+}
+
+func (w *wilder) ready() bool { return w.n > 0 && w.count >= w.n }
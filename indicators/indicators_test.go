@@ -0,0 +1,189 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func near(t *testing.T, name string, got, want, tol float64) {
+	t.Helper()
+	if math.IsNaN(got) || math.Abs(got-want) > tol {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+// testBars 40 根确定性K线，用于流式/批量一致性与分型检查
+func testBars() []Bar {
+	h := []float64{10.25, 10.54, 10.94, 11.15, 11.35, 11.34, 11.03, 10.84, 10.75, 10.7, 11.0, 11.4, 11.6, 11.8, 11.79, 11.47, 11.28, 11.2, 11.16, 11.46, 11.85, 12.06, 12.25, 12.23, 11.92, 11.72, 11.64, 11.61, 11.91, 12.31, 12.51, 12.7, 12.68, 12.36, 12.17, 12.09, 12.06, 12.37, 12.77, 12.97}
+	l := []float64{9.8, 10.01, 10.33, 10.61, 10.85, 10.76, 10.52, 10.25, 10.2, 10.22, 10.44, 10.76, 11.15, 11.27, 11.18, 10.93, 10.78, 10.62, 10.65, 10.87, 11.3, 11.58, 11.69, 11.59, 11.47, 11.19, 11.03, 11.07, 11.41, 11.73, 12.0, 12.11, 12.13, 11.88, 11.61, 11.45, 11.61, 11.84, 12.16, 12.43}
+	c := []float64{10.05, 10.29, 10.64, 10.95, 11.1, 11.04, 10.83, 10.59, 10.45, 10.5, 10.75, 11.1, 11.4, 11.55, 11.49, 11.27, 11.03, 10.9, 10.96, 11.21, 11.55, 11.86, 12.0, 11.93, 11.72, 11.47, 11.34, 11.41, 11.66, 12.01, 12.31, 12.45, 12.38, 12.16, 11.92, 11.79, 11.86, 12.12, 12.47, 12.77}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	bars := make([]Bar, len(c))
+	for i := range c {
+		bars[i] = Bar{Time: start.AddDate(0, 0, i), Open: c[i], High: h[i], Low: l[i], Close: c[i], Volume: int64(1000 + (i*37)%500)}
+	}
+	return bars
+}
+
+func TestMovingAverages(t *testing.T) {
+	sma := SMASeries([]float64{1, 2, 3, 4, 5}, 3)
+	if Valid(sma[1]) || sma[2] != 2 || sma[4] != 4 {
+		t.Fatalf("sma %v", sma)
+	}
+	ema := EMASeries([]float64{1, 2, 3}, 3) // α = 0.5
+	if ema[0] != 1 || ema[1] != 1.5 || ema[2] != 2.25 {
+		t.Fatalf("ema %v", ema)
+	}
+	bars := testBars()
+	bars[3].Volume = 0 // suspended bar is skipped
+	near(t, "VolumeMA", VolumeMA(bars, 4, 3), float64(1074+1148)/2, 1e-9)
+}
+
+func TestRSIReference(t *testing.T) {
+	// Wilder / StockCharts 示例数据；数值与 TA-Lib 一致（StockCharts 表格中间取整，首值写作 70.53）
+	closes := []float64{44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08, 45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64}
+	rsi := RSISeries(closes, 14)
+	if Valid(rsi[13]) {
+		t.Fatalf("rsi needs 15 closes, got %v at 14", rsi[13])
+	}
+	for i, want := range []float64{70.46, 66.25, 66.48, 69.35, 66.29, 57.92} {
+		near(t, "RSI14", rsi[14+i], want, 0.01)
+	}
+}
+
+// handBars 5 根K线，下面的参考值都按教科书公式手算（分数形式写出，便于核对）
+func handBars() []Bar {
+	hlcv := [][4]float64{{10, 8, 9, 100}, {11, 9, 10, 200}, {12, 10, 11, 300}, {13, 9, 12, 400}, {12, 8, 8, 500}}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	bars := make([]Bar, len(hlcv))
+	for i, x := range hlcv {
+		bars[i] = Bar{Time: start.AddDate(0, 0, i), Open: x[2], High: x[0], Low: x[1], Close: x[2], Volume: int64(x[3])}
+	}
+	return bars
+}
+
+func TestIndicatorsReference(t *testing.T) {
+	bars := handBars()
+
+	// TR = 2, 2, 2, 4, 4；ATR3 首值为前 3 个 TR 的均值，之后 (2·ATR' + TR)/3
+	atr := ATRSeries(bars, 3)
+	if Valid(atr[1]) {
+		t.Fatalf("atr ready too early")
+	}
+	near(t, "ATR[2]", atr[2], 2, 1e-12)
+	near(t, "ATR[3]", atr[3], 8.0/3, 1e-12)
+	near(t, "ATR[4]", atr[4], 28.0/9, 1e-12)
+
+	// n=2：+DM = 1, 1, 0, 0，-DM = 0, 0, 0, 1，TR = 2, 2, 4, 4（从第 2 根起）
+	// bar2：S(TR)=4, S(+DM)=2 → +DI 50, -DI 0, DX 100
+	// bar3：S(TR)=6, S(+DM)=1 → +DI 100/6, DX 100；ADX = (100+100)/2
+	// bar4：S(TR)=7, S(+DM)=0.5, S(-DM)=1 → +DI 50/7, -DI 100/7, DX 100/3；ADX = (100+100/3)/2
+	adx, pdi, mdi := ADXSeries(bars, 2)
+	if Valid(pdi[1]) || Valid(adx[2]) {
+		t.Fatalf("adx/di readiness: pdi[1]=%v adx[2]=%v", pdi[1], adx[2])
+	}
+	near(t, "+DI[2]", pdi[2], 50, 1e-12)
+	near(t, "-DI[2]", mdi[2], 0, 1e-12)
+	near(t, "+DI[3]", pdi[3], 100.0/6, 1e-12)
+	near(t, "ADX[3]", adx[3], 100, 1e-12)
+	near(t, "+DI[4]", pdi[4], 50.0/7, 1e-12)
+	near(t, "-DI[4]", mdi[4], 100.0/7, 1e-12)
+	near(t, "ADX[4]", adx[4], 200.0/3, 1e-12)
+
+	// KDJ(3,3,3)：RSV = 50, 200/3, 75, 75, 0（bar3、bar4 的窗口已滑动）
+	k, d, j := KDJSeries(bars, 3, 3, 3)
+	near(t, "K[1]", k[1], 500.0/9, 1e-12)
+	near(t, "D[1]", d[1], 1400.0/27, 1e-12)
+	near(t, "J[1]", j[1], 1700.0/27, 1e-12)
+	near(t, "K[4]", k[4], 10750.0/243, 1e-12)
+	near(t, "D[4]", d[4], 39400.0/729, 1e-12)
+	near(t, "J[4]", j[4], 17950.0/729, 1e-12)
+
+	// MACD(1,3,3)：EMA1 即收盘价，EMA3 = 9, 9.5, 10.25, 11.125, 9.5625
+	dif, dea, hist := MACDSeries(Closes(bars), 1, 3, 3)
+	for i, want := range [][3]float64{{0, 0, 0}, {0.5, 0.25, 0.5}, {0.75, 0.5, 0.5}, {0.875, 0.6875, 0.375}, {-1.5625, -0.4375, -2.25}} {
+		near(t, "DIF", dif[i], want[0], 1e-12)
+		near(t, "DEA", dea[i], want[1], 1e-12)
+		near(t, "MACD", hist[i], want[2], 1e-12)
+	}
+
+	// 总体标准差的经典例子：2,4,4,4,5,5,7,9 均值 5、标准差 2
+	up, mid, lo := BollingerSeries([]float64{2, 4, 4, 4, 5, 5, 7, 9}, 8, 2)
+	if Valid(mid[6]) {
+		t.Fatalf("boll ready too early")
+	}
+	near(t, "BOLL upper", up[7], 9, 1e-12)
+	near(t, "BOLL mid", mid[7], 5, 1e-12)
+	near(t, "BOLL lower", lo[7], 1, 1e-12)
+
+	// 0, +200, +300, +400, -500
+	obv := OBVSeries(bars)
+	for i, want := range []float64{0, 200, 500, 900, 400} {
+		near(t, "OBV", obv[i], want, 0)
+	}
+	// 典型价 34/3、28/3，成交量 400、500：(400·34/3 + 500·28/3)/900
+	vwap := VWAPSeries(bars, 2)
+	if Valid(vwap[0]) {
+		t.Fatalf("vwap ready too early")
+	}
+	near(t, "VWAP2", vwap[4], 92.0/9, 1e-12)
+
+	du, dm, dl := DonchianSeries(bars, 3)
+	if Valid(du[1]) {
+		t.Fatalf("donchian ready too early")
+	}
+	near(t, "Donchian upper", du[4], 13, 0)
+	near(t, "Donchian lower", dl[4], 8, 0)
+	near(t, "Donchian mid", dm[4], 10.5, 0)
+
+	long := testBars()
+	last := len(long) - 1
+	ks, _, _ := KDJSeries(long, 9, 3, 3)
+	s := Latest(long)
+	if s.ATR14 != round(ATRSeries(long, 14)[last]) || s.RSI14 == 0 || s.MA60 != 0 || s.K != round(ks[last]) {
+		t.Fatalf("snapshot %+v", s)
+	}
+}
+
+func TestStreamingMatchesSeries(t *testing.T) {
+	bars := testBars()
+	series := RSISeries(Closes(bars), 6)
+	r := NewRSI(6)
+	for i, b := range bars {
+		got := r.Update(b.Close)
+		if Valid(got) != Valid(series[i]) || (Valid(got) && got != series[i]) {
+			t.Fatalf("bar %d: stream %v, series %v", i, got, series[i])
+		}
+	}
+	if !r.Ready() || r.Value() != series[len(series)-1] {
+		t.Fatalf("final value %v", r.Value())
+	}
+}
+
+func TestSessionVWAPResetsDaily(t *testing.T) {
+	day := time.Date(2024, 1, 2, 9, 31, 0, 0, time.Local)
+	bars := []Bar{
+		{Time: day, High: 11, Low: 9, Close: 10, Volume: 100},
+		{Time: day.Add(time.Minute), High: 13, Low: 11, Close: 12, Volume: 300},
+		{Time: day.AddDate(0, 0, 1), High: 21, Low: 19, Close: 20, Volume: 50},
+	}
+	v := VWAPSeries(bars, 0)
+	if v[0] != 10 || v[1] != 11.5 || v[2] != 20 {
+		t.Fatalf("session vwap %v", v)
+	}
+}
+
+func TestPivots(t *testing.T) {
+	bars := testBars()
+	// bar 8 (low 10.20) is the lowest of bars 6..10
+	if !PivotLow(bars, 8, 2) || PivotLow(bars, 7, 2) || PivotLow(bars, 1, 2) {
+		t.Fatalf("pivot low")
+	}
+	if !PivotHigh(bars, 4, 2) || PivotHigh(bars, 39, 2) {
+		t.Fatalf("pivot high")
+	}
+	if hi, lo, ok := HighLow(bars, -5, 3); !ok || hi != 10.94 || lo != 9.8 {
+		t.Fatalf("high/low %v %v %v", hi, lo, ok)
+	}
+}
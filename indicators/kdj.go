@@ -0,0 +1,75 @@
+package indicators
+
+import "math"
+
+// KDJ 随机指标（流式，国内口径）：
+// RSV = (C - 最近 n 根最低价) / (最近 n 根最高价 - 最低价) × 100（区间无波动时取 50），
+// K = ((m1-1)·K' + RSV)/m1，D = ((m2-1)·D' + K)/m2，J = 3K - 2D，K、D 初值 50。
+// 与通达信一致，开头不足 n 根时用已有的K线计算。
+type KDJ struct {
+	n, m1, m2 int
+	highs     []float64
+	lows      []float64
+	k, d      float64
+	count     int
+}
+
+// NewKDJ 常用参数 9, 3, 3
+func NewKDJ(n, m1, m2 int) *KDJ {
+	if n < 1 {
+		n = 1
+	}
+	if m1 < 1 {
+		m1 = 1
+	}
+	if m2 < 1 {
+		m2 = 1
+	}
+	return &KDJ{n: n, m1: m1, m2: m2, k: 50, d: 50}
+}
+
+// Update 加入一根K线，返回 K、D、J
+func (s *KDJ) Update(b Bar) (k, d, j float64) {
+	s.highs = append(s.highs, b.High)
+	s.lows = append(s.lows, b.Low)
+	if len(s.highs) > s.n {
+		s.highs = s.highs[1:]
+		s.lows = s.lows[1:]
+	}
+	hi, lo := math.Inf(-1), math.Inf(1)
+	for i := range s.highs {
+		hi = math.Max(hi, s.highs[i])
+		lo = math.Min(lo, s.lows[i])
+	}
+	rsv := 50.0
+	if hi > lo {
+		rsv = (b.Close - lo) / (hi - lo) * 100
+	}
+	s.k = (float64(s.m1-1)*s.k + rsv) / float64(s.m1)
+	s.d = (float64(s.m2-1)*s.d + s.k) / float64(s.m2)
+	s.count++
+	return s.Value()
+}
+
+// Value 当前 K、D、J；尚无输入时为 NaN
+func (s *KDJ) Value() (k, d, j float64) {
+	if s.count == 0 {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+	return s.k, s.d, 3*s.k - 2*s.d
+}
+
+// Ready 是否已满 n 根
+func (s *KDJ) Ready() bool { return s.count >= s.n }
+
+// KDJSeries KDJ 序列
+func KDJSeries(bars []Bar, n, m1, m2 int) (k, d, j []float64) {
+	s := NewKDJ(n, m1, m2)
+	k = make([]float64, len(bars))
+	d = make([]float64, len(bars))
+	j = make([]float64, len(bars))
+	for i, b := range bars {
+		k[i], d[i], j[i] = s.Update(b)
+	}
+	return k, d, j
+}
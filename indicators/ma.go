@@ -0,0 +1,128 @@
+package indicators
+
+import "math"
+
+// SMA 简单移动平均（流式）
+type SMA struct {
+	n     int
+	buf   []float64
+	pos   int
+	count int
+	sum   float64
+}
+
+// NewSMA n 期简单移动平均；n < 1 按 1 处理
+func NewSMA(n int) *SMA {
+	if n < 1 {
+		n = 1
+	}
+	return &SMA{n: n, buf: make([]float64, n)}
+}
+
+// Update 加入一个值并返回当前均值；不足 n 个值时为 NaN
+func (s *SMA) Update(x float64) float64 {
+	if s.count >= s.n {
+		s.sum -= s.buf[s.pos]
+	} else {
+		s.count++
+	}
+	s.buf[s.pos] = x
+	s.sum += x
+	s.pos = (s.pos + 1) % s.n
+	return s.Value()
+}
+
+// Value 当前均值
+func (s *SMA) Value() float64 {
+	if !s.Ready() {
+		return math.NaN()
+	}
+	return s.sum / float64(s.n)
+}
+
+// Ready 是否已有 n 个值
+func (s *SMA) Ready() bool { return s.count >= s.n }
+
+// SMASeries 简单移动平均序列
+func SMASeries(xs []float64, n int) []float64 {
+	s := NewSMA(n)
+	out := make([]float64, len(xs))
+	for i, x := range xs {
+		out[i] = s.Update(x)
+	}
+	return out
+}
+
+// EMA 指数移动平均（流式），α = 2/(n+1)。
+// 与通达信 EMA 一致：首值取第一个输入，之后 v = α·x + (1-α)·v；满 n 个值后 Ready。
+type EMA struct {
+	n     int
+	alpha float64
+	count int
+	value float64
+}
+
+// NewEMA n 期指数移动平均；n < 1 按 1 处理
+func NewEMA(n int) *EMA {
+	if n < 1 {
+		n = 1
+	}
+	return &EMA{n: n, alpha: 2 / float64(n+1)}
+}
+
+// Update 加入一个值并返回当前 EMA
+func (e *EMA) Update(x float64) float64 {
+	if e.count == 0 {
+		e.value = x
+	} else {
+		e.value = e.alpha*x + (1-e.alpha)*e.value
+	}
+	e.count++
+	return e.value
+}
+
+// Value 当前 EMA；尚无输入时为 NaN
+func (e *EMA) Value() float64 {
+	if e.count == 0 {
+		return math.NaN()
+	}
+	return e.value
+}
+
+// Ready 是否已有 n 个值
+func (e *EMA) Ready() bool { return e.count >= e.n }
+
+// EMASeries 指数移动平均序列（从第一个值起有值）
+func EMASeries(xs []float64, n int) []float64 {
+	e := NewEMA(n)
+	out := make([]float64, len(xs))
+	for i, x := range xs {
+		out[i] = e.Update(x)
+	}
+	return out
+}
+
+// VolumeMA bars[i] 及之前最多 n 根K线的平均成交量，跳过成交量为 0 的K线（停牌）；
+// 没有有效成交量时返回 0。
+func VolumeMA(bars []Bar, i, n int) float64 {
+	if n <= 0 || i < 0 || i >= len(bars) {
+		return 0
+	}
+	start := i - n + 1
+	if start < 0 {
+		start = 0
+	}
+	sum := 0.0
+	cnt := 0
+	for j := start; j <= i; j++ {
+		if bars[j].Volume <= 0 {
+			continue
+		}
+		sum += float64(bars[j].Volume)
+		cnt++
+	}
+	if cnt == 0 {
+		return 0
+	}
+	return sum / float64(cnt)
+}
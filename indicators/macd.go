@@ -0,0 +1,46 @@
+package indicators
+
+import "math"
+
+// MACD 指数平滑异同移动平均（流式）：
+// DIF = EMA(C, fast) - EMA(C, slow)，DEA = EMA(DIF, signal)，柱 = 2·(DIF - DEA)（国内软件口径）。
+type MACD struct {
+	fast, slow, signal *EMA
+}
+
+// NewMACD 常用参数 12, 26, 9
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+// Update 加入一根收盘价，返回 DIF、DEA 与柱
+func (m *MACD) Update(close float64) (dif, dea, hist float64) {
+	dif = m.fast.Update(close) - m.slow.Update(close)
+	dea = m.signal.Update(dif)
+	return dif, dea, 2 * (dif - dea)
+}
+
+// Value 当前 DIF、DEA 与柱；尚无输入时为 NaN
+func (m *MACD) Value() (dif, dea, hist float64) {
+	if m.slow.count == 0 {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+	dif = m.fast.value - m.slow.value
+	dea = m.signal.value
+	return dif, dea, 2 * (dif - dea)
+}
+
+// Ready 慢线与信号线都已满周期
+func (m *MACD) Ready() bool { return m.slow.Ready() && m.signal.Ready() }
+
+// MACDSeries MACD 序列
+func MACDSeries(closes []float64, fast, slow, signal int) (dif, dea, hist []float64) {
+	m := NewMACD(fast, slow, signal)
+	dif = make([]float64, len(closes))
+	dea = make([]float64, len(closes))
+	hist = make([]float64, len(closes))
+	for i, c := range closes {
+		dif[i], dea[i], hist[i] = m.Update(c)
+	}
+	return dif, dea, hist
+}
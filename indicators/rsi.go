@@ -0,0 +1,58 @@
+package indicators
+
+import "math"
+
+// RSI 相对强弱指标（流式），威尔德平滑：前 n 个涨跌幅取简单平均，之后按 1/n 递推。
+// 需要 n+1 根收盘价才有值。
+type RSI struct {
+	gain, loss wilder
+	prev       float64
+	count      int
+	value      float64
+}
+
+// NewRSI 常用 6、14
+func NewRSI(n int) *RSI {
+	if n < 1 {
+		n = 1
+	}
+	return &RSI{gain: wilder{n: n}, loss: wilder{n: n}, value: math.NaN()}
+}
+
+// Update 加入一根收盘价并返回当前 RSI（0~100）
+func (r *RSI) Update(close float64) float64 {
+	r.count++
+	if r.count == 1 {
+		r.prev = close
+		return math.NaN()
+	}
+	d := close - r.prev
+	r.prev = close
+	g := r.gain.update(math.Max(d, 0))
+	l := r.loss.update(math.Max(-d, 0))
+	switch {
+	case !Valid(g):
+		r.value = math.NaN()
+	case g+l == 0:
+		r.value = 50
+	default:
+		r.value = 100 * g / (g + l)
+	}
+	return r.value
+}
+
+// Value 当前 RSI
+func (r *RSI) Value() float64 { return r.value }
+
+// Ready 是否已有值
+func (r *RSI) Ready() bool { return r.gain.ready() }
+
+// RSISeries RSI 序列
+func RSISeries(closes []float64, n int) []float64 {
+	r := NewRSI(n)
+	out := make([]float64, len(closes))
+	for i, c := range closes {
+		out[i] = r.Update(c)
+	}
+	return out
+}
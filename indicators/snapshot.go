@@ -0,0 +1,98 @@
+package indicators
+
+import "math"
+
+// Snapshot 最新一根K线的常用指标（默认参数）。未就绪的指标为 0，JSON 中省略。
+type Snapshot struct {
+	MA5  float64 `json:"ma5,omitempty"`
+	MA10 float64 `json:"ma10,omitempty"`
+	MA20 float64 `json:"ma20,omitempty"`
+	MA60 float64 `json:"ma60,omitempty"`
+
+	MACDDIF  float64 `json:"macd_dif,omitempty"`
+	MACDDEA  float64 `json:"macd_dea,omitempty"`
+	MACDHist float64 `json:"macd_hist,omitempty"`
+
+	RSI6  float64 `json:"rsi6,omitempty"`
+	RSI14 float64 `json:"rsi14,omitempty"`
+
+	K float64 `json:"kdj_k,omitempty"`
+	D float64 `json:"kdj_d,omitempty"`
+	J float64 `json:"kdj_j,omitempty"`
+
+	BollUpper float64 `json:"boll_upper,omitempty"`
+	BollMid   float64 `json:"boll_mid,omitempty"`
+	BollLower float64 `json:"boll_lower,omitempty"`
+
+	ATR14   float64 `json:"atr14,omitempty"`
+	ADX14   float64 `json:"adx14,omitempty"`
+	PlusDI  float64 `json:"plus_di,omitempty"`
+	MinusDI float64 `json:"minus_di,omitempty"`
+
+	OBV           float64 `json:"obv,omitempty"`
+	VWAP20        float64 `json:"vwap20,omitempty"`
+	DonchianUpper float64 `json:"donchian_upper20,omitempty"`
+	DonchianLower float64 `json:"donchian_lower20,omitempty"`
+}
+
+// Latest 计算 bars 最后一根的 Snapshot：MA 5/10/20/60、MACD(12,26,9)、RSI 6/14、KDJ(9,3,3)、
+// BOLL(20,2)、ATR/ADX(14)、OBV、VWAP(20) 与唐奇安(20)
+func Latest(bars []Bar) Snapshot {
+	ma5, ma10, ma20, ma60 := NewSMA(5), NewSMA(10), NewSMA(20), NewSMA(60)
+	macd := NewMACD(12, 26, 9)
+	rsi6, rsi14 := NewRSI(6), NewRSI(14)
+	kdj := NewKDJ(9, 3, 3)
+	boll := NewBollinger(20, 2)
+	atr := NewATR(14)
+	adx := NewADX(14)
+	obv := NewOBV()
+	vwap := NewVWAP(20)
+	don := NewDonchian(20)
+	for _, b := range bars {
+		for _, m := range []*SMA{ma5, ma10, ma20, ma60} {
+			m.Update(b.Close)
+		}
+		macd.Update(b.Close)
+		rsi6.Update(b.Close)
+		rsi14.Update(b.Close)
+		kdj.Update(b)
+		boll.Update(b.Close)
+		atr.Update(b)
+		adx.Update(b)
+		obv.Update(b)
+		vwap.Update(b)
+		don.Update(b)
+	}
+
+	var s Snapshot
+	s.MA5, s.MA10, s.MA20, s.MA60 = round(ma5.Value()), round(ma10.Value()), round(ma20.Value()), round(ma60.Value())
+	if macd.Ready() {
+		dif, dea, hist := macd.Value()
+		s.MACDDIF, s.MACDDEA, s.MACDHist = round(dif), round(dea), round(hist)
+	}
+	s.RSI6, s.RSI14 = round(rsi6.Value()), round(rsi14.Value())
+	if kdj.Ready() {
+		k, d, j := kdj.Value()
+		s.K, s.D, s.J = round(k), round(d), round(j)
+	}
+	up, mid, lo := boll.Value()
+	s.BollUpper, s.BollMid, s.BollLower = round(up), round(mid), round(lo)
+	s.ATR14 = round(atr.Value())
+	a, p, m := adx.Value()
+	s.ADX14, s.PlusDI, s.MinusDI = round(a), round(p), round(m)
+	if len(bars) > 0 {
+		s.OBV = obv.Value()
+	}
+	s.VWAP20 = round(vwap.Value())
+	up, _, lo = don.Value()
+	s.DonchianUpper, s.DonchianLower = round(up), round(lo)
+	return s
+}
+
+// round 保留 4 位小数，NaN/Inf 记为 0
+func round(x float64) float64 {
+	if !Valid(x) {
+		return 0
+	}
+	return math.Round(x*1e4) / 1e4
+}
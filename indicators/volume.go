@@ -0,0 +1,104 @@
+package indicators
+
+import "math"
+
+// OBV 能量潮（流式）：收盘上涨加当根成交量、下跌减成交量，第一根为 0。
+type OBV struct {
+	started bool
+	prev    float64
+	value   float64
+}
+
+// NewOBV 创建 OBV
+func NewOBV() *OBV { return &OBV{} }
+
+// Update 加入一根K线并返回当前 OBV
+func (o *OBV) Update(b Bar) float64 {
+	if o.started {
+		switch {
+		case b.Close > o.prev:
+			o.value += float64(b.Volume)
+		case b.Close < o.prev:
+			o.value -= float64(b.Volume)
+		}
+	}
+	o.started = true
+	o.prev = b.Close
+	return o.value
+}
+
+// Value 当前 OBV
+func (o *OBV) Value() float64 { return o.value }
+
+// OBVSeries OBV 序列
+func OBVSeries(bars []Bar) []float64 {
+	o := NewOBV()
+	out := make([]float64, len(bars))
+	for i, b := range bars {
+		out[i] = o.Update(b)
+	}
+	return out
+}
+
+// VWAP 成交量加权均价（流式），价格取典型价 (H+L+C)/3。
+// n > 0：最近 n 根滚动；n <= 0：按交易日累计（分时 VWAP，换日重置）。
+// 区间成交量为 0 时取最新一根的典型价。
+type VWAP struct {
+	n       int
+	pv, vol []float64
+	day     string
+	sumPV   float64
+	sumVol  float64
+	last    float64
+	count   int
+}
+
+// NewVWAP n 根滚动 VWAP；n <= 0 为分时 VWAP
+func NewVWAP(n int) *VWAP { return &VWAP{n: n} }
+
+// Update 加入一根K线并返回当前 VWAP
+func (v *VWAP) Update(b Bar) float64 {
+	tp := TypicalPrice(b)
+	pv, vol := tp*float64(b.Volume), float64(b.Volume)
+	if v.n <= 0 {
+		if d := b.Time.Format("2006-01-02"); d != v.day {
+			v.day = d
+			v.sumPV, v.sumVol = 0, 0
+		}
+	} else {
+		v.pv = append(v.pv, pv)
+		v.vol = append(v.vol, vol)
+		if len(v.pv) > v.n {
+			v.sumPV -= v.pv[0]
+			v.sumVol -= v.vol[0]
+			v.pv = v.pv[1:]
+			v.vol = v.vol[1:]
+		}
+	}
+	v.sumPV += pv
+	v.sumVol += vol
+	v.last = tp
+	v.count++
+	return v.Value()
+}
+
+// Value 当前 VWAP；滚动模式不足 n 根时为 NaN
+func (v *VWAP) Value() float64 {
+	if v.count == 0 || (v.n > 0 && len(v.pv) < v.n) {
+		return math.NaN()
+	}
+	if v.sumVol <= 0 {
+		return v.last
+	}
+	return v.sumPV / v.sumVol
+}
+
+// VWAPSeries VWAP 序列
+func VWAPSeries(bars []Bar, n int) []float64 {
+	v := NewVWAP(n)
+	out := make([]float64, len(bars))
+	for i, b := range bars {
+		out[i] = v.Update(b)
+	}
+	return out
+}
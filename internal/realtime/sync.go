@@ -20,7 +20,7 @@ type SyncOptions struct {
 	Quiet  bool
 }
 
-func RunDataSync(cfg *config.Config, c *cache.Cache, sf *fetcher.StockFetcher, ff *fetcher.FuturesFetcher, stop <-chan struct{}, opt SyncOptions) {
+func RunDataSync(cfg *config.Config, c *cache.Cache, src fetcher.QuoteSource, stop <-chan struct{}, opt SyncOptions) {
 	logger := opt.Logger
 	if logger == nil {
 		logger = log.Default()
@@ -32,8 +32,8 @@ func RunDataSync(cfg *config.Config, c *cache.Cache, sf *fetcher.StockFetcher, f
 	if !opt.Quiet {
 		logger.Printf("[sync] initial fetch...")
 	}
-	fetchChinaData(cfg.Stocks, chinaFutures, c, sf, ff, logger, opt.Quiet)
-	fetchGlobalFutures(globalFutures, c, ff, logger, opt.Quiet)
+	fetchChinaData(cfg.Stocks, chinaFutures, c, src, logger, opt.Quiet)
+	fetchGlobalFutures(globalFutures, c, src, logger, opt.Quiet)
 
 	refreshTicker := time.NewTicker(cfg.RefreshInterval) // China markets, only during trading time
 	globalTicker := time.NewTicker(cfg.RefreshInterval)  // hf_ futures, always refresh
@@ -52,12 +52,12 @@ func RunDataSync(cfg *config.Config, c *cache.Cache, sf *fetcher.StockFetcher, f
 
 		case <-refreshTicker.C:
 			if trading.IsTradingTime() {
-				fetchChinaData(cfg.Stocks, chinaFutures, c, sf, ff, logger, opt.Quiet)
+				fetchChinaData(cfg.Stocks, chinaFutures, c, src, logger, opt.Quiet)
 			}
 
 		case <-globalTicker.C:
 			if len(globalFutures) > 0 {
-				fetchGlobalFutures(globalFutures, c, ff, logger, opt.Quiet)
+				fetchGlobalFutures(globalFutures, c, src, logger, opt.Quiet)
 			}
 
 		case <-checkTicker.C:
@@ -73,9 +73,9 @@ func RunDataSync(cfg *config.Config, c *cache.Cache, sf *fetcher.StockFetcher, f
 	}
 }
 
-func fetchChinaData(stocks []string, futures []string, c *cache.Cache, sf *fetcher.StockFetcher, ff *fetcher.FuturesFetcher, logger Logger, quiet bool) {
+func fetchChinaData(stocks []string, futures []string, c *cache.Cache, src fetcher.QuoteSource, logger Logger, quiet bool) {
 	if len(stocks) > 0 {
-		quotes, err := sf.Fetch(stocks)
+		quotes, err := src.FetchStocks(stocks)
 		if err != nil {
 			if !quiet {
 				logger.Printf("[sync] fetch stocks failed: %v", err)
@@ -89,7 +89,7 @@ func fetchChinaData(stocks []string, futures []string, c *cache.Cache, sf *fetch
 	}
 
 	if len(futures) > 0 {
-		quotes, err := ff.Fetch(futures)
+		quotes, err := src.FetchFutures(futures)
 		if err != nil {
 			if !quiet {
 				logger.Printf("[sync] fetch futures failed: %v", err)
@@ -103,11 +103,11 @@ func fetchChinaData(stocks []string, futures []string, c *cache.Cache, sf *fetch
 	}
 }
 
-func fetchGlobalFutures(futures []string, c *cache.Cache, ff *fetcher.FuturesFetcher, logger Logger, quiet bool) {
+func fetchGlobalFutures(futures []string, c *cache.Cache, src fetcher.QuoteSource, logger Logger, quiet bool) {
 	if len(futures) == 0 {
 		return
 	}
-	quotes, err := ff.Fetch(futures)
+	quotes, err := src.FetchFutures(futures)
 	if err != nil {
 		if !quiet {
 			logger.Printf("[sync] fetch global futures failed: %v", err)
@@ -82,7 +82,7 @@ func runAnalyze(serviceConfigPath, btConfigPath, outDir string, windowDays int,
 		return err
 	}
 	stocks := append([]string(nil), svcCfg.Stocks...)
-	futures := filterChinaFutures(svcCfg.Futures)
+	futures := append([]string(nil), svcCfg.Futures...)
 
 	// Load backtest config (strategy params source)
 	btCfg, err := backtest.LoadRunConfig(btConfigPath)
@@ -90,14 +90,14 @@ func runAnalyze(serviceConfigPath, btConfigPath, outDir string, windowDays int,
 		return err
 	}
 
-	ts, ok := btCfg.Strategy.(*backtest.TsaiSenStrategy)
-	if !ok {
-		return fmt.Errorf("strategy.type must be tsai_sen for -analyze (got %T)", btCfg.Strategy)
+	// Volume MA window: follow the strategy's volume filter when it has one
+	volMAN := 20
+	if vf, ok := btCfg.Strategy.(backtest.VolumeFilter); ok && vf.VolumeMAN() > 0 {
+		volMAN = vf.VolumeMAN()
 	}
-	tsParams := ts.Params()
 
 	// Merge instruments: bt-config + service config
-	btCfg.Instruments = mergeInstruments(btCfg.Instruments, stocks, futures)
+	btCfg.Instruments = mergeInstruments(btCfg, stocks, futures)
 
 	// Apply analysis window
 	now := time.Now().In(time.Local)
@@ -116,13 +116,19 @@ func runAnalyze(serviceConfigPath, btConfigPath, outDir string, windowDays int,
 		start := end.AddDate(0, 0, -windowDays)
 		btCfg.Start = start
 		btCfg.End = end
-		need := windowDays + 200
+		need := btCfg.Frequency.BarsForDays(windowDays) + 200
 		if need > btCfg.Days {
 			btCfg.Days = need
 		}
 	}
 
-	runner := backtest.NewRunner()
+	if btCfg.Data.IsZero() {
+		btCfg.Data = svcCfg.Data
+	}
+	runner, err := newRunner(btCfg)
+	if err != nil {
+		return err
+	}
 
 	// Latest scan snapshot (no chart)
 	scanCfg := btCfg
@@ -157,19 +163,10 @@ func runAnalyze(serviceConfigPath, btConfigPath, outDir string, windowDays int,
 
 	results := make([]*instrumentAnalysis, 0, len(btCfg.Instruments))
 	for _, inst := range btCfg.Instruments {
-		// backtest/scan are China-only; ignore hf_ just in case.
-		if strings.HasPrefix(strings.ToLower(inst.Symbol), "hf_") {
-			report.Skipped = append(report.Skipped, struct {
-				Symbol string `json:"symbol"`
-				Reason string `json:"reason"`
-			}{Symbol: inst.Symbol, Reason: "hf_ realtime-only; no daily KLine support for analysis"})
-			continue
-		}
-
 		out := &instrumentAnalysis{
 			Symbol:     inst.Symbol,
 			Instrument: string(inst.Type),
-			VolumeMAN:  tsParams.VolMAN,
+			VolumeMAN:  volMAN,
 		}
 
 		if r, ok := scanBySym[inst.Symbol]; ok {
@@ -194,7 +191,7 @@ func runAnalyze(serviceConfigPath, btConfigPath, outDir string, windowDays int,
 
 		lastIdx := len(barsOne) - 1
 		out.LastVolume = barsOne[lastIdx].Volume
-		ma := backtest.VolumeMA(barsOne, lastIdx, tsParams.VolMAN)
+		ma := backtest.VolumeMA(barsOne, lastIdx, volMAN)
 		out.LastVolumeMA = round2(ma)
 		if ma > 0 {
 			out.LastVolumeRatio = round2(float64(out.LastVolume) / ma)
@@ -228,7 +225,7 @@ func runAnalyze(serviceConfigPath, btConfigPath, outDir string, windowDays int,
 			})
 		}
 
-		svg, serr := backtest.RenderCandlesWithVolumeSVG(inst.Symbol, barsOne, lines, points, tsParams.VolMAN, backtest.SVGChartOptions{})
+		svg, serr := backtest.RenderCandlesWithVolumeSVG(inst.Symbol, barsOne, lines, points, volMAN, backtest.SVGChartOptions{})
 		if serr != nil {
 			out.Errors = append(out.Errors, serr.Error())
 		} else {
@@ -283,6 +280,9 @@ func runAnalyze(serviceConfigPath, btConfigPath, outDir string, windowDays int,
 	if err := writeTradesCSV(filepath.Join(outDir, "trades.csv"), report); err != nil {
 		return err
 	}
+	if err := writeReturnsCSV(filepath.Join(outDir, "returns.csv"), report); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -318,6 +318,10 @@ func writeAnalysisCSV(path string, rep analysisReport) error {
 		"support", "resistance", "stop", "target",
 		"vol_ma_n", "last_volume", "last_volume_ma", "last_volume_ratio",
 		"final_equity", "max_dd_pct", "win_rate_pct", "total_trades",
+		"cagr_pct", "annual_vol_pct", "sharpe", "sortino", "calmar",
+		"profit_factor", "expectancy", "avg_win_loss_ratio",
+		"longest_dd_days", "exposure_pct", "avg_holding_days",
+		"benchmark", "benchmark_return_pct", "excess_return_pct", "beta", "alpha_pct",
 		"chart_path", "errors",
 	})
 
@@ -339,14 +343,40 @@ func writeAnalysisCSV(path string, rep analysisReport) error {
 		maxDD := ""
 		winRate := ""
 		totalTrades := ""
+		metrics := make([]string, 11)
+		bench := make([]string, 5)
 		if r.YearStats != nil {
 			finalEquity = fmt.Sprintf("%.2f", r.YearStats.FinalEquity)
 			maxDD = fmt.Sprintf("%.2f", r.YearStats.MaxDDPct)
 			winRate = fmt.Sprintf("%.2f", r.YearStats.WinRatePct)
 			totalTrades = fmt.Sprintf("%d", r.YearStats.TotalTrades)
+			if m := r.YearStats.Metrics; m != nil {
+				metrics = []string{
+					fmt.Sprintf("%.2f", m.CAGRPct),
+					fmt.Sprintf("%.2f", m.AnnualVolPct),
+					fmt.Sprintf("%.2f", m.Sharpe),
+					fmt.Sprintf("%.2f", m.Sortino),
+					fmt.Sprintf("%.2f", m.Calmar),
+					fmt.Sprintf("%.2f", m.ProfitFactor),
+					fmt.Sprintf("%.2f", m.Expectancy),
+					fmt.Sprintf("%.2f", m.AvgWinLossRatio),
+					fmt.Sprintf("%d", m.LongestDDDays),
+					fmt.Sprintf("%.2f", m.ExposurePct),
+					fmt.Sprintf("%.2f", m.AvgHoldingDays),
+				}
+			}
+			if b := r.YearStats.Benchmark; b != nil && b.Error == "" {
+				bench = []string{
+					b.Symbol,
+					fmt.Sprintf("%.2f", b.ReturnPct),
+					fmt.Sprintf("%.2f", b.ExcessReturnPct),
+					fmt.Sprintf("%.4f", b.Beta),
+					fmt.Sprintf("%.2f", b.AlphaPct),
+				}
+			}
 		}
 
-		_ = w.Write([]string{
+		row := []string{
 			r.Symbol,
 			r.Name,
 			r.Instrument,
@@ -370,9 +400,11 @@ func writeAnalysisCSV(path string, rep analysisReport) error {
 			maxDD,
 			winRate,
 			totalTrades,
-			r.ChartPath,
-			strings.Join(r.Errors, " | "),
-		})
+		}
+		row = append(row, metrics...)
+		row = append(row, bench...)
+		row = append(row, r.ChartPath, strings.Join(r.Errors, " | "))
+		_ = w.Write(row)
 	}
 	return w.Error()
 }
@@ -424,6 +456,39 @@ func writeTradesCSV(path string, rep analysisReport) error {
 	return w.Error()
 }
 
+func writeReturnsCSV(path string, rep analysisReport) error {
+	if err := ensureParentDir(path); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	defer w.Flush()
+
+	_ = w.Write([]string{"symbol", "instrument", "period_type", "period", "return_pct"})
+
+	for _, r := range rep.Results {
+		if r.YearStats == nil || r.YearStats.Metrics == nil {
+			continue
+		}
+		m := r.YearStats.Metrics
+		for _, t := range []struct {
+			kind string
+			rows []backtest.PeriodReturn
+		}{{"month", m.MonthlyReturns}, {"year", m.YearlyReturns}} {
+			for _, p := range t.rows {
+				_ = w.Write([]string{r.Symbol, r.Instrument, t.kind, p.Period, fmt.Sprintf("%.2f", p.ReturnPct)})
+			}
+		}
+	}
+
+	return w.Error()
+}
+
 func sanitizeFilename(s string) string {
 	s = strings.TrimSpace(s)
 	if s == "" {
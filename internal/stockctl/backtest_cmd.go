@@ -2,9 +2,11 @@ package stockctl
 
 import (
 	"fmt"
+	"log"
 	"os"
 
 	"stock/backtest"
+	"stock/barstore"
 )
 
 func runBacktest(configPath, outPath string) error {
@@ -13,11 +15,23 @@ func runBacktest(configPath, outPath string) error {
 		return err
 	}
 
-	runner := backtest.NewRunner()
-	results, err := runner.Run(cfg)
+	runner, err := newRunner(cfg)
 	if err != nil {
 		return err
 	}
+	var results []backtest.Result
+	if cfg.Portfolio {
+		res, err := runner.RunPortfolio(cfg)
+		if err != nil {
+			return err
+		}
+		results = []backtest.Result{res}
+	} else {
+		results, err = runner.Run(cfg)
+		if err != nil {
+			return err
+		}
+	}
 
 	if outPath == "" {
 		return backtest.WriteResultsJSON(os.Stdout, results)
@@ -33,3 +47,16 @@ func runBacktest(configPath, outPath string) error {
 	defer f.Close()
 	return backtest.WriteResultsJSON(f, results)
 }
+
+// newRunner builds a backtest runner on the bar source(s) selected by cfg.Data,
+// backed by the local bar store unless data.disable_cache is set.
+func newRunner(cfg backtest.RunConfig) (*backtest.Runner, error) {
+	for _, sym := range cfg.MissingContractSpecs() {
+		log.Printf("警告: %s 不在合约表中，合约乘数取 backtest.futures_multiplier（默认 1），保证金与手续费取回测全局设置；可在 backtest.contract_specs 中补充", sym)
+	}
+	src, err := barstore.NewSourceFromConfig(cfg.Data)
+	if err != nil {
+		return nil, err
+	}
+	return backtest.NewRunnerWithSource(src), nil
+}
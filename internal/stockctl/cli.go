@@ -13,6 +13,7 @@ import (
 
 	"stock"
 	"stock/analyzer"
+	"stock/barstore"
 	"stock/cache"
 	"stock/config"
 	"stock/fetcher"
@@ -38,18 +39,25 @@ func runStandaloneCLI(configPath string, enableAI bool) error {
 
 	cfg := config.GetConfig(configPath)
 	c := cache.NewCache()
-	sf := fetcher.NewStockFetcher()
-	ff := fetcher.NewFuturesFetcher()
+	quotes, err := fetcher.NewQuoteSource(cfg.Data.QuoteSource, cfg.Data)
+	if err != nil {
+		return err
+	}
 
 	aiEnabled := enableAI || cfg.EnableAI
 	var a *analyzer.ClaudeAnalyzer
 	if aiEnabled && cfg.ClaudeAPIKey != "" {
+		bars, err := barstore.NewSourceFromConfig(cfg.Data)
+		if err != nil {
+			return err
+		}
 		a = analyzer.NewClaudeAnalyzer(cfg.ClaudeAPIKey, cfg.ClaudeAPIBase, cfg.ClaudeModel)
+		a.SetBarSource(bars)
 		_ = a.LoadFromFile(stock.DefaultAIStorePath())
 	}
 
 	stop := make(chan struct{})
-	go realtime.RunDataSync(cfg, c, sf, ff, stop, realtime.SyncOptions{Quiet: true})
+	go realtime.RunDataSync(cfg, c, quotes, stop, realtime.SyncOptions{Quiet: true})
 	if a != nil && a.IsEnabled() {
 		// Best-effort: run once in background; stockd has a scheduler, standalone CLI keeps it simple.
 		go func() {
@@ -0,0 +1,229 @@
+package stockctl
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"stock/backtest"
+	"stock/barstore"
+	"stock/fetcher"
+)
+
+// runData handles `stockctl data export|import ...`.
+func runData(args []string) int {
+	if len(args) == 0 {
+		dataUsage()
+		return 2
+	}
+	var err error
+	switch args[0] {
+	case "export":
+		err = runDataExport(args[1:])
+	case "import":
+		err = runDataImport(args[1:])
+	default:
+		dataUsage()
+		return 2
+	}
+	if err != nil {
+		if err == flag.ErrHelp {
+			return 2
+		}
+		log.Printf("[ERROR] data %s 失败: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func dataUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  stockctl data export [-bt-config backtest.yaml] [-format csv|jsonl] [-out runtime/export | -]")
+	fmt.Fprintln(os.Stderr, "  stockctl data import [-format auto|tdx|wind|generic|jsonl] [-symbol sh600000] [-freq 1d] [-adjust forward] [-store-dir runtime/bars] FILE...")
+}
+
+// runDataExport dumps the bar series the backtest would use for every configured instrument.
+func runDataExport(args []string) error {
+	fs := flag.NewFlagSet("stockctl data export", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	btConfig := fs.String("bt-config", "backtest.yaml", "回测配置文件路径（标的、日期窗口与 data 数据源）")
+	format := fs.String("format", barstore.FormatCSV, "导出格式：csv | jsonl")
+	out := fs.String("out", "runtime/export", "输出目录（每个标的一个文件）；- 表示全部写到 stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	f := strings.ToLower(strings.TrimSpace(*format))
+	if f != barstore.FormatCSV && f != barstore.FormatJSONL {
+		return fmt.Errorf("未知的导出格式: %s (可选: csv, jsonl)", *format)
+	}
+
+	cfg, err := backtest.LoadRunConfig(*btConfig)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Instruments) == 0 {
+		return fmt.Errorf("no instruments configured")
+	}
+	runner, err := newRunner(cfg)
+	if err != nil {
+		return err
+	}
+
+	var all []barstore.Series
+	exported := 0
+	for _, inst := range cfg.Instruments {
+		bars, err := runner.LoadBars(inst, cfg)
+		if err != nil {
+			log.Printf("[WARN] 导出 %s 失败: %v\n", inst.Symbol, err)
+			continue
+		}
+		s := barstore.Series{Symbol: inst.Symbol, Bars: barsToKLines(bars, cfg)}
+		exported++
+		if *out == "-" {
+			all = append(all, s)
+			continue
+		}
+		path := filepath.Join(*out, exportFileName(inst.Symbol, f))
+		if err := writeSeriesFile(path, f, s); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "导出 %s: %d 根 -> %s\n", inst.Symbol, len(bars), path)
+	}
+	if *out == "-" {
+		if err := barstore.WriteBars(os.Stdout, f, all); err != nil {
+			return err
+		}
+	}
+	if exported == 0 {
+		return fmt.Errorf("没有可导出的标的")
+	}
+	return nil
+}
+
+// runDataImport loads CSV/JSONL files into the local bar store; backtests then read them via
+// data.bar_source: local (or a per-symbol data.sources entry) without network access.
+func runDataImport(args []string) error {
+	fs := flag.NewFlagSet("stockctl data import", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	format := fs.String("format", barstore.FormatAuto, "导入格式：auto | tdx | wind | generic | jsonl")
+	symbol := fs.String("symbol", "", "标的代码（文件内无代码列时使用；默认从文件名推断，如 SH#600000.txt / 600000.SH.csv）")
+	freqFlag := fs.String("freq", "1d", "K线周期：1d | 1m | 5m | 15m | 30m | 60m（与 backtest.frequency 对应）")
+	adjustFlag := fs.String("adjust", "forward", "股票数据的复权方式：none | forward | backward（与 backtest.adjust 对应；期货忽略）")
+	storeDir := fs.String("store-dir", "", "本地K线库目录（默认 runtime/bars，与 data.store_dir 保持一致）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	adjust, err := fetcher.ParseAdjust(*adjustFlag)
+	if err != nil {
+		return err
+	}
+	freq, err := fetcher.ParsePeriod(*freqFlag)
+	if err != nil {
+		return err
+	}
+	files := fs.Args()
+	if len(files) == 0 {
+		return fmt.Errorf("no input files")
+	}
+	if *symbol != "" && len(files) > 1 {
+		return fmt.Errorf("-symbol 只能用于单个文件")
+	}
+
+	store := barstore.New(*storeDir)
+	for _, path := range files {
+		series, err := readSeriesFile(path, *format)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, s := range series {
+			sym := s.Symbol
+			if *symbol != "" {
+				sym = barstore.NormalizeSymbol(*symbol)
+			}
+			if sym == "" {
+				sym = barstore.SymbolFromPath(path)
+			}
+			if sym == "" {
+				return fmt.Errorf("%s: 无法确定标的代码，请使用 -symbol", path)
+			}
+			opt := fetcher.KLineOptions{Adjust: adjust, Period: freq}
+			key := barstore.FuturesKey(sym, opt)
+			if barstore.IsStockSymbol(sym) {
+				key = barstore.StockKey(sym, opt)
+			}
+			n, err := store.Import(key, s.Bars)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			fmt.Fprintf(os.Stderr, "导入 %s: %d 根 (%s ~ %s)，库内共 %d 根\n",
+				sym, len(s.Bars), s.Bars[0].Date, s.Bars[len(s.Bars)-1].Date, n)
+		}
+	}
+	return nil
+}
+
+func readSeriesFile(path, format string) ([]barstore.Series, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	series, err := barstore.ReadBars(f, format)
+	if err != nil {
+		return nil, err
+	}
+	out := series[:0]
+	for _, s := range series {
+		if len(s.Bars) > 0 {
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no bars found")
+	}
+	return out, nil
+}
+
+func writeSeriesFile(path, format string, s barstore.Series) error {
+	if err := ensureParentDir(path); err != nil {
+		return fmt.Errorf("prepare output dir: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+	if err := barstore.WriteBars(f, format, []barstore.Series{s}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func exportFileName(symbol, format string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, symbol)
+	return name + "." + format
+}
+
+func barsToKLines(bars []backtest.Bar, cfg backtest.RunConfig) []fetcher.KLine {
+	out := make([]fetcher.KLine, 0, len(bars))
+	for _, b := range bars {
+		out = append(out, fetcher.KLine{
+			Date:         cfg.FormatTime(b.Time),
+			Open:         b.Open,
+			High:         b.High,
+			Low:          b.Low,
+			Close:        b.Close,
+			Volume:       b.Volume,
+			OpenInterest: b.OpenInterest,
+		})
+	}
+	return out
+}
@@ -64,16 +64,35 @@ func runLLMGenerateBacktest(baseURL, model, prompt, promptFile, outPath string,
       "vol_ma_n": 20,
       "vol_ratio_min": 0,
       "enable_fake_breakout": true,
-      "fake_max_bars": 10
+      "fake_max_bars": 10,
+      "htf_filter": "",
+      "htf_lookback": 26
     }
   }
-}`
+}
+
+Instead of tsai_sen, "strategy" may be an indicator rule strategy:
+  "strategy": {
+    "type": "rules",
+    "params": {
+      "entry": "close > sma(close, 20) and rsi(14) < 70",
+      "exit": "cross_below(close, sma(close, 20)) or rsi(14) > 80",
+      "short_entry": "",
+      "short_exit": "",
+      "stop_pct": 0.05,
+      "target_pct": 0
+    }
+  }
+Rule expressions: fields open high low close volume oi; operators + - * / < <= > >= == != and or not, parentheses;
+functions sma(x,n) ema(x,n) highest(x,n) lowest(x,n) ref(x,n) abs(x) max(a,b) min(a,b) cross_above(a,b) cross_below(a,b)
+rsi(n) macd(fast,slow,signal) macd_signal(...) macd_hist(...) kdj_k(n,m1,m2) kdj_d(...) kdj_j(...) boll_upper(n,k) boll_mid(...) boll_lower(...)
+atr(n) adx(n) plus_di(n) minus_di(n) obv() vwap(n) donchian_upper(n) donchian_lower(n). n/k must be number literals.`
 
 	fullPrompt := strings.TrimSpace(`
 You will generate a backtest configuration for A-share / China futures daily bars.
 Rules:
 - Daily bars, close-confirm signal, execute at next-day open.
-- Futures use main continuous symbol format like nf_I0, or short like pp2605 (will be normalized).
+- Futures use main continuous symbol format like nf_I0, or short like pp2605 (will be normalized); overseas futures use hf_ (e.g. hf_CL, hf_SI).
 ` + "\n\n" + schemaHint + "\n\nUser requirement:\n" + userPrompt)
 
 	req := llm.GenerateRequest{
@@ -165,6 +184,12 @@ func runLLMAnalyzeReport(baseURL, model, reportPath, btConfigPath, outPath strin
 字段定义（请按此定义解释）:
 - avg_win_rate_pct: 各品种 win_rate_pct 的简单平均（不按交易次数加权）
 - overall_win_rate_pct: 总盈利笔数 / 总交易笔数 * 100（按交易数加权的整体胜率）
+- cagr_pct / sharpe / sortino / calmar: 年化收益率、年化夏普、索提诺（无风险利率按 0）、年化收益/最大回撤
+- profit_factor: 总盈利 / 总亏损；expectancy: 平均每笔净盈亏；avg_win_loss_ratio: 平均盈利 / 平均亏损
+- longest_drawdown_days: 最长回撤持续天数（自然日）；exposure_pct: 持仓K线占比；avg_holding_days: 平均持仓天数
+- yearly_returns: 各年度收益率
+- benchmark_return_pct / excess_return_pct: 基准（benchmark）同期买入持有收益率、策略收益率减基准收益率
+- beta / alpha_pct / information_ratio: 相对基准的贝塔、年化阿尔法、信息比率（按日收益计算）
 `)
 
 	prompt := defs + "\n\n回测摘要(JSON):\n" + string(sumJSON)
@@ -207,7 +232,10 @@ func runLLMScanAdvice(baseURL, model, btConfigPath, serviceConfigPath, outPath s
 	cfg.ScanChartDir = scanChartDir
 	cfg.ScanChartBars = scanChartBars
 
-	runner := backtest.NewRunner()
+	runner, err := newRunner(cfg)
+	if err != nil {
+		return err
+	}
 	results, err := runner.Scan(cfg)
 	if err != nil {
 		return err
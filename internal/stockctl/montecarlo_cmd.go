@@ -0,0 +1,141 @@
+package stockctl
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"stock/backtest"
+)
+
+// runMonteCarlo handles `stockctl montecarlo ...`.
+func runMonteCarlo(args []string) int {
+	def := backtest.DefaultMonteCarloSpec()
+	fs := flag.NewFlagSet("stockctl montecarlo", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	reportPath := fs.String("report", "runtime/report.json", "回测结果 JSON（-backtest / walkforward 输出的 report.json）")
+	outDir := fs.String("out", "runtime/montecarlo", "输出目录（montecarlo.json 与直方图 SVG）")
+	method := fs.String("method", string(def.Method), "抽样方式：bootstrap（有放回重抽交易）| shuffle（打乱交易顺序）")
+	sims := fs.Int("sims", def.Simulations, "模拟次数")
+	seed := fs.Int64("seed", def.Seed, "随机种子")
+	slippage := fs.Float64("slippage", 0, "每笔交易每边额外随机滑点上限（成交额的百分比，如 0.1 = 0.1%）")
+	ruinDD := fs.Float64("ruin-dd", def.RuinDDPct, "回撤达到该百分比视为破产（risk of ruin）")
+	cash := fs.Float64("cash", 0, "初始资金；0 = 由 final_equity 减去交易净盈亏推算")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	m, err := backtest.ParseMonteCarloMethod(*method)
+	if err != nil {
+		log.Printf("[ERROR] %v\n", err)
+		return 2
+	}
+	spec := def
+	spec.Method = m
+	spec.Simulations = *sims
+	spec.Seed = *seed
+	spec.SlippagePct = *slippage
+	spec.RuinDDPct = *ruinDD
+	if err := monteCarlo(*reportPath, *outDir, *cash, spec); err != nil {
+		log.Printf("[ERROR] 蒙特卡洛分析失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func monteCarlo(reportPath, outDir string, cash float64, spec backtest.MonteCarloSpec) error {
+	raw, err := os.ReadFile(reportPath)
+	if err != nil {
+		return err
+	}
+	var results []backtest.Result
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return fmt.Errorf("parse report json: %w", err)
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+
+	var out []backtest.MonteCarloResult
+	fmt.Printf("%-12s %-7s %-14s %-14s %-14s %-10s %-10s %-8s %-8s\n",
+		"SYMBOL", "TRADES", "ACTUAL_EQ", "P5_EQ", "P50_EQ", "P95_DD%", "ACT_DD%", "LOSS%", "RUIN%")
+	for _, res := range results {
+		if len(res.Errors) > 0 {
+			continue
+		}
+		initial := cash
+		if initial <= 0 {
+			initial = inferInitialCash(res)
+		}
+		mc := backtest.MonteCarlo(res.Symbol, res.Trades, initial, spec)
+		out = append(out, mc)
+		if mc.Error != "" {
+			fmt.Printf("%-12s ERROR %s\n", res.Symbol, mc.Error)
+			continue
+		}
+		fmt.Printf("%-12s %-7d %-14.2f %-14.2f %-14.2f %-10.2f %-10.2f %-8.2f %-8.2f\n",
+			res.Symbol, mc.Trades, mc.ActualFinalEquity,
+			percentileValue(mc.FinalEquity, 5), percentileValue(mc.FinalEquity, 50),
+			percentileValue(mc.MaxDDPct, 95), mc.ActualMaxDDPct, mc.ProbLossPct, mc.RiskOfRuinPct)
+
+		if err := writeMonteCarloSVGs(outDir, mc); err != nil {
+			log.Printf("[WARN] %s 直方图生成失败: %v\n", res.Symbol, err)
+		}
+	}
+	if len(out) == 0 {
+		return fmt.Errorf("no usable result in %s", reportPath)
+	}
+	if err := writeJSON(filepath.Join(outDir, "montecarlo.json"), out); err != nil {
+		return err
+	}
+	fmt.Printf("json: %s\n", filepath.Join(outDir, "montecarlo.json"))
+	return nil
+}
+
+// inferInitialCash backs out the starting capital of a report entry.
+func inferInitialCash(res backtest.Result) float64 {
+	c := res.FinalEquity
+	for _, t := range res.Trades {
+		c -= t.NetPnL
+	}
+	if c <= 0 && len(res.EquityCurve) > 0 {
+		c = res.EquityCurve[0].Equity
+	}
+	return c
+}
+
+func percentileValue(ps []backtest.PercentileValue, p float64) float64 {
+	for _, v := range ps {
+		if v.Percentile == p {
+			return v.Value
+		}
+	}
+	return 0
+}
+
+func writeMonteCarloSVGs(outDir string, mc backtest.MonteCarloResult) error {
+	name := sanitizeFilename(mc.Symbol)
+	marks := func(ps []backtest.PercentileValue, actual float64) []backtest.ChartLine {
+		lines := []backtest.ChartLine{{Price: actual, Label: "actual " + fmt.Sprintf("%.2f", actual), Color: "#f59e0b"}}
+		for _, p := range ps {
+			lines = append(lines, backtest.ChartLine{Price: p.Value, Label: fmt.Sprintf("p%g", p.Percentile), Dash: true})
+		}
+		return lines
+	}
+	title := fmt.Sprintf("%s final equity (%s, %d runs)", mc.Symbol, mc.Method, mc.Simulations)
+	svg, err := backtest.RenderHistogramSVG(title, mc.FinalEquityHistogram, marks(mc.FinalEquity, mc.ActualFinalEquity), backtest.SVGChartOptions{})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, name+"_final_equity.svg"), svg, 0o644); err != nil {
+		return err
+	}
+	title = fmt.Sprintf("%s max drawdown %% (risk of ruin %.2f%% at %g%%)", mc.Symbol, mc.RiskOfRuinPct, mc.RuinDDPct)
+	svg, err = backtest.RenderHistogramSVG(title, mc.MaxDDHistogram, marks(mc.MaxDDPct, mc.ActualMaxDDPct), backtest.SVGChartOptions{})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outDir, name+"_max_dd.svg"), svg, 0o644)
+}
@@ -0,0 +1,153 @@
+package stockctl
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"stock/backtest"
+)
+
+// runOptimize handles `stockctl optimize ...`.
+func runOptimize(args []string) int {
+	fs := flag.NewFlagSet("stockctl optimize", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	btConfig := fs.String("bt-config", "backtest.yaml", "回测配置文件路径（标的、窗口、费用与基础策略参数）")
+	specPath := fs.String("spec", "", "参数搜索配置（optimize 段）；默认读取 -bt-config 中的 optimize 段")
+	outDir := fs.String("out", "runtime/optimize", "输出目录（results.csv 与 best.yaml）")
+	workers := fs.Int("workers", 0, "并行数（覆盖 optimize.workers；0 = CPU 核数）")
+	top := fs.Int("top", 10, "终端打印前 N 名")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := optimize(*btConfig, *specPath, *outDir, *workers, *top); err != nil {
+		log.Printf("[ERROR] 参数优化失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func optimize(btConfigPath, specPath, outDir string, workers, top int) error {
+	cfg, err := backtest.LoadRunConfig(btConfigPath)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(specPath) == "" {
+		specPath = btConfigPath
+	}
+	spec, err := backtest.LoadOptimizeSpec(specPath)
+	if err != nil {
+		return err
+	}
+	if workers > 0 {
+		spec.Workers = workers
+	}
+
+	runner, err := newRunner(cfg)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] optimize: %s 模式, %d 组参数, 目标 %s\n", spec.Mode, len(spec.Combinations()), spec.Objective)
+	trials, err := runner.Optimize(cfg, spec)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+	names := spec.ParamNames()
+	if err := writeOptimizeCSV(filepath.Join(outDir, "results.csv"), names, trials); err != nil {
+		return err
+	}
+
+	if len(trials) == 0 || trials[0].Error != "" {
+		return fmt.Errorf("no successful trial (first error: %s)", backtest.FirstTrialError(trials))
+	}
+	best := trials[0]
+	raw, err := os.ReadFile(btConfigPath)
+	if err != nil {
+		return err
+	}
+	params := map[string]any{}
+	for k, v := range cfg.StrategyParams {
+		params[k] = v
+	}
+	for k, v := range best.Params {
+		params[k] = v
+	}
+	bestYAML, err := backtest.SetStrategyParamsYAML(raw, params)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "best.yaml"), bestYAML, 0o644); err != nil {
+		return err
+	}
+
+	fmt.Printf("%-5s %-10s %-12s %-8s %-8s %-8s %-7s ", "RANK", "SCORE", "NET_PNL", "SHARPE", "CALMAR", "MAX_DD%", "TRADES")
+	fmt.Println(strings.Join(names, " "))
+	for i, t := range trials {
+		if i >= top {
+			break
+		}
+		if t.Error != "" {
+			fmt.Printf("%-5d ERROR %s\n", i+1, t.Error)
+			continue
+		}
+		vals := make([]string, len(names))
+		for j, name := range names {
+			vals[j] = fmt.Sprint(t.Params[name])
+		}
+		fmt.Printf("%-5d %-10.4f %-12.2f %-8.2f %-8.2f %-8.2f %-7d %s\n", i+1, t.Score, t.NetPnL, t.Sharpe, t.Calmar, t.MaxDDPct, t.Trades, strings.Join(vals, " "))
+	}
+	fmt.Printf("results: %s\nbest:    %s\n", filepath.Join(outDir, "results.csv"), filepath.Join(outDir, "best.yaml"))
+	return nil
+}
+
+func writeOptimizeCSV(path string, names []string, trials []backtest.OptimizeTrial) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	w := csv.NewWriter(f)
+
+	header := []string{"rank", "score"}
+	header = append(header, names...)
+	header = append(header,
+		"net_pnl", "cagr_pct", "sharpe", "sortino", "calmar", "profit_factor",
+		"max_dd_pct", "win_rate_pct", "total_trades", "error",
+	)
+	_ = w.Write(header)
+
+	for i, t := range trials {
+		row := []string{fmt.Sprintf("%d", i+1), fmt.Sprintf("%.4f", t.Score)}
+		for _, name := range names {
+			row = append(row, fmt.Sprint(t.Params[name]))
+		}
+		row = append(row,
+			fmt.Sprintf("%.2f", t.NetPnL),
+			fmt.Sprintf("%.2f", t.CAGRPct),
+			fmt.Sprintf("%.2f", t.Sharpe),
+			fmt.Sprintf("%.2f", t.Sortino),
+			fmt.Sprintf("%.2f", t.Calmar),
+			fmt.Sprintf("%.2f", t.ProfitFactor),
+			fmt.Sprintf("%.2f", t.MaxDDPct),
+			fmt.Sprintf("%.2f", t.WinRatePct),
+			fmt.Sprintf("%d", t.Trades),
+			t.Error,
+		)
+		_ = w.Write(row)
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -20,7 +20,10 @@ func runScan(btConfigPath, serviceConfigPath, outPath string, jsonOut bool, only
 	cfg.ScanChartDir = scanChartDir
 	cfg.ScanChartBars = scanChartBars
 
-	runner := backtest.NewRunner()
+	runner, err := newRunner(cfg)
+	if err != nil {
+		return err
+	}
 	results, err := runner.Scan(cfg)
 	if err != nil {
 		return err
@@ -31,41 +31,25 @@ func loadScanRunConfig(btConfigPath, serviceConfigPath string) (backtest.RunConf
 		return backtest.RunConfig{}, fmt.Errorf("load config.yaml: %w", err)
 	}
 
-	// 扫描/回测目前仅支持 A股 与国内期货日K；外盘(hf_)仅用于实时行情监控，避免自动合并进扫描。
-	btCfg.Instruments = mergeInstruments(btCfg.Instruments, cfg.Stocks, filterChinaFutures(cfg.Futures))
-	return btCfg, nil
-}
-
-func filterChinaFutures(codes []string) []string {
-	out := make([]string, 0, len(codes))
-	for _, c := range codes {
-		s := strings.TrimSpace(c)
-		if s == "" {
-			continue
-		}
-		if strings.HasPrefix(strings.ToLower(s), "hf_") {
-			continue
-		}
-		out = append(out, s)
+	// 监控清单中的 A股、国内期货(nf_) 与外盘期货(hf_，仅日线) 都会合并进扫描/回测。
+	if btCfg.Data.IsZero() {
+		btCfg.Data = cfg.Data
 	}
-	return out
+	btCfg.Instruments = mergeInstruments(btCfg, cfg.Stocks, cfg.Futures)
+	return btCfg, nil
 }
 
-func mergeInstruments(existing []backtest.Instrument, stocks []string, futures []string) []backtest.Instrument {
+// mergeInstruments adds watchlist symbols to the configured instruments; futures take
+// their multiplier/margin/fees from the contract spec table of btCfg.
+func mergeInstruments(btCfg backtest.RunConfig, stocks []string, futures []string) []backtest.Instrument {
+	existing := btCfg.Instruments
 	stockLot := int64(100)
-	futMult := 1.0
 	for _, inst := range existing {
 		if inst.Type == backtest.InstrumentTypeStock && inst.LotSize > 0 {
 			stockLot = inst.LotSize
 			break
 		}
 	}
-	for _, inst := range existing {
-		if inst.Type == backtest.InstrumentTypeFutures && inst.Multiplier > 0 {
-			futMult = inst.Multiplier
-			break
-		}
-	}
 
 	type key struct {
 		t backtest.InstrumentType
@@ -96,7 +80,7 @@ func mergeInstruments(existing []backtest.Instrument, stocks []string, futures [
 		if _, ok := m[k]; ok {
 			continue
 		}
-		m[k] = backtest.Instrument{Symbol: sym, Type: backtest.InstrumentTypeFutures, Multiplier: futMult, AllowShort: true}
+		m[k] = btCfg.FuturesInstrument(sym)
 	}
 
 	out := make([]backtest.Instrument, 0, len(m))
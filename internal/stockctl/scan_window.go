@@ -21,8 +21,9 @@ func applyScanDays(cfg *backtest.RunConfig, scanDays int) string {
 	cfg.Start = start
 	cfg.End = end
 
-	// Ensure we fetch enough bars for the time-window filter (non-trading days exist).
-	need := scanDays + 200
+	// Ensure we fetch enough bars for the time-window filter (non-trading days exist;
+	// intraday frequencies need many bars per day).
+	need := cfg.Frequency.BarsForDays(scanDays) + 200
 	if need > cfg.Days {
 		cfg.Days = need
 	}
@@ -9,6 +9,19 @@ import (
 )
 
 func Run(args []string) int {
+	if len(args) > 0 && args[0] == "data" {
+		return runData(args[1:])
+	}
+	if len(args) > 0 && args[0] == "optimize" {
+		return runOptimize(args[1:])
+	}
+	if len(args) > 0 && args[0] == "walkforward" {
+		return runWalkForward(args[1:])
+	}
+	if len(args) > 0 && args[0] == "montecarlo" {
+		return runMonteCarlo(args[1:])
+	}
+
 	fs := flag.NewFlagSet("stockctl", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
@@ -186,5 +199,9 @@ func Run(args []string) int {
 	fmt.Fprintln(os.Stderr, "  stockctl -scan -bt-config backtest.yaml [-scan-days 365] [-scan-chart]")
 	fmt.Fprintln(os.Stderr, "  stockctl -backtest -bt-config backtest.yaml [-bt-out runtime/report.json]")
 	fmt.Fprintln(os.Stderr, "  stockctl -llm-gen-bt / -llm-analyze / -llm-scan ...")
+	fmt.Fprintln(os.Stderr, "  stockctl data export|import ...")
+	fmt.Fprintln(os.Stderr, "  stockctl optimize [-bt-config backtest.yaml] [-spec optimize.yaml] [-out runtime/optimize] [-workers N]")
+	fmt.Fprintln(os.Stderr, "  stockctl walkforward [-bt-config backtest.yaml] [-spec optimize.yaml] [-out runtime/walkforward] [-workers N]")
+	fmt.Fprintln(os.Stderr, "  stockctl montecarlo [-report runtime/report.json] [-out runtime/montecarlo] [-method bootstrap|shuffle] [-sims 5000] [-slippage 0]")
 	return 2
 }
@@ -0,0 +1,154 @@
+package stockctl
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"stock/backtest"
+)
+
+// runWalkForward handles `stockctl walkforward ...`.
+func runWalkForward(args []string) int {
+	fs := flag.NewFlagSet("stockctl walkforward", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	btConfig := fs.String("bt-config", "backtest.yaml", "回测配置文件路径（标的、窗口、费用与基础策略参数）")
+	specPath := fs.String("spec", "", "optimize 与 walk_forward 段所在文件；默认读取 -bt-config")
+	outDir := fs.String("out", "runtime/walkforward", "输出目录（windows.csv、walkforward.json 与拼接后的 report.json）")
+	workers := fs.Int("workers", 0, "并行数（覆盖 optimize.workers；0 = CPU 核数）")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := walkForward(*btConfig, *specPath, *outDir, *workers); err != nil {
+		log.Printf("[ERROR] 滚动样本外检验失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func walkForward(btConfigPath, specPath, outDir string, workers int) error {
+	cfg, err := backtest.LoadRunConfig(btConfigPath)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(specPath) == "" {
+		specPath = btConfigPath
+	}
+	spec, err := backtest.LoadOptimizeSpec(specPath)
+	if err != nil {
+		return err
+	}
+	if workers > 0 {
+		spec.Workers = workers
+	}
+	wf, err := backtest.LoadWalkForwardSpec(specPath)
+	if err != nil {
+		return err
+	}
+
+	runner, err := newRunner(cfg)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] walkforward: 样本内 %d 天 / 样本外 %d 天, 每窗口 %d 组参数, 目标 %s\n",
+		wf.InSampleDays, wf.OutOfSampleDays, len(spec.Combinations()), spec.Objective)
+	rep, err := runner.WalkForward(cfg, spec, wf)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+	names := spec.ParamNames()
+	if err := writeWalkForwardCSV(filepath.Join(outDir, "windows.csv"), names, rep.Windows); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(outDir, "walkforward.json"), rep); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(outDir, "report.json"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := backtest.WriteResultsJSON(f, rep.Results); err != nil {
+		return err
+	}
+
+	fmt.Printf("%-23s %-23s %-10s %-10s ", "IN_SAMPLE", "OUT_OF_SAMPLE", "IS_SCORE", "OOS_SCORE")
+	fmt.Println(strings.Join(names, " "))
+	for _, w := range rep.Windows {
+		is := w.InSampleStart + "~" + w.InSampleEnd
+		oos := w.OutOfSampleStart + "~" + w.OutOfSampleEnd
+		if w.Error != "" {
+			fmt.Printf("%-23s %-23s ERROR %s\n", is, oos, w.Error)
+			continue
+		}
+		vals := make([]string, len(names))
+		for j, name := range names {
+			vals[j] = fmt.Sprint(w.Params[name])
+		}
+		fmt.Printf("%-23s %-23s %-10.4f %-10.4f %s\n", is, oos, w.InSample.Score, w.OutOfSample.Score, strings.Join(vals, " "))
+	}
+	for _, res := range rep.Results {
+		fmt.Printf("OOS %s: final_equity=%.2f max_dd=%.2f%% trades=%d\n", res.Symbol, res.FinalEquity, res.MaxDDPct, res.TotalTrades)
+	}
+	fmt.Printf("efficiency (OOS/IS): %.2f\n", rep.Efficiency)
+	fmt.Printf("windows: %s\nreport:  %s\n", filepath.Join(outDir, "windows.csv"), filepath.Join(outDir, "report.json"))
+	return nil
+}
+
+func writeWalkForwardCSV(path string, names []string, windows []backtest.WalkForwardWindow) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	w := csv.NewWriter(f)
+
+	header := []string{"in_sample_start", "in_sample_end", "out_of_sample_start", "out_of_sample_end"}
+	header = append(header, names...)
+	header = append(header,
+		"is_score", "oos_score", "is_net_pnl", "oos_net_pnl",
+		"oos_max_dd_pct", "oos_win_rate_pct", "oos_trades", "error",
+	)
+	_ = w.Write(header)
+
+	for _, win := range windows {
+		row := []string{win.InSampleStart, win.InSampleEnd, win.OutOfSampleStart, win.OutOfSampleEnd}
+		for _, name := range names {
+			v := ""
+			if p, ok := win.Params[name]; ok {
+				v = fmt.Sprint(p)
+			}
+			row = append(row, v)
+		}
+		errMsg := win.Error
+		if errMsg == "" {
+			errMsg = win.OutOfSample.Error
+		}
+		row = append(row,
+			fmt.Sprintf("%.4f", win.InSample.Score),
+			fmt.Sprintf("%.4f", win.OutOfSample.Score),
+			fmt.Sprintf("%.2f", win.InSample.NetPnL),
+			fmt.Sprintf("%.2f", win.OutOfSample.NetPnL),
+			fmt.Sprintf("%.2f", win.OutOfSample.MaxDDPct),
+			fmt.Sprintf("%.2f", win.OutOfSample.WinRatePct),
+			fmt.Sprintf("%d", win.OutOfSample.Trades),
+			errMsg,
+		)
+		_ = w.Write(row)
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -13,6 +13,7 @@ import (
 	"stock"
 	"stock/analyzer"
 	"stock/api"
+	"stock/barstore"
 	"stock/cache"
 	"stock/config"
 	"stock/fetcher"
@@ -47,21 +48,30 @@ func Run(args []string) int {
 	cfg := config.GetConfig(configPath)
 	dataCache := cache.Global
 
-	stockFetcher := fetcher.NewStockFetcher()
-	futuresFetcher := fetcher.NewFuturesFetcher()
+	quoteSource, err := fetcher.NewQuoteSource(cfg.Data.QuoteSource, cfg.Data)
+	if err != nil {
+		log.Printf("[ERROR] %v\n", err)
+		return 2
+	}
 
 	aiEnabled := enableAI || cfg.EnableAI
 	var globalAnalyzer *analyzer.ClaudeAnalyzer
 	aiStorePath := stock.DefaultAIStorePath()
 	if aiEnabled && cfg.ClaudeAPIKey != "" {
+		barSource, err := barstore.NewSourceFromConfig(cfg.Data)
+		if err != nil {
+			log.Printf("[ERROR] %v\n", err)
+			return 2
+		}
 		globalAnalyzer = analyzer.NewClaudeAnalyzer(cfg.ClaudeAPIKey, cfg.ClaudeAPIBase, cfg.ClaudeModel)
+		globalAnalyzer.SetBarSource(barSource)
 		if err := globalAnalyzer.LoadFromFile(aiStorePath); err != nil {
 			log.Printf("[WARN] load persisted AI analysis failed: %v\n", err)
 		}
 	}
 
 	stop := make(chan struct{})
-	go realtime.RunDataSync(cfg, dataCache, stockFetcher, futuresFetcher, stop, realtime.SyncOptions{Logger: log.Default(), Quiet: false})
+	go realtime.RunDataSync(cfg, dataCache, quoteSource, stop, realtime.SyncOptions{Logger: log.Default(), Quiet: false})
 
 	if globalAnalyzer != nil && globalAnalyzer.IsEnabled() {
 		go runAIAnalysisLoop(cfg, dataCache, globalAnalyzer, stop, aiStorePath)
@@ -65,6 +65,7 @@ func Render(s Snapshot) {
 		vol := formatVolume(q.Volume)
 		code := strings.TrimPrefix(q.Code, "sz")
 		code = strings.TrimPrefix(code, "sh")
+		code = strings.TrimPrefix(code, "bj")
 		fmt.Printf("║  %-8s %-12s %s%8.2f  %+7.2f%%  %+8.2f\033[0m  %12s  ║\n",
 			code, name, color, q.Price, changePercent, change, vol)
 	}
@@ -33,6 +33,23 @@ type SymbolPerformance struct {
 	AvgTradeNetPnL float64 `json:"avg_trade_net_pnl"`
 	BestTradeNetPnL float64 `json:"best_trade_net_pnl"`
 	WorstTradeNetPnL float64 `json:"worst_trade_net_pnl"`
+	CAGRPct         float64 `json:"cagr_pct"`
+	Sharpe          float64 `json:"sharpe"`
+	Sortino         float64 `json:"sortino"`
+	Calmar          float64 `json:"calmar"`
+	ProfitFactor    float64 `json:"profit_factor"`
+	Expectancy      float64 `json:"expectancy"`
+	AvgWinLossRatio float64 `json:"avg_win_loss_ratio"`
+	LongestDDDays   int     `json:"longest_drawdown_days"`
+	ExposurePct     float64 `json:"exposure_pct"`
+	AvgHoldingDays  float64 `json:"avg_holding_days"`
+	YearlyReturns   []backtest.PeriodReturn `json:"yearly_returns,omitempty"`
+	Benchmark          string  `json:"benchmark,omitempty"`
+	BenchmarkReturnPct float64 `json:"benchmark_return_pct,omitempty"`
+	ExcessReturnPct    float64 `json:"excess_return_pct,omitempty"`
+	Beta               float64 `json:"beta,omitempty"`
+	AlphaPct           float64 `json:"alpha_pct,omitempty"`
+	InformationRatio   float64 `json:"information_ratio,omitempty"`
 	Start       string  `json:"start,omitempty"`
 	End         string  `json:"end,omitempty"`
 	Error       string  `json:"error,omitempty"`
@@ -67,6 +84,32 @@ func SummarizeReport(results []backtest.Result) ReportSummary {
 			p.Start = r.EquityCurve[0].Time
 			p.End = r.EquityCurve[n-1].Time
 		}
+		if m := r.Metrics; m != nil || len(r.EquityCurve) > 0 {
+			if m == nil {
+				// reports written before metrics were added
+				computed := backtest.ComputeMetrics(r.EquityCurve, r.Trades, 0)
+				m = &computed
+			}
+			p.CAGRPct = m.CAGRPct
+			p.Sharpe = m.Sharpe
+			p.Sortino = m.Sortino
+			p.Calmar = m.Calmar
+			p.ProfitFactor = m.ProfitFactor
+			p.Expectancy = m.Expectancy
+			p.AvgWinLossRatio = m.AvgWinLossRatio
+			p.LongestDDDays = m.LongestDDDays
+			p.ExposurePct = m.ExposurePct
+			p.AvgHoldingDays = m.AvgHoldingDays
+			p.YearlyReturns = m.YearlyReturns
+		}
+		if b := r.Benchmark; b != nil && b.Error == "" {
+			p.Benchmark = b.Symbol
+			p.BenchmarkReturnPct = b.ReturnPct
+			p.ExcessReturnPct = b.ExcessReturnPct
+			p.Beta = b.Beta
+			p.AlphaPct = b.AlphaPct
+			p.InformationRatio = b.InformationRatio
+		}
 		net := 0.0
 		best := 0.0
 		worst := 0.0
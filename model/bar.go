@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Bar 一根K线（回测引擎与技术指标共用）
+type Bar struct {
+	Time   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume int64
+
+	OpenInterest int64 // 持仓量（期货）；连续合约换月规则使用
+}